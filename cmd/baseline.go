@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mddiff/pkg/domain"
+	"mddiff/pkg/manifest"
+)
+
+// readBaseline loads a tree saved with "mddiff manifest".
+func readBaseline(path string) (*domain.DirectoryTree, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("opening baseline: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	tree, err := manifest.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline %s: %w", path, err)
+	}
+	return tree, nil
+}
+
+// checkBaselineContent rejects content checks that the baseline at path can't
+// support. The baseline has no files to read, so only hashing works, and only
+// with the digests "mddiff manifest --compare=hash" records.
+func checkBaselineContent(path string, tree *domain.DirectoryTree, modes []string, useHash bool) error {
+	for _, mode := range []string{"quickhash", "content"} {
+		if slices.Contains(modes, mode) {
+			return fmt.Errorf("--compare=%s reads the files, which baseline %s doesn't have; use --compare=hash",
+				mode, path)
+		}
+	}
+	if !useHash {
+		return nil
+	}
+	for _, asset := range tree.Assets {
+		if !asset.IsDir && asset.Hash == "" {
+			return fmt.Errorf("baseline %s has no hashes; re-create it with `mddiff manifest --compare=hash`", path)
+		}
+	}
+	return nil
+}
+
+// countFiles returns the number of non-directory assets in tree.
+func countFiles(tree *domain.DirectoryTree) int {
+	n := 0
+	for _, asset := range tree.Assets {
+		if !asset.IsDir {
+			n++
+		}
+	}
+	return n
+}
+
+// parseDrift parses a --max-baseline-drift value: a number of changed files,
+// or a percentage of the files in the baseline such as "2.5%".
+func parseDrift(s string) (limit float64, isPercent bool, err error) {
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		limit, err = strconv.ParseFloat(pct, 64)
+		if err != nil || limit < 0 || limit > 100 {
+			return 0, false, fmt.Errorf("invalid percentage %q", s)
+		}
+		return limit, true, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid file count %q", s)
+	}
+	return float64(n), false, nil
+}
+
+// checkDrift compares the number of changed items in r against
+// --max-baseline-drift. baselineFiles is the number of files in the baseline
+// side of the pair.
+func checkDrift(r *domain.DiffReport, baselineFiles int) error {
+	limit, isPercent, err := parseDrift(maxBaselineDrift)
+	if err != nil {
+		return fmt.Errorf("invalid --max-baseline-drift: %w", err)
+	}
+
+	changed := r.Differences()
+	drift := float64(changed)
+	if isPercent {
+		if baselineFiles == 0 {
+			drift = 0
+			if changed > 0 {
+				drift = 100
+			}
+		} else {
+			drift = drift * 100 / float64(baselineFiles)
+		}
+	}
+	if drift <= limit {
+		return nil
+	}
+	return fmt.Errorf("%d changed file(s) between %s and %s exceed --max-baseline-drift %s",
+		changed, r.SourceDir, r.TargetDir, maxBaselineDrift)
+}
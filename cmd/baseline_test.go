@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDrift(t *testing.T) {
+	tests := []struct {
+		in        string
+		limit     float64
+		isPercent bool
+		wantErr   bool
+	}{
+		{in: "0"},
+		{in: "3", limit: 3},
+		{in: "2.5%", limit: 2.5, isPercent: true},
+		{in: "100%", limit: 100, isPercent: true},
+		{in: "-1", wantErr: true},
+		{in: "1.5", wantErr: true},
+		{in: "101%", wantErr: true},
+		{in: "%", wantErr: true},
+		{in: "many", wantErr: true},
+	}
+	for _, tt := range tests {
+		limit, isPercent, err := parseDrift(tt.in)
+		if (err != nil) != tt.wantErr || limit != tt.limit || isPercent != tt.isPercent {
+			t.Errorf("parseDrift(%q) = %v, %v, %v, want %v, %v, error %v",
+				tt.in, limit, isPercent, err, tt.limit, tt.isPercent, tt.wantErr)
+		}
+	}
+}
+
+func TestBaselineDrift(t *testing.T) {
+	dir := writeTree(t, t.TempDir(), map[string]string{"a.mkv": "1", "b.mkv": "1", "c.mkv": "1", "d.mkv": "1"})
+	baseline := filepath.Join(t.TempDir(), "baseline.json")
+	if _, stderr, code := run(t, "manifest", "-o", baseline, dir); code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	// Two of the baseline's four files change.
+	writeTree(t, dir, map[string]string{"a.mkv": "12", "e.mkv": "1"})
+
+	tests := []struct {
+		drift string
+		code  int
+	}{
+		{"", 1},
+		{"2", 0},
+		{"1", 1},
+		{"50%", 0},
+		{"49%", 1},
+	}
+	for _, tt := range tests {
+		args := []string{"-f", "csv", baseline, dir}
+		if tt.drift != "" {
+			args = append([]string{"--max-baseline-drift", tt.drift}, args...)
+		}
+		stdout, stderr, code := run(t, args...)
+		if code != tt.code {
+			t.Errorf("--max-baseline-drift %q: exit status %d, want %d; stderr %q", tt.drift, code, tt.code, stderr)
+		}
+		if !strings.Contains(stdout, "MODIFIED,a.mkv,") || !strings.Contains(stdout, "EXTRA,e.mkv,") {
+			t.Errorf("--max-baseline-drift %q: stdout = %q, want the drift reported", tt.drift, stdout)
+		}
+		if tt.drift != "" && tt.code == 1 && !strings.Contains(stderr, "2 changed file(s) between ") {
+			t.Errorf("--max-baseline-drift %q: stderr = %q, want the drift explained", tt.drift, stderr)
+		}
+	}
+
+	_, stderr, code := run(t, "--max-baseline-drift", "lots", baseline, dir)
+	wantError(t, stderr, code, `invalid --max-baseline-drift: invalid file count "lots"`)
+}
+
+func TestBaselineDriftIgnored(t *testing.T) {
+	dir := writeTree(t, t.TempDir(), map[string]string{"a.mkv": "1", "a.nfo": "info"})
+	baseline := filepath.Join(t.TempDir(), "baseline.json")
+	if _, stderr, code := run(t, "manifest", "-o", baseline, dir); code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	if err := os.Remove(filepath.Join(dir, "a.nfo")); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, code := run(t, "-f", "csv", "--max-baseline-drift", "0", baseline, dir)
+	if code != 1 || !strings.Contains(stdout, "MISSING,a.nfo,") {
+		t.Errorf("exit status %d, stdout %q, want 1 and a.nfo missing", code, stdout)
+	}
+
+	// The ignored file neither drifts nor counts among the baseline's files.
+	for _, drift := range []string{"0", "0%"} {
+		stdout, stderr, code := run(t, "-f", "csv", "--ignore-ext", ".nfo", "--max-baseline-drift", drift, baseline, dir)
+		if code != 0 || strings.Contains(stdout, "a.nfo") {
+			t.Errorf("--max-baseline-drift %s: exit status %d, stdout %q, stderr %q, want 0 and no a.nfo",
+				drift, code, stdout, stderr)
+		}
+	}
+}
+
+func TestBaselineContentChecks(t *testing.T) {
+	dir := writeTree(t, t.TempDir(), map[string]string{"a.mkv": "1"})
+	baseline := filepath.Join(t.TempDir(), "baseline.json")
+	hashed := filepath.Join(t.TempDir(), "hashed.json")
+	if _, stderr, code := run(t, "manifest", "-o", baseline, dir); code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	if _, stderr, code := run(t, "manifest", "--compare=hash", "-o", hashed, dir); code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+
+	_, stderr, code := run(t, "--hash", baseline, dir)
+	wantError(t, stderr, code, "baseline "+baseline+" has no hashes; re-create it with `mddiff manifest --compare=hash`")
+	_, stderr, code = run(t, "--compare", "content", dir, hashed)
+	wantError(t, stderr, code, "--compare=content reads the files, which baseline "+hashed+" doesn't have")
+
+	if _, stderr, code := run(t, "--hash", hashed, dir); code != 0 {
+		t.Errorf("--hash against a hashed baseline: exit status %d, stderr %q", code, stderr)
+	}
+}
+
+func TestFromManifestSource(t *testing.T) {
+	dir := writeTree(t, t.TempDir(), map[string]string{"a.mkv": "1"})
+	baseline := filepath.Join(t.TempDir(), "baseline.json")
+	if _, stderr, code := run(t, "manifest", "-o", baseline, dir); code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+
+	if _, stderr, code := run(t, "--from-manifest-source", baseline, dir); code != 0 {
+		t.Errorf("exit status %d, stderr %q, want 0", code, stderr)
+	}
+	_, stderr, code := run(t, "--from-manifest-source", dir, baseline)
+	wantError(t, stderr, code, "--from-manifest-source: "+dir+" is not a manifest file")
+}
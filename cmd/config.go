@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"mddiff/pkg/config"
+)
+
+// userConfig holds the defaults read from --config or the user's
+// .mddiff.yaml. A target's own .mddiff.yaml is merged over it per pair.
+var userConfig = &config.Config{}
+
+// loadUserConfig reads --config, or else the first .mddiff.yaml in the
+// working directory and then the home directory, unless --no-config is set.
+// Its format and compare settings fill in --format and --compare when they
+// weren't given, so they are validated like the flags; the other settings, and
+// a target's compare setting, are applied per pair by newEngine and
+// ignoreEntries.
+func loadUserConfig(cmd *cobra.Command) error {
+	if noConfig {
+		if configPath != "" {
+			return errors.New("--config can't be combined with --no-config")
+		}
+		return nil
+	}
+
+	path := configPath
+	if path == "" {
+		cwd, _ := os.Getwd()
+		home, _ := os.UserHomeDir()
+		if path = config.Search(cwd, home); path == "" {
+			return nil
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("invalid --config: %w", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	userConfig = cfg
+
+	if cfg.Format != nil && !cmd.Flags().Changed("format") && !quiet && !combinedJSON {
+		formats = cfg.Format
+	}
+	if cfg.Compare != nil && !cmd.Flags().Changed("compare") {
+		compareModes = cfg.Compare
+	}
+	return nil
+}
+
+// checkCompareModes reports the first of modes that --compare doesn't accept.
+func checkCompareModes(modes []string) error {
+	for _, mode := range modes {
+		switch mode {
+		case "size", "hash", "mtime", "quickhash", "content":
+		default:
+			return fmt.Errorf("%s (want size|hash|quickhash|content|mtime)", mode)
+		}
+	}
+	return nil
+}
+
+// checkDirConfig rejects settings in the config at the root of the target dir
+// that can't apply to a single pair. The outputs are opened once for the whole
+// run, so only --format or the user's config can choose them.
+func checkDirConfig(dir string, cfg *config.Config) error {
+	path := filepath.Join(dir, config.DirConfigName)
+	if cfg.Format != nil {
+		return fmt.Errorf("%s: format can only be set by --format or the user's config", path)
+	}
+	if err := checkCompareModes(cfg.Compare); err != nil {
+		return fmt.Errorf("%s: invalid compare: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/config"
+)
+
+func TestTargetConfig(t *testing.T) {
+	const (
+		contentDiffers = "MODIFIED,a.mkv,Content differs at byte 1,3,3\n"
+		hashDiffers    = "MODIFIED,a.mkv,Content hash mismatch,3,3\n"
+		sizeDiffers    = "MODIFIED,b.mkv,Size changed: +1 bytes,5,6\n"
+	)
+	tests := []struct {
+		name    string
+		config  string
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no config",
+			want:    []string{sizeDiffers},
+			notWant: []string{contentDiffers, hashDiffers},
+		},
+		{
+			name:   "compare",
+			config: "compare: [content]\n",
+			want:   []string{contentDiffers, sizeDiffers},
+		},
+		{
+			name:    "compare given on the command line wins",
+			config:  "compare: [content]\n",
+			args:    []string{"--compare", "size"},
+			notWant: []string{contentDiffers},
+		},
+		{
+			name:   "hash",
+			config: "hash: true\n",
+			want:   []string{hashDiffers},
+		},
+		{
+			name:    "size threshold",
+			config:  "size_threshold: 10\n",
+			notWant: []string{sizeDiffers},
+		},
+		{
+			name:    "ignore lists",
+			config:  "ignore_names: [a.mkv]\nignore_ext: [.mkv]\n",
+			notWant: []string{"a.mkv", "b.mkv"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, target := fixture(t,
+				map[string]string{"a.mkv": "abc", "b.mkv": "12345"},
+				map[string]string{"a.mkv": "axc", "b.mkv": "123456", ".mddiff.yaml": tt.config})
+			args := append(append([]string{"-f", "csv"}, tt.args...), source, target)
+			stdout, stderr, _ := run(t, args...)
+			for _, want := range tt.want {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout = %q, stderr = %q, want %q", stdout, stderr, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(stdout, notWant) {
+					t.Errorf("stdout = %q, want no %q", stdout, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestTargetConfigInvalid(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{"format: [csv]\n", "format can only be set by --format or the user's config"},
+		{"compare: [bogus]\n", "invalid compare: bogus (want size|hash|quickhash|content|mtime)"},
+		{"hash: maybe\n", "parsing"},
+	}
+	for _, tt := range tests {
+		source, target := fixture(t, nil, map[string]string{".mddiff.yaml": tt.config})
+		_, stderr, code := run(t, source, target)
+		wantError(t, stderr, code, tt.err)
+	}
+}
+
+func TestNoConfigSkipsTargetConfig(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "abc"},
+		map[string]string{"a.mkv": "axc", ".mddiff.yaml": "compare: [content]\n"})
+	stdout, _, code := run(t, "--no-config", source, target)
+	// The config file itself is always ignored.
+	if code != 0 || !strings.Contains(stdout, "No differences found.") {
+		t.Errorf("exit status %d, stdout = %q, want the target's config ignored", code, stdout)
+	}
+}
+
+func TestIgnoreEntries(t *testing.T) {
+	t.Setenv(envIgnore, "env.txt, *.part")
+	t.Setenv(envIgnoreExt, "nfo")
+	defer func(names, exts []string) { ignoreNames, ignoreExt = names, exts }(ignoreNames, ignoreExt)
+	ignoreNames, ignoreExt = []string{"!*.part"}, []string{"srt"}
+
+	names, exts := ignoreEntries(&config.Config{IgnoreNames: []string{"cfg.txt"}, IgnoreExt: []string{"!nfo"}})
+	wantNames := []string{"env.txt", "*.part", "cfg.txt", "!*.part", config.DirConfigName}
+	if !slices.Equal(names, wantNames) {
+		t.Errorf("names = %q, want %q", names, wantNames)
+	}
+	if wantExts := []string{"nfo", "!nfo", "srt"}; !slices.Equal(exts, wantExts) {
+		t.Errorf("exts = %q, want %q", exts, wantExts)
+	}
+}
+
+func TestIgnoreSources(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.part": "1", "b.tmp": "1", "c.nfo": "1", ".DS_Store": "1"},
+		nil)
+	t.Setenv(envIgnore, "*.part,*.tmp")
+	t.Setenv(envIgnoreExt, "nfo")
+
+	// The flags negate one entry from the environment and one built-in name.
+	stdout, _, _ := run(t, "-f", "csv", "--ignore-name", "!*.part,!.DS_Store", source, target)
+	for _, want := range []string{"MISSING,a.part,", "MISSING,.DS_Store,"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout = %q, want %q", stdout, want)
+		}
+	}
+	for _, notWant := range []string{"b.tmp", "c.nfo"} {
+		if strings.Contains(stdout, notWant) {
+			t.Errorf("stdout = %q, want %s ignored", stdout, notWant)
+		}
+	}
+}
+
+func TestIgnoreNamePatterns(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "a.mkv.part": "1", ".cache/x.jpg": "1", "~$draft.docx": "1"},
+		map[string]string{"a.mkv": "1", ".mddiff.yaml": "ignore_names: ['.cache*']\n"})
+
+	stdout, _, code := run(t, "-f", "csv", "--ignore-name", "*.part,~$*", source, target)
+	if code != 0 {
+		t.Errorf("exit status %d, stdout %q, want the patterns and the target's config to hide every difference",
+			code, stdout)
+	}
+	stdout, _, _ = run(t, "-f", "csv", "--no-config", source, target)
+	for _, want := range []string{"MISSING,a.mkv.part,", "MISSING,.cache/x.jpg,", "MISSING,~$draft.docx,"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("without patterns, stdout %q, want %q", stdout, want)
+		}
+	}
+}
+
+func TestUserConfig(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"b.mkv": "1"})
+	cwd, home := t.TempDir(), t.TempDir()
+	write := func(dir, content string) string {
+		path := filepath.Join(dir, config.DirConfigName)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	const (
+		csvHeader = "type,path,reason,src_size,tgt_size\n"
+		counts    = "MISSING=1 MODIFIED=0 EXTRA=1 RENAMED=0 TOTAL=2\n"
+	)
+
+	write(home, "format: [counts]\n")
+	if stdout, stderr, _ := runInHome(t, cwd, home, source, target); stdout != counts {
+		t.Errorf("home config: stdout %q, stderr %q, want %q", stdout, stderr, counts)
+	}
+	// The working directory's config wins over the home directory's.
+	write(cwd, "format: [csv]\n")
+	if stdout, _, _ := runInHome(t, cwd, home, source, target); !strings.HasPrefix(stdout, csvHeader) {
+		t.Errorf("working directory config: stdout %q, want CSV", stdout)
+	}
+	// Flags win over the file.
+	if stdout, _, _ := runInHome(t, cwd, home, "-f", "counts", source, target); stdout != counts {
+		t.Errorf("--format: stdout %q, want %q", stdout, counts)
+	}
+	// --config reads another file, and --no-config none.
+	other := write(t.TempDir(), "format: [counts]\nignore_names: [b.mkv]\n")
+	want := "MISSING=1 MODIFIED=0 EXTRA=0 RENAMED=0 TOTAL=1\n"
+	if stdout, _, _ := runInHome(t, cwd, home, "--config", other, source, target); stdout != want {
+		t.Errorf("--config: stdout %q, want %q", stdout, want)
+	}
+	if stdout, _, _ := runInHome(t, cwd, home, "--no-config", source, target); !strings.Contains(stdout, "TYPE") {
+		t.Errorf("--no-config: stdout %q, want the default table", stdout)
+	}
+
+	_, stderr, code := runInHome(t, cwd, home, "--config", other, "--no-config", source, target)
+	wantError(t, stderr, code, "--config can't be combined with --no-config")
+	_, stderr, code = runInHome(t, cwd, home, "--config", filepath.Join(cwd, "nope.yaml"), source, target)
+	wantError(t, stderr, code, "invalid --config")
+	write(cwd, "compare: [bogus]\n")
+	_, stderr, code = runInHome(t, cwd, home, source, target)
+	wantError(t, stderr, code, "invalid --compare: bogus")
+}
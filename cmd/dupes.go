@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/diff"
+	"mddiff/pkg/report"
+	"mddiff/pkg/scanner"
+)
+
+var (
+	dupesHash    bool
+	dupesMinSize string
+	dupesFormat  string
+	dupesOutput  string
+)
+
+// dupesCmd represents the dupes command.
+var dupesCmd = &cobra.Command{
+	Use:   "dupes path/to/dir",
+	Short: "Find duplicate files within a directory",
+	Long: `
+Scan a single directory and list groups of two or more files with the same
+size, largest first, such as the same movie saved under different names.
+
+Files of the same size aren't necessarily the same. Pass --hash to confirm
+each group by SHA-256, which reads every candidate file in full. --min-size
+leaves out small files, which often share sizes by chance.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDupes,
+}
+
+func init() {
+	rootCmd.AddCommand(dupesCmd)
+
+	dupesCmd.Flags().BoolVar(&dupesHash, "hash", false, "Only group files whose SHA-256 content hashes match")
+	dupesCmd.Flags().StringVar(&dupesMinSize, "min-size", "1B",
+		"Leave out files smaller than this, e.g. 1MB or 512KiB; the default leaves out empty files")
+	dupesCmd.Flags().StringVarP(&dupesFormat, "format", "f", "human", "Output format (human|json)")
+	dupesCmd.Flags().StringVarP(&dupesOutput, "output", "o", "", "Write the groups to a file instead of stdout")
+}
+
+func runDupes(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	if dupesFormat != "human" && dupesFormat != "json" {
+		return fmt.Errorf("invalid --format: %s (want human|json)", dupesFormat)
+	}
+	minSize, err := diff.ParseSize(dupesMinSize)
+	if err != nil {
+		return fmt.Errorf("invalid --min-size: %w", err)
+	}
+
+	root, err := filepath.Abs(filepath.Clean(args[0]))
+	if err != nil {
+		return fmt.Errorf("resolving path: %w", err)
+	}
+	tree, err := scanner.NewLinearScannerWithOptions(scanner.ScanOptions{MinSize: minSize}).Scan(root)
+	if err != nil {
+		return err
+	}
+	var hasher *diff.HashComparator
+	if dupesHash {
+		hasher = &diff.HashComparator{Limiter: checksum.NewLimiter(0)}
+	}
+	groups, err := diff.GroupDuplicates(tree, hasher)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if dupesOutput != "" {
+		f, err := os.Create(dupesOutput) // #nosec G304 -- path is chosen by the user
+		if err != nil {
+			return fmt.Errorf("creating output file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	return report.WriteDuplicateGroups(out, root, groups, dupesFormat == "json")
+}
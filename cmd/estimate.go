@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"mddiff/pkg/domain"
+	"mddiff/pkg/scanner"
+)
+
+// treeTotals counts the files in tree and their combined size.
+func treeTotals(tree *domain.DirectoryTree) (files int, bytes int64) {
+	for _, asset := range tree.Assets {
+		if !asset.IsDir {
+			files++
+			bytes += asset.Size
+		}
+	}
+	return files, bytes
+}
+
+// runEstimate does a metadata-only scan of each directory and prints how much
+// work a full run would do, without diffing or hashing anything. When hashing
+// is enabled it also estimates the hashing time from throughputMBps, assuming
+// every file on both sides is read once.
+func runEstimate(w io.Writer, args []string, withHash bool, throughputMBps float64) error {
+	s := scanner.NewLinearScanner()
+
+	var totalBytes int64
+	for _, arg := range args {
+		path, err := filepath.Abs(filepath.Clean(arg))
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", arg, err)
+		}
+		tree, err := s.Scan(path)
+		if err != nil {
+			return err
+		}
+		files, bytes := treeTotals(tree)
+		totalBytes += bytes
+		fmt.Fprintf(w, "%s: %d files, %d bytes\n", path, files, bytes)
+	}
+
+	if withHash && throughputMBps > 0 {
+		seconds := float64(totalBytes) / (throughputMBps * 1e6)
+		fmt.Fprintf(w, "Estimated hashing time: %s at %g MB/s\n",
+			time.Duration(seconds*float64(time.Second)).Round(time.Second), throughputMBps)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunEstimate(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "12345", "show/ep1.mkv": "123"},
+		map[string]string{"a.mkv": strings.Repeat("x", 2_000_000)})
+
+	tests := []struct {
+		name     string
+		withHash bool
+		want     []string
+	}{
+		{
+			name: "counts only",
+			want: []string{source + ": 2 files, 8 bytes\n", target + ": 1 files, 2000000 bytes\n"},
+		},
+		{
+			name:     "hashing time",
+			withHash: true,
+			want:     []string{"Estimated hashing time: 2s at 1 MB/s\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := runEstimate(&buf, []string{source, target}, tt.withHash, 1); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output = %q, want %q", out, want)
+				}
+			}
+			if !tt.withHash && strings.Contains(out, "Estimated") {
+				t.Errorf("output = %q, want no time estimate without hashing", out)
+			}
+		})
+	}
+
+	if err := runEstimate(&bytes.Buffer{}, []string{filepath.Join(source, "nope")}, false, 1); err == nil {
+		t.Error("runEstimate of a missing directory succeeded")
+	}
+}
+
+func TestEstimateFlag(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, nil)
+	stdout, stderr, code := run(t, "--estimate", source, target)
+	if code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	if !strings.Contains(stdout, source+": 1 files, 1 bytes") || strings.Contains(stdout, "MISSING") {
+		t.Errorf("stdout = %q, want only the estimate", stdout)
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"mddiff/pkg/config"
+)
+
+// Environment variables holding comma-separated ignore entries.
+const (
+	envIgnore    = "MDDIFF_IGNORE"
+	envIgnoreExt = "MDDIFF_IGNORE_EXT"
+)
+
+// ignoreEntries merges every source of ignore entries into the ordered lists
+// the scanner applies. Sources go lowest precedence first, so an entry in a
+// later source can negate one from an earlier source with a "!" prefix:
+//
+//  1. the scanner's built-in OS and editor metadata names, unless
+//     --no-default-ignores is set
+//  2. MDDIFF_IGNORE and MDDIFF_IGNORE_EXT
+//  3. the user's .mddiff.yaml or --config file, then the target's
+//     .mddiff.yaml, as merged into cfg
+//  4. --ignore-name and --ignore-ext
+//
+// The .mddiff.yaml file itself is always ignored.
+func ignoreEntries(cfg *config.Config) (names, exts []string) {
+	names = append(names, splitList(os.Getenv(envIgnore))...)
+	names = append(names, cfg.IgnoreNames...)
+	names = append(names, ignoreNames...)
+	names = append(names, config.DirConfigName)
+
+	exts = append(exts, splitList(os.Getenv(envIgnoreExt))...)
+	exts = append(exts, cfg.IgnoreExt...)
+	exts = append(exts, ignoreExt...)
+	return names, exts
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for entry := range strings.SplitSeq(s, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
@@ -0,0 +1,11 @@
+package cmd
+
+import (
+	"log"
+	"os"
+)
+
+// diag writes diagnostics: errors, warnings, verdicts, --verbose statistics
+// and progress. It writes to stderr so stdout only ever carries the report,
+// and is safe to use from the progress goroutine.
+var diag = log.New(os.Stderr, "", 0)
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifestFormat(t *testing.T) {
+	dir := writeTree(t, t.TempDir(), map[string]string{"a b.mkv": "hello", "show/ep1.mkv": "hello"})
+	const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	stdout, stderr, code := run(t, "manifest", "--manifest-format", "sha256sum", dir)
+	if code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	want := helloSHA256 + "  a b.mkv\n" + helloSHA256 + "  show/ep1.mkv\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	stdout, _, _ = run(t, "manifest", "--manifest-format", "tsv", dir)
+	if !strings.HasPrefix(stdout, "path\tsize\tsha256\na b.mkv\t5\t"+helloSHA256+"\n") {
+		t.Errorf("stdout = %q, want a TSV table", stdout)
+	}
+
+	_, stderr, code = run(t, "manifest", "--manifest-format", "sfv", dir)
+	wantError(t, stderr, code, "invalid --manifest-format: sfv (want json|md5sum|sha256sum|tsv)")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"mddiff/pkg/report"
+)
+
+// reportFiles maps each --format to the file it is written to under
+// --output-dir. human and table are the same format.
+var reportFiles = map[string]string{
+	"human":    "report.txt",
+	"table":    "report.txt",
+	"json":     "report.json",
+	"ndjson":   "report.ndjson",
+	"tree":     "report-tree.txt",
+	"counts":   "report-counts.txt",
+	"csv":      "report.csv",
+	"html":     "report.html",
+	"markdown": "report.md",
+	"junit":    "report-junit.xml",
+	"sarif":    "report.sarif",
+}
+
+// formatNames lists the --format values for help and error messages.
+const formatNames = "human|table|json|ndjson|tree|counts|csv|html|markdown|junit|sarif"
+
+// itemOnlyFormats list items and have no summary to fall back to, so they
+// can't be used with --summary-only.
+var itemOnlyFormats = map[string]bool{"csv": true, "junit": true, "sarif": true}
+
+// output is a reporter and the writer it reports to.
+type output struct {
+	reporter report.Reporter
+	w        io.Writer
+}
+
+// validateFormats checks --format, --output and --output-dir.
+func validateFormats(cmd *cobra.Command) error {
+	seen := make(map[string]string)
+	for _, f := range formats {
+		name, ok := reportFiles[f]
+		if !ok {
+			return fmt.Errorf("invalid --format: %s (want %s)", f, formatNames)
+		}
+		if changedDirs && f != "human" && f != "table" && f != "json" {
+			return fmt.Errorf("--format %s can't be combined with --changed-dirs (want human|table|json)", f)
+		}
+		if summaryOnly && itemOnlyFormats[f] {
+			return fmt.Errorf("--format %s lists files and can't be combined with --summary-only", f)
+		}
+		if prev, ok := seen[name]; ok {
+			return fmt.Errorf("--format %s and %s both write %s", prev, f, name)
+		}
+		seen[name] = f
+	}
+	switch {
+	case len(formats) == 0:
+		return fmt.Errorf("invalid --format: no format given (want %s)", formatNames)
+	case outputPath != "" && outputDir != "":
+		return errors.New("--output and --output-dir can't be combined")
+	case len(formats) > 1 && outputDir == "":
+		return fmt.Errorf("--format %s writes more than one report and requires --output-dir",
+			strings.Join(formats, ","))
+	case len(formats) > 1 && combinedJSON:
+		return errors.New("--combined-json only writes JSON and can't be combined with several formats")
+	case combinedJSON && cmd.Flags().Changed("format") && formats[0] != "json":
+		return fmt.Errorf("--format %s can't be combined with --combined-json, which only writes JSON", formats[0])
+	case summaryOnly && combinedJSON:
+		return errors.New("--summary-only can't be combined with --combined-json")
+	case changedDirs && combinedJSON:
+		return errors.New("--changed-dirs can't be combined with --combined-json")
+	}
+	return nil
+}
+
+// openOutputs builds a reporter for every --format and opens where it writes:
+// stdout, the --output file, or its file under --output-dir. The returned
+// function closes any files opened. With --quiet there are no outputs.
+func openOutputs(opts report.Options) ([]output, func(), error) {
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	if quiet {
+		return nil, closeAll, nil
+	}
+
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0o750); err != nil {
+			return nil, nil, fmt.Errorf("creating output directory: %w", err)
+		}
+	}
+	outputs := make([]output, 0, len(formats))
+	for _, f := range formats {
+		reporter, err := report.NewReporter(f, opts)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+
+		path := outputPath
+		if outputDir != "" {
+			path = filepath.Join(outputDir, reportFiles[f])
+		}
+		if path == "" {
+			outputs = append(outputs, output{reporter, os.Stdout})
+			continue
+		}
+		file, err := os.Create(path) // #nosec G304 -- path is chosen by the user
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("creating output file: %w", err)
+		}
+		files = append(files, file)
+		outputs = append(outputs, output{reporter, file})
+	}
+	return outputs, closeAll, nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"mddiff/pkg/scanner"
+)
+
+// progressInterval is how often the live scan progress on a terminal is
+// redrawn.
+const progressInterval = 200 * time.Millisecond
+
+// progressEvery is how many entries apart scan progress lines are printed
+// with --verbose when stderr isn't a terminal.
+const progressEvery = 10000
+
+// stderrIsTerminal reports whether stderr is a character device such as a
+// TTY.
+func stderrIsTerminal() bool {
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressLines returns an OnProgress callback that prints p's status on its
+// own line every progressEvery entries, for logs that can't redraw a line.
+func progressLines(p *scanner.Progress) func(int) {
+	return func(scanned int) {
+		if scanned%progressEvery == 0 {
+			diag.Println(formatProgress(p.Status(time.Now())))
+		}
+	}
+}
+
+// startProgress counts the entries on the local sides in the background, so
+// the scan can start right away. With live set, it also redraws p's status
+// in place on stderr every progressInterval until the returned function is
+// first called, which clears it.
+func startProgress(
+	ctx context.Context, s *scanner.LinearScanner, p *scanner.Progress, live bool, sides ...side,
+) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		var total int64
+		for _, sd := range sides {
+			if sd.remote != nil || sd.baseline {
+				continue
+			}
+			n, err := s.Count(ctx, sd.path)
+			if err != nil {
+				return
+			}
+			total += n
+		}
+		p.SetTotal(total)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if !live {
+			<-ctx.Done()
+			return
+		}
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Fprint(diag.Writer(), "\r\x1b[K")
+				return
+			case now := <-ticker.C:
+				fmt.Fprint(diag.Writer(), "\r\x1b[K"+formatProgress(p.Status(now)))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
+
+func formatProgress(st scanner.ProgressStatus) string {
+	if st.Total == 0 {
+		return fmt.Sprintf("Scanned %d entries (%.0f/s)", st.Done, st.Rate)
+	}
+	eta := "unknown"
+	if st.ETA >= 0 {
+		eta = st.ETA.Round(time.Second).String()
+	}
+	return fmt.Sprintf("Scanned %d of %d entries (%.0f/s, ETA %s)", st.Done, st.Total, st.Rate, eta)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"mddiff/pkg/scanner"
+)
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		st   scanner.ProgressStatus
+		want string
+	}{
+		{scanner.ProgressStatus{Done: 42, Rate: 10.4, ETA: -1}, "Scanned 42 entries (10/s)"},
+		{scanner.ProgressStatus{Done: 42, Total: 100, ETA: -1}, "Scanned 42 of 100 entries (0/s, ETA unknown)"},
+		{
+			scanner.ProgressStatus{Done: 42, Total: 100, Rate: 20, ETA: 2900 * time.Millisecond},
+			"Scanned 42 of 100 entries (20/s, ETA 3s)",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatProgress(tt.st); got != tt.want {
+			t.Errorf("formatProgress(%+v) = %q, want %q", tt.st, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeRemote puts an ssh and an mddiff on the PATH, so an ssh:// side runs
+// the test binary on this machine through sh, as sshd would on another.
+func fakeRemote(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"ssh": "#!/bin/sh\nwhile [ \"$1\" != \"--\" ]; do shift; done\nshift 2\nexec sh -c \"$*\"\n",
+		// run sets runMainEnv, so the test binary runs as mddiff.
+		"mddiff": "#!/bin/sh\nexec '" + os.Args[0] + "' \"$@\"\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o700); err != nil { // #nosec G306 -- test script
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRemoteSide(t *testing.T) {
+	fakeRemote(t)
+	root := t.TempDir()
+	source := writeTree(t, filepath.Join(root, "source"), map[string]string{"a.mkv": "1"})
+	target := writeTree(t, filepath.Join(root, "My Backup"), map[string]string{
+		"a.mkv": "1", "a.nfo": "info", "extras/b.mkv": "2",
+	})
+
+	stdout, stderr, _ := run(t, "-f", "csv", source, "ssh://localhost"+target)
+	want := "type,path,reason,src_size,tgt_size\n" +
+		"EXTRA,a.nfo,,0,4\n" +
+		"EXTRA,extras/b.mkv,,0,1\n"
+	if stdout != want {
+		t.Fatalf("stdout = %q, want %q (stderr %q)", stdout, want, stderr)
+	}
+
+	// Scan options apply to the remote side too.
+	stdout, stderr, code := run(t, "-f", "csv", "--ignore-ext", ".nfo", "--exclude", "extras",
+		source, "ssh://localhost"+target)
+	want = "type,path,reason,src_size,tgt_size\n"
+	if code != 0 || stdout != want {
+		t.Errorf("exit status %d, stdout %q, want 0 and %q (stderr %q)", code, stdout, want, stderr)
+	}
+}
@@ -4,35 +4,217 @@ Package cmd is the CLI package for mddiff.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/config"
+	"mddiff/pkg/diff"
+	"mddiff/pkg/domain"
+	"mddiff/pkg/pipeline"
+	"mddiff/pkg/remote"
+	"mddiff/pkg/report"
+	"mddiff/pkg/scanner"
+)
+
+var (
+	formats       []string
+	hash          bool
+	compareModes  []string
+	mtimeTol      time.Duration
+	samplePercent float64
+	sampleSeed    int64
+	maxOpenFiles  int
+	workers       int
+
+	detectDuplicates  bool
+	duplicatePatterns []string
+
+	combinedJSON bool
+
+	detectMoves    bool
+	detectRenames  bool
+	countRenamesAs string
+
+	verifySuperset bool
+
+	sizeThreshold    string
+	sizeThresholdPct float64
+	sizeThresholdMap string
+
+	ignoreCase       bool
+	normalizeUnicode bool
+
+	equivalentExts       string
+	noDefaultEquivalents bool
+
+	color         string
+	noColor       bool
+	humanReadable bool
+	junitPassing  bool
+	summaryOnly   bool
+	outputPath    string
+	outputDir     string
+
+	hashManifest      string
+	writeHashManifest string
+	cacheShards       int
+	hashCache         string
+
+	changedDirs bool
+
+	zeroBytePolicy string
+
+	useSyslog   bool
+	syslogItems bool
+
+	estimate       bool
+	hashThroughput float64
+
+	verbose bool
+	quiet   bool
+
+	configPath string
+	noConfig   bool
+
+	ignoreNames      []string
+	noDefaultIgnores bool
+	ignoreExt        []string
+	includeExt       []string
+	maxDepth         int
+	noRecursion      bool
+	ignoreFile       string
+	excludes         []string
+
+	followSymlinks bool
+	symlinkMode    string
+
+	minSize string
+	// minSizeBytes is --min-size as parsed by validateInputs.
+	minSizeBytes int64
+
+	quickHashSize string
+	// quickHashBytes is --quickhash-bytes as parsed by validateInputs.
+	quickHashBytes int64
+
+	normalizeExtDisplay bool
+
+	allowSame bool
+
+	editorLinks string
+
+	perceptualImages bool
+
+	stemsOnly bool
+
+	compareFileType bool
+
+	maxBaselineDrift   string
+	fromManifestSource bool
+
+	compareOwnership bool
+
+	separateExtChanges bool
+	separateEmptyDirs  bool
+
+	showMatched bool
+
+	textPreviewLines int
+
+	requireFullCoverage bool
+
+	anonymize     bool
+	absolutePaths bool
+
+	textCompareExts []string
+
+	deadline time.Duration
+
+	exitZero bool
+	failOn   []string
+
+	onlyTypes    []string
+	excludeTypes []string
+
+	sortKey     string
+	reverseSort bool
+	swapSides   bool
+
+	pathInclude []string
+	pathExclude []string
+	// pathIncludeRes and pathExcludeRes are --path-include and --path-exclude
+	// as compiled by validateInputs.
+	pathIncludeRes []*regexp.Regexp
+	pathExcludeRes []*regexp.Regexp
 )
 
-var format string
+// errVerificationFailed is returned when --verify-superset finds a source file
+// that is missing or modified in the target. The verdict has already been
+// printed, so Execute exits without repeating it.
+var errVerificationFailed = errors.New("backup verification failed")
+
+// errDifferencesFound is returned when a report has an item of a --fail-on
+// type, so the process exits with status 1. The report itself says what
+// differs.
+var errDifferencesFound = errors.New("differences found")
+
+// errTruncated is returned when --deadline stopped the run before every file
+// was compared, even with --exit-zero, since the reports can't be trusted to
+// show every difference.
+var errTruncated = errors.New("--deadline reached before every file was compared; the report is incomplete")
 
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
-	Use:   "mddiff path/to/dir1 path/to/dir2",
+	Use:   "mddiff path/to/dir1 path/to/dir2 [path/to/dir3 path/to/dir4 ...]",
 	Short: "A media directory diff tool",
 	Long: `
 mddiff is a tool for comparing two media directories and identifying
 differences between them. Since mddiff knows the directories being compared
 are media directories, it be smarter about how it compares the files within
 them. For example, it can understand the difference between two completely
-different video files and two different encodings of the same video file.`,
-	Args:    cobra.ExactArgs(2),
+different video files and two different encodings of the same video file.
+
+Several source/target pairs can be compared in one run by passing them in
+order; each pair gets its own report.
+
+Either directory can be on another machine, given as
+ssh://[user@]host[:port]/path. mddiff must be installed on that host; it is run
+there over ssh to scan the directory.
+
+Either directory can also be a baseline: a file saved earlier with
+"mddiff manifest -o baseline.json". A file argument is read as one, and
+--from-manifest-source makes that a requirement for the source. Use
+--max-baseline-drift to fail only when more than an expected number of files
+changed since the baseline.
+
+mddiff exits with status 0 when the directories match, 1 when they differ or
+an error occurs. Use --exit-zero to exit 0 whenever the reports were written.`,
+	Args:    pairArgs,
 	PreRunE: validateInputs,
-	Run:     mddiff,
+	RunE:    runDiff,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.SilenceErrors = true
 	err := rootCmd.Execute()
 	if err != nil {
+		if !errors.Is(err, errVerificationFailed) && !errors.Is(err, errDifferencesFound) {
+			diag.Println("Error:", err)
+		}
 		os.Exit(1)
 	}
 }
@@ -44,38 +226,1118 @@ func init() {
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
-	rootCmd.Flags().StringVarP(&format, "format", "f", "human", "Output format (human|json)")
+	rootCmd.Flags().StringSliceVarP(&formats, "format", "f", []string{"human"},
+		"Output format ("+formatNames+"); give several, e.g. json,html, to write each to --output-dir")
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print comparison statistics to stderr")
+	rootCmd.Flags().StringVar(&configPath, "config", "",
+		"Read default settings from this file instead of .mddiff.yaml in the working or home directory")
+	rootCmd.Flags().BoolVar(&noConfig, "no-config", false,
+		"Ignore every .mddiff.yaml, including the target's, and use only flags and defaults")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false,
+		"Print nothing but errors and only set the exit status; no report, warnings or progress are written")
+	rootCmd.Flags().BoolVarP(&humanReadable, "human-readable", "H", false,
+		"Show sizes in binary units, e.g. 4.5 GiB, instead of bytes; JSON adds them next to the byte counts")
+	rootCmd.Flags().BoolVar(&summaryOnly, "summary-only", false,
+		"Print only the summary counts and totals, without the individual files")
+	rootCmd.Flags().StringSliceVar(&ignoreNames, "ignore-name", nil,
+		`File or directory names or globs, e.g. *.part, to skip; prefix with "!" to stop ignoring one ignored by default, `+
+			"MDDIFF_IGNORE or .mddiff.yaml")
+	rootCmd.Flags().BoolVar(&noDefaultIgnores, "no-default-ignores", false,
+		"Scan OS and editor metadata such as .DS_Store and .git, which are skipped by default")
+	rootCmd.Flags().StringSliceVar(&ignoreExt, "ignore-ext", nil,
+		`File extensions to skip, e.g. .nfo,.txt; case-insensitive, leading dot optional, "!" prefix to un-ignore`)
+	rootCmd.Flags().StringVar(&ignoreFile, "ignore-file", "",
+		"Read gitignore-style patterns from this file instead of each directory's "+scanner.IgnoreFileName)
+	rootCmd.Flags().StringVar(&minSize, "min-size", "",
+		`Skip files smaller than this on both sides, e.g. "1KB" (same units as --size-threshold)`)
+	rootCmd.Flags().StringVar(&symlinkMode, "symlinks", "compare",
+		"How symbolic links are handled (compare|ignore|follow): compare where they point, leave them out, "+
+			"or scan what they point to, descending into linked directories and skipping loops and broken links")
+	rootCmd.Flags().BoolVar(&followSymlinks, "follow-symlinks", false, "The same as --symlinks=follow")
+	rootCmd.Flags().IntVar(&maxDepth, "max-depth", -1,
+		"Only scan this many levels below each root; 0 compares just the root's immediate children, -1 everything")
+	rootCmd.Flags().BoolVar(&noRecursion, "no-recursion", false, "The same as --max-depth=0")
+	rootCmd.Flags().StringSliceVar(&includeExt, "include-ext", nil,
+		"Only scan files with these extensions, e.g. .flac,.mp3; --ignore-ext still applies")
+	rootCmd.Flags().StringArrayVar(&excludes, "exclude", nil,
+		`Glob of paths to skip (repeatable); "*sample*" matches names, "**/extras/*" matches paths from the root`)
+	rootCmd.Flags().BoolVar(&normalizeExtDisplay, "normalize-extensions-display", false,
+		"Show extensions in lowercase in reasons (matching is unaffected)")
+	rootCmd.Flags().BoolVar(&allowSame, "allow-same", false,
+		"Warn instead of failing when source and target are the same directory")
+	rootCmd.Flags().BoolVar(&exitZero, "exit-zero", false,
+		"Exit with status 0 even when differences are found; errors still exit 1")
+	rootCmd.Flags().StringSliceVar(&failOn, "fail-on", []string{"missing", "extra", "modified"},
+		"Differences that make mddiff exit with status 1 (missing,extra,modified or none)")
+	rootCmd.Flags().StringSliceVar(&onlyTypes, "only", nil,
+		"Only show these types of item (missing,extra,modified,renamed,moved,emptydir,matched); "+
+			"summary counts still cover all")
+	rootCmd.Flags().StringSliceVar(&excludeTypes, "exclude-type", nil,
+		"Hide these types of item (missing,extra,modified,renamed,moved,emptydir,matched); "+
+			"summary counts still cover all")
+	rootCmd.Flags().StringVar(&sortKey, "sort", string(diff.SortByType),
+		"Order of the listed items (type|path|size); type groups them by type, then path, and size lists the largest first")
+	rootCmd.Flags().BoolVar(&reverseSort, "reverse", false, "Reverse the --sort order")
+	rootCmd.Flags().BoolVar(&swapSides, "swap", false,
+		"Compare each pair the other way round, treating the second directory as the source")
+	rootCmd.Flags().StringArrayVar(&pathInclude, "path-include", nil,
+		"Only show items whose path matches this regular expression; can be repeated")
+	rootCmd.Flags().StringArrayVar(&pathExclude, "path-exclude", nil,
+		"Hide items whose path matches this regular expression, even if --path-include matches; can be repeated")
+	rootCmd.Flags().DurationVar(&deadline, "deadline", 0,
+		"Stop after this long, e.g. 30m, and write whatever was compared so far as an incomplete report")
+	rootCmd.Flags().BoolVar(&anonymize, "anonymize", false,
+		"Replace file and directory names in the report with aliases, keeping extensions, so it can be shared")
+	rootCmd.Flags().BoolVar(&absolutePaths, "absolute-paths", false,
+		"Report full paths under the source root, or the target root for extra files, instead of relative ones")
+	rootCmd.Flags().BoolVar(&requireFullCoverage, "require-full-coverage", false,
+		"Fail if any file or directory couldn't be read; by default they are skipped with a warning")
+	rootCmd.Flags().IntVar(&textPreviewLines, "text-preview", 0,
+		"Show the first N lines of a diff for modified --text-compare files (20 if N is omitted)")
+	rootCmd.Flags().Lookup("text-preview").NoOptDefVal = "20"
+	rootCmd.Flags().StringSliceVar(&textCompareExts, "text-compare", nil,
+		"Compare files with these extensions by content, e.g. .srt,.txt, reporting line-ending-only changes separately")
+	rootCmd.Flags().BoolVar(&separateExtChanges, "separate-ext-changes", false,
+		"Report extension changes, e.g. remuxes, as EXT_CHANGED instead of MODIFIED")
+	rootCmd.Flags().BoolVar(&separateEmptyDirs, "separate-empty-dirs", false,
+		"Report empty directories found on only one side as EMPTY_DIR instead of MISSING or EXTRA")
+	rootCmd.Flags().BoolVar(&showMatched, "show-matched", false,
+		"Also list every unchanged file as MATCHED; on large trees the report gets as long as the file count. "+
+			"With --sample-percent, files outside the sample are marked as not verified")
+	rootCmd.Flags().BoolVar(&compareOwnership, "compare-ownership", false,
+		"Report files whose owner or group differs as modified (not supported on Windows)")
+	rootCmd.Flags().StringVar(&maxBaselineDrift, "max-baseline-drift", "",
+		`When comparing against a baseline, fail only if more than this many files changed, or this percentage, `+
+			`e.g. "2%"; fewer changes exit 0`)
+	rootCmd.Flags().BoolVar(&fromManifestSource, "from-manifest-source", false,
+		`Require each source to be a manifest saved with "mddiff manifest" rather than a directory`)
+	rootCmd.Flags().BoolVar(&compareFileType, "compare-file-type", false,
+		"Report a file that is a symlink on one side and a regular file on the other as modified")
+	rootCmd.Flags().BoolVar(&stemsOnly, "stems-only", false,
+		"Only report titles (directory and name without extension) missing from either side; ignore sizes and extensions")
+	rootCmd.Flags().BoolVar(&perceptualImages, "perceptual-images", false,
+		"Treat images (jpg, png, gif) that look the same as unchanged, even if rescaled or recompressed")
+	rootCmd.Flags().StringVar(&editorLinks, "editor-links", "",
+		"Add a link that opens each item's file in an editor ("+strings.Join(report.Editors(), "|")+")")
+	rootCmd.Flags().StringVar(&color, "color", "auto",
+		"Color table output (auto|always|never); auto disables color when not writing to a terminal or NO_COLOR is set")
+	rootCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable color, the same as --color=never")
+	rootCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the report to a file instead of stdout")
+	rootCmd.Flags().BoolVar(&junitPassing, "junit-passing", false,
+		"With --format junit, report extra and matched files as passing test cases instead of skipped ones")
+	rootCmd.Flags().StringVar(&outputDir, "output-dir", "",
+		"Write each --format to a file in this directory, e.g. report.json and report.html")
+	rootCmd.Flags().StringVar(&hashManifest, "hash-manifest", "",
+		"Reuse source hashes from this manifest for files whose size and mtime are unchanged")
+	rootCmd.Flags().StringVar(&writeHashManifest, "write-hash-manifest", "",
+		"Hash every source file and save the digests to this manifest for later --hash-manifest runs")
+	rootCmd.Flags().IntVar(&cacheShards, "cache-shards", 1,
+		"Split hash manifests across this many files, e.g. hashes.0.json, hashes.1.json, ...")
+	rootCmd.Flags().StringVar(&hashCache, "hash-cache", "",
+		"Remember hashes in this file, created if missing, and skip rehashing files with unchanged size and mtime")
+	rootCmd.Flags().BoolVar(&changedDirs, "changed-dirs", false,
+		"Only list the directories that contain differences, with per-directory counts; "+
+			"only the human and json formats support it")
+	rootCmd.Flags().StringVar(&zeroBytePolicy, "zero-byte-policy", "match",
+		"Handling of zero-byte files: match (compare by size as usual), flag (always modified), or ignore (skip)")
+	rootCmd.Flags().BoolVar(&useSyslog, "syslog", false, "Also send a summary of each report to the local syslog")
+	rootCmd.Flags().BoolVar(&syslogItems, "syslog-items", false, "With --syslog, also send each item")
+	rootCmd.Flags().BoolVar(&estimate, "estimate", false,
+		"Only count the files and bytes to process (and hashing time with --hash), then exit")
+	rootCmd.Flags().Float64Var(&hashThroughput, "hash-throughput", 100,
+		"Assumed hashing throughput in MB/s for --estimate")
+	rootCmd.Flags().BoolVar(&hash, "hash", false, "Verify matched files by SHA-256 content hash")
+	rootCmd.Flags().StringSliceVar(&compareModes, "compare", []string{"size"},
+		"How matched files are compared, e.g. size,mtime (size|hash|quickhash|content|mtime); size is always "+
+			"checked, hash also checks content (the same as --hash), quickhash only the start and end of each file, "+
+			"content every byte, stopping at the first difference, and mtime modification times")
+	rootCmd.Flags().DurationVar(&mtimeTol, "mtime-tolerance", diff.DefaultMTimeTolerance,
+		"With --compare=mtime, how far apart modification times may be before files count as modified")
+	rootCmd.Flags().StringVar(&quickHashSize, "quickhash-bytes", "4MB",
+		"With --compare=quickhash, how much of the start and of the end of each file to hash")
+	rootCmd.Flags().IntVar(&maxOpenFiles, "max-open-files", 0,
+		"Maximum files open at once while hashing (default: half the open file limit)")
+	rootCmd.Flags().IntVar(&workers, "workers", runtime.NumCPU(),
+		"Number of goroutines reading file metadata while scanning")
+	rootCmd.Flags().Float64Var(&samplePercent, "sample-percent", 0,
+		"With --hash, only verify a random sample of this percentage of matched files")
+	rootCmd.Flags().Int64Var(&sampleSeed, "sample-seed", 0,
+		"Seed for --sample-percent selection (default: random, printed in the report)")
+	rootCmd.Flags().BoolVar(&detectDuplicates, "detect-duplicates", false,
+		`Report files that look like accidental copies, e.g. "Movie (1).mkv" next to "Movie.mkv"`)
+	rootCmd.Flags().StringArrayVar(&duplicatePatterns, "duplicate-pattern", diff.DefaultDuplicatePatterns,
+		"Regex matching a duplicate suffix at the end of a stem (repeatable)")
+	rootCmd.Flags().BoolVar(&combinedJSON, "combined-json", false,
+		"Write the reports for all pairs as a single JSON array (implies --format json)")
+	rootCmd.Flags().BoolVar(&detectMoves, "detect-moves", false,
+		"Report a missing and an extra file with the same name and size in different directories as a single move")
+	rootCmd.Flags().BoolVar(&detectRenames, "detect-renames", false,
+		"Report a missing and an extra file with the same extension and size as a single rename; "+
+			"with --hash, their content must match too")
+	rootCmd.Flags().StringVar(&countRenamesAs, "count-renames-as", "separate",
+		"How renames count in the summary (separate|modified)")
+	rootCmd.Flags().BoolVar(&verifySuperset, "verify-superset", false,
+		"Only succeed if every source file exists unchanged in the target; extra target files are allowed")
+	rootCmd.Flags().StringVar(&sizeThreshold, "size-threshold", "",
+		`Size difference tolerated before a file counts as modified, either way, e.g. "512", "1KB" or "5MiB"`)
+	rootCmd.Flags().Float64Var(&sizeThresholdPct, "size-threshold-percent", 0,
+		"Size difference tolerated as a percentage of the larger file, e.g. 2; replaces --size-threshold")
+	rootCmd.Flags().BoolVar(&ignoreCase, "ignore-case", false,
+		`Match files whose directory and name differ only in case, reporting them as modified with "Case differs"`)
+	rootCmd.Flags().BoolVar(&normalizeUnicode, "normalize-unicode", false,
+		"Match names that only differ in Unicode normalization, e.g. accents decomposed by macOS (NFD) and composed (NFC)")
+	rootCmd.Flags().StringVar(&equivalentExts, "equivalent-ext", "",
+		`Extensions that name the same format, so changing between them isn't a modification, e.g. ".m2ts=.mts"; `+
+			"added to the defaults: "+diff.DefaultExtEquivalents)
+	rootCmd.Flags().BoolVar(&noDefaultEquivalents, "no-default-equivalents", false,
+		"Don't treat the default --equivalent-ext groups as the same format")
+	rootCmd.Flags().StringVar(&sizeThresholdMap, "size-threshold-map", "",
+		`Per-extension size tolerance in bytes or percent, e.g. ".flac=0,.mp4=5%,*=1%"`)
 }
 
-func mddiff(cmd *cobra.Command, args []string) {
-	dir1 := args[0]
-	dir2 := args[1]
+func runDiff(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
 
-	format, _ := cmd.Flags().GetString("format")
+	if estimate {
+		return runEstimate(os.Stdout, args, hash, hashThroughput)
+	}
+	if quiet {
+		// Restored before Execute prints any error.
+		diag.SetOutput(io.Discard)
+		defer diag.SetOutput(os.Stderr)
+	}
 
-	dir1abs, err := filepath.Abs(filepath.Clean(dir1))
+	outputs, closeOutputs, err := openOutputs(report.Options{
+		Color:             report.ColorMode(color),
+		ChangedDirs:       changedDirs,
+		HumanReadable:     humanReadable,
+		JUnitPassing:      junitPassing,
+		SummaryOnly:       summaryOnly,
+		KeepOrder:         diff.SortKey(sortKey) != diff.SortByType || reverseSort,
+		RenamesAsModified: countRenamesAs == "modified",
+	})
 	if err != nil {
-		println("Error getting absolute path for dir1:", err.Error())
-		os.Exit(1)
+		return err
+	}
+	defer closeOutputs()
+
+	logger := dialSyslog()
+	if logger != nil {
+		defer func() { _ = logger.Close() }()
+	}
+
+	var anon *report.Anonymizer
+	if anonymize {
+		if anon, err = report.NewAnonymizer(); err != nil {
+			return fmt.Errorf("initializing --anonymize: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	var cache *checksum.Cache
+	if hashCache != "" {
+		if cache, err = checksum.LoadCache(hashCache); err != nil {
+			return err
+		}
+	}
+
+	var combined []domain.PairReport
+	verified := true
+	differs := false
+	var driftErr error
+	skipped := 0
+	truncated := false
+	// Pairs reached after the deadline are still reported, as empty
+	// truncated reports, so none go missing without notice.
+	for i := 0; i < len(args); i += 2 {
+		sourceArg, targetArg := args[i], args[i+1]
+		if swapSides {
+			sourceArg, targetArg = targetArg, sourceArg
+		}
+		result, err := diffPair(ctx, cmd, cache, sourceArg, targetArg)
+		if err != nil {
+			return err
+		}
+		diffReport := result.report
+		skipped += result.scanErrors
+		if anon != nil {
+			diffReport = anon.Report(diffReport)
+			sourceArg, targetArg = diffReport.SourceDir, diffReport.TargetDir
+		}
+		if diffReport.Truncated {
+			truncated = true
+			diag.Printf("Warning: --deadline reached; the report for %s and %s is incomplete\n",
+				args[i], args[i+1])
+		}
+
+		// A baseline pair under --max-baseline-drift passes or fails on its
+		// drift alone, so differences within the limit don't fail the run.
+		driftGated := maxBaselineDrift != "" && result.baselineFiles >= 0
+		if driftGated && driftErr == nil {
+			driftErr = checkDrift(diffReport, result.baselineFiles)
+		}
+
+		if logger != nil {
+			if err := report.SendToSyslog(logger, diffReport, syslogItems); err != nil {
+				diag.Println("Warning: writing to syslog:", err)
+			}
+		}
+
+		if verifySuperset && !printVerdict(diffReport) {
+			verified = false
+		}
+		if !driftGated && failsOn(diffReport) {
+			differs = true
+		}
+		filterItems(diffReport)
+		if diff.SortKey(sortKey) != diff.SortByType || reverseSort {
+			diff.SortItems(diffReport.Items, diff.SortKey(sortKey), reverseSort)
+		}
+
+		if absolutePaths {
+			diffReport = report.AbsolutePaths(diffReport)
+		}
+		if combinedJSON {
+			combined = append(combined, domain.PairReport{Source: sourceArg, Target: targetArg, Report: diffReport})
+			continue
+		}
+		for _, o := range outputs {
+			if err := o.reporter.Report(o.w, diffReport); err != nil {
+				return err
+			}
+		}
+	}
+
+	if combinedJSON {
+		// validateFormats allows only one output with --combined-json.
+		if err := report.WriteCombinedJSON(outputs[0].w, combined); err != nil {
+			return err
+		}
+	}
+	if cache != nil {
+		if err := cache.Save(hashCache); err != nil {
+			return err
+		}
+	}
+	if !verified {
+		return errVerificationFailed
 	}
-	dir2abs, err := filepath.Abs(filepath.Clean(dir2))
+	if truncated {
+		return errTruncated
+	}
+	if requireFullCoverage && skipped > 0 {
+		return fmt.Errorf("%d path(s) couldn't be read, so the comparison is incomplete (--require-full-coverage)",
+			skipped)
+	}
+	if driftErr != nil {
+		return driftErr
+	}
+	// --verify-superset decides the outcome itself, allowing extra files.
+	if differs && !verifySuperset && !exitZero {
+		return errDifferencesFound
+	}
+	return nil
+}
+
+// dialSyslog connects to syslog when --syslog is set. Failing to connect only
+// warns, since the report itself is still written.
+func dialSyslog() report.SyslogWriter {
+	if !useSyslog {
+		return nil
+	}
+	w, err := report.DialSyslog()
 	if err != nil {
-		println("Error getting absolute path for dir2:", err.Error())
-		os.Exit(1)
+		diag.Println("Warning: --syslog disabled:", err)
+		return nil
+	}
+	return w
+}
+
+// printVerdict writes a --verify-superset verdict for r to stderr, keeping
+// stdout clean for the report, and reports whether verification passed. A
+// renamed file counts as missing since its source path is absent, and so does
+// an empty directory missing from the target.
+func printVerdict(r *domain.DiffReport) bool {
+	if r.Truncated {
+		diag.Printf("Backup NOT VERIFIED: --deadline stopped the comparison of %s and %s before every file was checked\n",
+			r.SourceDir, r.TargetDir)
+		return false
+	}
+	var missing, modified int
+	for _, item := range r.Items {
+		switch item.Type {
+		case domain.Missing, domain.Renamed, domain.Moved:
+			missing++
+		case domain.EmptyDir:
+			if item.Reason == domain.EmptyDirInSource {
+				missing++
+			}
+		case domain.Modified, domain.ExtChanged:
+			modified++
+		}
+	}
+
+	if missing == 0 && modified == 0 {
+		diag.Printf("Backup VERIFIED: %s contains every file in %s\n", r.TargetDir, r.SourceDir)
+		return true
+	}
+	diag.Printf("Backup FAILED: %s is missing %d and has %d modified file(s) from %s\n",
+		r.TargetDir, missing, modified, r.SourceDir)
+	return false
+}
+
+// failsOn reports whether r has an item of a --fail-on type. As in
+// printVerdict, a renamed file counts as missing, and an extension change as
+// modified. An empty directory counts as missing or extra by its side. A
+// truncated report always fails, since it may be missing differences.
+func failsOn(r *domain.DiffReport) bool {
+	if r.Truncated {
+		return true
+	}
+	for _, item := range r.Items {
+		kind := "modified"
+		switch item.Type {
+		case domain.Missing, domain.Renamed, domain.Moved:
+			kind = "missing"
+		case domain.Extra:
+			kind = "extra"
+		case domain.EmptyDir:
+			kind = "missing"
+			if item.Reason == domain.EmptyDirInTarget {
+				kind = "extra"
+			}
+		case domain.Matched:
+			continue
+		}
+		if slices.Contains(failOn, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemTypes maps the names --only and --exclude-type accept to the diff types
+// they select. As in failsOn, an extension change is a modification.
+var itemTypes = map[string][]domain.DiffType{
+	"missing":  {domain.Missing},
+	"extra":    {domain.Extra},
+	"modified": {domain.Modified, domain.ExtChanged},
+	"renamed":  {domain.Renamed},
+	"moved":    {domain.Moved},
+	"matched":  {domain.Matched},
+	"emptydir": {domain.EmptyDir},
+}
+
+// selectsType reports whether any of the type names in names selects t.
+func selectsType(names []string, t domain.DiffType) bool {
+	for _, name := range names {
+		if slices.Contains(itemTypes[name], t) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether any of res matches path.
+func matchesAny(res []*regexp.Regexp, path string) bool {
+	return slices.ContainsFunc(res, func(re *regexp.Regexp) bool { return re.MatchString(path) })
+}
+
+// filterItems drops the items hidden by --only, --exclude-type,
+// --path-include and --path-exclude from r. Paths are matched with forward
+// slashes, and an excluded path stays hidden even if it is also included. It
+// runs after the exit status is decided, and leaves r.Summary alone, so that
+// hiding items never hides the fact that they exist.
+func filterItems(r *domain.DiffReport) {
+	if len(onlyTypes) == 0 && len(excludeTypes) == 0 && len(pathIncludeRes) == 0 && len(pathExcludeRes) == 0 {
+		return
+	}
+	kept := make([]domain.DiffItem, 0, len(r.Items))
+	for _, item := range r.Items {
+		if len(onlyTypes) > 0 && !selectsType(onlyTypes, item.Type) {
+			continue
+		}
+		if selectsType(excludeTypes, item.Type) {
+			continue
+		}
+		if len(pathIncludeRes) > 0 && !matchesAny(pathIncludeRes, item.Path) {
+			continue
+		}
+		if matchesAny(pathExcludeRes, item.Path) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	r.Items = kept
+}
+
+// compilePatterns compiles the --flag regular expressions in patterns.
+func compilePatterns(flag string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %q: %w", flag, p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// namedStats labels a StatsComparator with the stage it instruments.
+type namedStats struct {
+	name string
+	*diff.StatsComparator
+}
+
+// instrument wraps c in a StatsComparator when --verbose is set, recording it
+// in stats so its counters can be printed after the diff.
+func instrument(name string, c domain.AssetComparator, stats *[]namedStats) domain.AssetComparator {
+	if !verbose {
+		return c
+	}
+	sc := diff.NewStatsComparator(c)
+	*stats = append(*stats, namedStats{name: name, StatsComparator: sc})
+	return sc
+}
+
+// printStats writes the counters collected by instrument to stderr.
+func printStats(stats []namedStats) {
+	for _, s := range stats {
+		st := s.Stats()
+		reasons := make([]string, 0, len(st.ByReason))
+		for reason, n := range st.ByReason {
+			reasons = append(reasons, fmt.Sprintf("%s: %d", reason, n))
+		}
+		sort.Strings(reasons)
+		diag.Printf("%s: %d comparisons, %d modified [%s], %d unchanged in %s\n",
+			s.name, st.Calls, st.Modified, strings.Join(reasons, ", "), st.Unchanged, st.Elapsed)
+	}
+}
+
+// newEngine builds the diff engine from the command-line flags, falling back
+// to cfg for any setting whose flag wasn't given. With --verbose, the
+// comparators are instrumented and returned so their stats can be printed.
+// cache, when not nil, is shared by every hash comparison.
+func newEngine(cmd *cobra.Command, cfg *config.Config, cache *checksum.Cache) (*diff.Engine, []namedStats, error) {
+	var stats []namedStats
+
+	comparator := &diff.BasicComparator{
+		FlagZeroByte:         zeroBytePolicy == "flag",
+		CompareFileType:      compareFileType,
+		CompareLinkTargets:   symlinkMode == "compare",
+		LowercaseExtInReason: normalizeExtDisplay,
+	}
+	if cfg.SizeThreshold != nil {
+		comparator.SizeThreshold = *cfg.SizeThreshold
+	}
+	if sizeThreshold != "" {
+		n, err := diff.ParseSize(sizeThreshold)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --size-threshold: %w", err)
+		}
+		comparator.SizeThreshold = n
+	}
+	comparator.SizeThresholdPercent = sizeThresholdPct
+	groups := equivalentExts
+	if !noDefaultEquivalents {
+		groups = diff.DefaultExtEquivalents + "," + groups
+	}
+	equivalents, err := diff.ParseExtEquivalence(groups)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --equivalent-ext: %w", err)
+	}
+	comparator.EquivalentExts = equivalents
+	if sizeThresholdMap != "" {
+		thresholds, err := diff.ParseThresholdMap(sizeThresholdMap)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --size-threshold-map: %w", err)
+		}
+		comparator.ExtThresholds = thresholds
+	}
+	compare := withPerceptual(comparator)
+	if len(textCompareExts) > 0 {
+		compare = diff.NewTextComparator(compare, textCompareExts)
+	}
+	if compareOwnership {
+		compare = diff.NewOwnershipComparator(compare)
+	}
+	if stemsOnly {
+		compare = diff.NopComparator{}
+	}
+	opts := pipeline.Options{
+		Comparator:         instrument("compare", compare, &stats),
+		IgnoreCase:         ignoreCase,
+		NormalizeUnicode:   normalizeUnicode,
+		DetectMoves:        detectMoves,
+		DetectRenames:      detectRenames,
+		SeparateExtChanges: separateExtChanges,
+		SeparateEmptyDirs:  separateEmptyDirs,
+	}
+
+	modes, useHash := compareChecks(cmd, cfg)
+	// Checks beyond size re-examine the files the comparator found unchanged,
+	// cheapest first.
+	var verifiers []domain.AssetComparator
+	if slices.Contains(modes, "mtime") {
+		verifiers = append(verifiers, &diff.MTimeComparator{Tolerance: mtimeTol})
+	}
+	limiter := checksum.NewLimiter(maxOpenFiles)
+	if slices.Contains(modes, "quickhash") {
+		verifiers = append(verifiers, &diff.QuickHashComparator{Bytes: quickHashBytes, Limiter: limiter})
+	}
+	if slices.Contains(modes, "content") {
+		verifiers = append(verifiers, &diff.ContentComparator{Limiter: limiter})
+	}
+	hasher := &diff.HashComparator{Limiter: limiter, Cache: cache}
+	if useHash {
+		verifiers = append(verifiers, withPerceptual(hasher))
+	}
+	switch len(verifiers) {
+	case 0:
+	case 1:
+		opts.Verifier = instrument("verify", verifiers[0], &stats)
+	default:
+		opts.Verifier = instrument("verify", diff.NewCompositeComparator(verifiers...), &stats)
+	}
+
+	engine := pipeline.NewEngine(opts)
+	if useHash {
+		if detectRenames || detectMoves {
+			engine.RenameVerifier = hasher
+		}
+		engine.SamplePercent = samplePercent
+		engine.SampleSeed = sampleSeed
+		if !cmd.Flags().Changed("sample-seed") {
+			engine.SampleSeed = time.Now().UnixNano()
+		}
+	}
+	engine.CountRenamesAsModified = countRenamesAs == "modified"
+	engine.ShowMatched = showMatched
+
+	if detectDuplicates {
+		for _, p := range duplicatePatterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid --duplicate-pattern %q: %w", p, err)
+			}
+			engine.DuplicatePatterns = append(engine.DuplicatePatterns, re)
+		}
+	}
+	return engine, stats, nil
+}
+
+// compareChecks returns the --compare modes and whether to hash. A target's
+// config can choose the checks when the flags don't.
+func compareChecks(cmd *cobra.Command, cfg *config.Config) (modes []string, useHash bool) {
+	modes, useHash = compareModes, hash
+	if !cmd.Flags().Changed("hash") && !cmd.Flags().Changed("compare") {
+		if cfg.Compare != nil {
+			modes = cfg.Compare
+		}
+		useHash = slices.Contains(modes, "hash") || (cfg.Hash != nil && *cfg.Hash)
+	}
+	return modes, useHash
+}
+
+// withPerceptual wraps c so images are compared by how they look when
+// --perceptual-images is set.
+func withPerceptual(c domain.AssetComparator) domain.AssetComparator {
+	if !perceptualImages {
+		return c
+	}
+	return diff.NewPerceptualComparator(c)
+}
+
+// side is one directory argument: a local path, a directory on another host
+// given as ssh://[user@]host[:port]/path, or a baseline manifest file.
+type side struct {
+	path     string
+	remote   *remote.Target
+	baseline bool
+}
+
+func parseSide(arg string) (side, error) {
+	target, ok, err := remote.ParseTarget(arg)
+	if err != nil {
+		return side{}, err
+	}
+	if ok {
+		return side{path: target.Path, remote: &target}, nil
+	}
+
+	path, err := filepath.Abs(filepath.Clean(arg))
+	if err != nil {
+		return side{}, fmt.Errorf("resolving %s: %w", arg, err)
+	}
+	info, err := os.Stat(path)
+	return side{path: path, baseline: err == nil && info.Mode().IsRegular()}, nil
+}
+
+// scan scans the side, locally with s or over ssh for a remote side, or reads
+// a baseline. A local scan stops when ctx is done, returning the partial tree
+// and ctx's error. The remote host and the baseline record everything, so s's
+// options are applied to their trees here.
+func (sd side) scan(ctx context.Context, s *scanner.LinearScanner) (*domain.DirectoryTree, error) {
+	if sd.remote != nil {
+		tree, err := remote.NewScanner(*sd.remote).ScanContext(ctx, sd.path)
+		if err != nil {
+			return tree, err
+		}
+		return s.FilterTree(tree)
+	}
+	if sd.baseline {
+		tree, err := readBaseline(sd.path)
+		if err != nil {
+			return nil, err
+		}
+		return s.FilterTree(tree)
+	}
+	return s.ScanContext(ctx, sd.path)
+}
+
+// scanFilter builds the scanner's asset filter from the command-line flags. It
+// returns nil when no flag needs one.
+func scanFilter() func(domain.Asset) bool {
+	if zeroBytePolicy != "ignore" {
+		return nil
+	}
+	return func(a domain.Asset) bool {
+		return a.IsDir || a.Size > 0
+	}
+}
+
+// addEditorLinks sets the --editor-links URI on each item. Extra items link to
+// the target file and everything else to the source file; files on a remote
+// side get no link.
+func addEditorLinks(r *domain.DiffReport, source, target side) error {
+	for i, item := range r.Items {
+		sd := source
+		if item.Type == domain.Extra {
+			sd = target
+		}
+		if sd.remote != nil {
+			continue
+		}
+		link, err := report.EditorLink(editorLinks, filepath.Join(sd.path, item.Path))
+		if err != nil {
+			return err
+		}
+		r.Items[i].Link = link
+	}
+	return nil
+}
+
+// addTextPreviews sets a diff preview on each modified --text-compare file
+// that has the same path on both sides. Files that can't be read only warn.
+func addTextPreviews(r *domain.DiffReport, source, target side) {
+	if source.remote != nil || target.remote != nil || source.baseline || target.baseline {
+		return
+	}
+	exts := make(map[string]bool, len(textCompareExts))
+	for _, ext := range textCompareExts {
+		exts[strings.ToLower("."+strings.TrimPrefix(ext, "."))] = true
+	}
+
+	for i, item := range r.Items {
+		// A line-ending change would show every line as changed.
+		if item.Type != domain.Modified || item.Reason == diff.LineEndingsReason ||
+			!exts[strings.ToLower(filepath.Ext(item.Path))] {
+			continue
+		}
+		preview, err := diff.TextPreview(filepath.Join(source.path, item.Path), filepath.Join(target.path, item.Path),
+			textPreviewLines)
+		if err != nil {
+			diag.Printf("Warning: previewing %s: %v\n", item.Path, err)
+			continue
+		}
+		r.Items[i].Preview = preview
+	}
+}
+
+// checkDistinct guards against comparing a directory with itself, including
+// through a symlink, which would misleadingly report no differences. With
+// --allow-same it only warns.
+func checkDistinct(source, target side) error {
+	if source.remote != nil || target.remote != nil {
+		return nil
+	}
+	sourceReal, err := filepath.EvalSymlinks(source.path)
+	if err != nil {
+		return fmt.Errorf("resolving source path: %w", err)
+	}
+	targetReal, err := filepath.EvalSymlinks(target.path)
+	if err != nil {
+		return fmt.Errorf("resolving target path: %w", err)
+	}
+	if sourceReal != targetReal {
+		return nil
+	}
+
+	msg := fmt.Sprintf("source %s and target %s are the same directory", source.path, target.path)
+	if !allowSame {
+		return fmt.Errorf("%s (pass --allow-same to compare anyway)", msg)
+	}
+	diag.Println("Warning:", msg)
+	return nil
+}
+
+// pairResult is the outcome of diffPair.
+type pairResult struct {
+	report *domain.DiffReport
+	// baselineFiles is the number of files in the pair's baseline side, or -1
+	// when neither side is a baseline.
+	baselineFiles int
+	// scanErrors is the number of paths skipped because they couldn't be read.
+	scanErrors int
+}
+
+// diffPair scans sourceArg and targetArg and compares them. A .mddiff.yaml at
+// the root of a local target supplies defaults for that comparison; it applies
+// to both scans so that an ignored file isn't reported as missing.
+func diffPair(
+	ctx context.Context, cmd *cobra.Command, cache *checksum.Cache, sourceArg, targetArg string,
+) (*pairResult, error) {
+	sourceSide, err := parseSide(sourceArg)
+	if err != nil {
+		return nil, err
+	}
+	targetSide, err := parseSide(targetArg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDistinct(sourceSide, targetSide); err != nil {
+		return nil, err
+	}
+
+	cfg := userConfig
+	if targetSide.remote == nil && !targetSide.baseline && !noConfig {
+		dirCfg, err := config.LoadDir(targetSide.path)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkDirConfig(targetSide.path, dirCfg); err != nil {
+			return nil, err
+		}
+		cfg = config.Merge(userConfig, dirCfg)
+	}
+	engine, stats, err := newEngine(cmd, cfg, cache)
+	if err != nil {
+		return nil, err
+	}
+	names, exts := ignoreEntries(cfg)
+	// Progress is drawn live on a terminal, and only printed as lines
+	// elsewhere with --verbose.
+	var progress *scanner.Progress
+	var onProgress func(int)
+	live := stderrIsTerminal()
+	if live || verbose {
+		progress = scanner.NewProgress(0, scanner.DefaultProgressWindow)
+	}
+	if progress != nil && !live {
+		onProgress = progressLines(progress)
+	}
+	s := scanner.NewLinearScannerWithOptions(scanner.ScanOptions{
+		IgnoreExt:        exts,
+		IgnoreNames:      names,
+		NoDefaultIgnores: noDefaultIgnores,
+		IncludeExt:       includeExt,
+		MaxDepth:         maxDepth + 1,
+		Exclude:          excludes,
+		IgnoreFile:       ignoreFile,
+		MinSize:          minSizeBytes,
+		FollowSymlinks:   symlinkMode == "follow",
+		SkipSymlinks:     symlinkMode == "ignore",
+		Filter:           scanFilter(),
+		ContinueOnError:  true,
+		Progress:         progress,
+		OnProgress:       onProgress,
+		Workers:          workers,
+	})
+	stopProgress := func() {}
+	if progress != nil {
+		stopProgress = startProgress(ctx, s, progress, live, sourceSide, targetSide)
+		defer stopProgress()
+	}
+
+	source, err := sourceSide.scan(ctx, s)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	target, err := targetSide.scan(ctx, s)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	stopProgress()
+
+	if hashManifest != "" {
+		m, err := checksum.LoadSharded(hashManifest, cacheShards)
+		if err != nil {
+			return nil, err
+		}
+		m.Apply(source)
+	}
+	modes, useHash := compareChecks(cmd, cfg)
+	for _, b := range []struct {
+		side side
+		tree *domain.DirectoryTree
+	}{{sourceSide, source}, {targetSide, target}} {
+		if b.side.baseline {
+			if err := checkBaselineContent(b.side.path, b.tree, modes, useHash); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	diffReport := engine.DiffContext(ctx, source, target)
+	diffReport.Truncated = diffReport.Truncated || ctx.Err() != nil
+	printStats(stats)
+	if editorLinks != "" {
+		if err := addEditorLinks(diffReport, sourceSide, targetSide); err != nil {
+			return nil, err
+		}
+	}
+	if textPreviewLines > 0 {
+		addTextPreviews(diffReport, sourceSide, targetSide)
 	}
 
-	println("Resolved dir1:", dir1abs)
-	println("Resolved dir2:", dir2abs)
-	println("format:", format)
-	// compareDirectories(dir1, dir2, format)
+	// A partial manifest would be mistaken for a complete one by later runs.
+	if writeHashManifest != "" && !diffReport.Truncated {
+		m, err := checksum.Build(source)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.SaveSharded(writeHashManifest, cacheShards); err != nil {
+			return nil, fmt.Errorf("writing hash manifest: %w", err)
+		}
+	}
+	result := &pairResult{
+		report:        diffReport,
+		baselineFiles: -1,
+		scanErrors:    warnScanErrors(source) + warnScanErrors(target),
+	}
+	switch {
+	case sourceSide.baseline:
+		result.baselineFiles = countFiles(source)
+	case targetSide.baseline:
+		result.baselineFiles = countFiles(target)
+	}
+	return result, nil
 }
 
-func validateInputs(_ *cobra.Command, _ []string) error {
-	// Enum check
-	switch format {
-	case "human", "json":
+// warnScanErrors prints a warning for each path skipped while scanning tree
+// and returns how many there were. Symlink cycles are skipped on purpose, so
+// they are mentioned but not counted.
+func warnScanErrors(tree *domain.DirectoryTree) int {
+	for _, p := range tree.SymlinkCycles {
+		diag.Printf("Note: not following %s: symlink cycle\n", filepath.Join(tree.RootPath, p))
+	}
+	for _, e := range tree.ScanErrors {
+		diag.Printf("Warning: skipped %s: %s\n", filepath.Join(tree.RootPath, e.Path), e.Err)
+	}
+	return len(tree.ScanErrors)
+}
+
+// pairArgs accepts one or more source/target directory pairs.
+func pairArgs(_ *cobra.Command, args []string) error {
+	if len(args) < 2 || len(args)%2 != 0 {
+		return fmt.Errorf("expected source/target directory pairs, got %d arg(s)", len(args))
+	}
+	return nil
+}
+
+func validateInputs(cmd *cobra.Command, args []string) error {
+	if err := loadUserConfig(cmd); err != nil {
+		return err
+	}
+	if err := validateFormats(cmd); err != nil {
+		return err
+	}
+	if quiet {
+		for _, name := range []string{"format", "output", "output-dir", "combined-json", "verbose", "estimate"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--quiet can't be combined with --%s", name)
+			}
+		}
+	}
+
+	switch report.ColorMode(color) {
+	case report.ColorAuto, report.ColorAlways, report.ColorNever:
+	default:
+		return fmt.Errorf("invalid --color: %s (want auto|always|never)", color)
+	}
+	if absolutePaths && anonymize {
+		return errors.New("--absolute-paths can't be combined with --anonymize")
+	}
+	if followSymlinks {
+		if cmd.Flags().Changed("symlinks") && symlinkMode != "follow" {
+			return fmt.Errorf("--follow-symlinks can't be combined with --symlinks=%s", symlinkMode)
+		}
+		symlinkMode = "follow"
+	}
+	switch symlinkMode {
+	case "compare", "ignore", "follow":
+	default:
+		return fmt.Errorf("invalid --symlinks: %s (want compare|ignore|follow)", symlinkMode)
+	}
+
+	if noColor {
+		if cmd.Flags().Changed("color") && report.ColorMode(color) != report.ColorNever {
+			return fmt.Errorf("--no-color can't be combined with --color=%s", color)
+		}
+		color = string(report.ColorNever)
+	}
+	// NO_COLOR (https://no-color.org) only changes the default; an explicit
+	// --color wins.
+	if os.Getenv("NO_COLOR") != "" && report.ColorMode(color) == report.ColorAuto {
+		color = string(report.ColorNever)
+	}
+
+	for _, kind := range failOn {
+		switch kind {
+		case "missing", "extra", "modified":
+		case "none":
+			if len(failOn) > 1 {
+				return errors.New("--fail-on=none can't be combined with other types")
+			}
+		default:
+			return fmt.Errorf("invalid --fail-on: %s (want missing|extra|modified|none)", kind)
+		}
+	}
+
+	for flag, names := range map[string][]string{"only": onlyTypes, "exclude-type": excludeTypes} {
+		for _, name := range names {
+			if _, ok := itemTypes[name]; !ok {
+				return fmt.Errorf("invalid --%s: %s (want missing|extra|modified|renamed|moved|emptydir|matched)", flag, name)
+			}
+		}
+	}
+
+	var err error
+	if pathIncludeRes, err = compilePatterns("path-include", pathInclude); err != nil {
+		return err
+	}
+	if pathExcludeRes, err = compilePatterns("path-exclude", pathExclude); err != nil {
+		return err
+	}
+
+	switch diff.SortKey(sortKey) {
+	case diff.SortByType, diff.SortByPath, diff.SortBySize:
+	default:
+		return fmt.Errorf("invalid --sort: %s (want type|path|size)", sortKey)
+	}
+
+	switch countRenamesAs {
+	case "separate", "modified":
+	default:
+		return fmt.Errorf("invalid --count-renames-as: %s (want separate|modified)", countRenamesAs)
+	}
+
+	switch zeroBytePolicy {
+	case "match", "flag", "ignore":
 	default:
-		return fmt.Errorf("invalid --format: %s (want human|json)", format)
+		return fmt.Errorf("invalid --zero-byte-policy: %s (want match|flag|ignore)", zeroBytePolicy)
+	}
+
+	if err := scanner.Excludes(excludes).Validate(); err != nil {
+		return fmt.Errorf("invalid --exclude: %w", err)
+	}
+
+	if err := checkCompareModes(compareModes); err != nil {
+		return fmt.Errorf("invalid --compare: %w", err)
+	}
+	if slices.Contains(compareModes, "hash") {
+		// --compare=hash is the long form of --hash.
+		hash = true
+	}
+	n, err := diff.ParseSize(quickHashSize)
+	if err != nil {
+		return fmt.Errorf("invalid --quickhash-bytes: %w", err)
+	}
+	if n < 1 {
+		return fmt.Errorf("invalid --quickhash-bytes: %s (must be at least 1 byte)", quickHashSize)
+	}
+	quickHashBytes = n
+	if minSize != "" {
+		n, err := diff.ParseSize(minSize)
+		if err != nil {
+			return fmt.Errorf("invalid --min-size: %w", err)
+		}
+		minSizeBytes = n
+	}
+	if sizeThresholdPct < 0 {
+		return fmt.Errorf("invalid --size-threshold-percent: %g (must not be negative)", sizeThresholdPct)
+	}
+	if sizeThresholdPct > 0 && sizeThreshold != "" {
+		return errors.New("--size-threshold and --size-threshold-percent can't be combined")
+	}
+	if mtimeTol < 0 {
+		return fmt.Errorf("invalid --mtime-tolerance: %s (must not be negative)", mtimeTol)
+	}
+
+	if fromManifestSource {
+		for i := 0; i < len(args); i += 2 {
+			if sd, err := parseSide(args[i]); err != nil || !sd.baseline {
+				return fmt.Errorf("--from-manifest-source: %s is not a manifest file", args[i])
+			}
+		}
+	}
+	if writeHashManifest != "" && len(args) > 2 {
+		return errors.New("--write-hash-manifest can only be used with a single source/target pair")
+	}
+
+	if samplePercent < 0 || samplePercent > 100 {
+		return fmt.Errorf("invalid --sample-percent: %g (want 0-100)", samplePercent)
+	}
+	if editorLinks != "" {
+		if _, err := report.EditorLink(editorLinks, "/"); err != nil {
+			return fmt.Errorf("invalid --editor-links: %w", err)
+		}
+	}
+	if maxBaselineDrift != "" {
+		if _, _, err := parseDrift(maxBaselineDrift); err != nil {
+			return fmt.Errorf("invalid --max-baseline-drift: %w", err)
+		}
+	}
+	if textPreviewLines != 0 && len(textCompareExts) == 0 {
+		return errors.New("--text-preview requires --text-compare")
+	}
+	if deadline < 0 {
+		return fmt.Errorf("invalid --deadline: %s (must not be negative)", deadline)
+	}
+	if cacheShards < 1 {
+		return fmt.Errorf("invalid --cache-shards: %d (must be at least 1)", cacheShards)
+	}
+	if noRecursion {
+		if cmd.Flags().Changed("max-depth") && maxDepth != 0 {
+			return fmt.Errorf("--no-recursion can't be combined with --max-depth=%d", maxDepth)
+		}
+		maxDepth = 0
+	}
+	if maxDepth < -1 {
+		return fmt.Errorf("invalid --max-depth: %d (want -1 or more)", maxDepth)
+	}
+	if workers < 1 {
+		return fmt.Errorf("invalid --workers: %d (must be at least 1)", workers)
+	}
+	if maxOpenFiles < 0 {
+		return fmt.Errorf("invalid --max-open-files: %d (must not be negative)", maxOpenFiles)
+	}
+	if compareOwnership && !scanner.RecordsOwnership {
+		diag.Println("Warning: --compare-ownership is not supported on this platform and has no effect")
+	}
+	if stemsOnly && (hash || perceptualImages) {
+		return errors.New("--stems-only can't be combined with --hash or --perceptual-images")
+	}
+	if samplePercent > 0 && !hash {
+		return errors.New("--sample-percent requires --hash")
 	}
 	return nil
 }
@@ -0,0 +1,1137 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/diff"
+	"mddiff/pkg/domain"
+)
+
+// writeTree creates files under dir, one per entry of files mapping a
+// slash-separated path to its content, and returns dir.
+func writeTree(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	for p, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// fixture creates a source and a target directory holding sourceFiles and
+// targetFiles, as writeTree does, under a new temporary directory.
+func fixture(t *testing.T, sourceFiles, targetFiles map[string]string) (source, target string) {
+	t.Helper()
+	root := t.TempDir()
+	source = filepath.Join(root, "source")
+	target = filepath.Join(root, "target")
+	if err := os.Mkdir(source, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(target, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	return writeTree(t, source, sourceFiles), writeTree(t, target, targetFiles)
+}
+
+// runMainEnv makes the test binary run mddiff instead of the tests, so run
+// can start it as a fresh process for every command line.
+const runMainEnv = "MDDIFF_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// run runs mddiff with args and returns what it wrote to stdout and stderr
+// and its exit status. It runs in an empty working and home directory, so no
+// .mddiff.yaml is found unless the test writes one there with runIn.
+func run(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	return runIn(t, t.TempDir(), args...)
+}
+
+// runIn is like run, but uses dir as the working and home directory.
+func runIn(t *testing.T, dir string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	return runInHome(t, dir, dir, args...)
+}
+
+// runInHome is like runIn, but with a separate home directory.
+func runInHome(t *testing.T, dir, home string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	c := exec.Command(os.Args[0], args...) // #nosec G204 -- the test binary itself
+	c.Dir = dir
+	c.Env = append(os.Environ(), runMainEnv+"=1", "HOME="+home, "NO_COLOR=1")
+	var out, errOut bytes.Buffer
+	c.Stdout, c.Stderr = &out, &errOut
+	err := c.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		t.Fatal(err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+// wantError fails t unless mddiff exited with status 1 and printed an error
+// containing substr.
+func wantError(t *testing.T, stderr string, code int, substr string) {
+	t.Helper()
+	if code != 1 {
+		t.Fatalf("exit status = %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "Error: ") || !strings.Contains(stderr, substr) {
+		t.Fatalf("stderr = %q, want an error containing %q", stderr, substr)
+	}
+}
+
+func TestIdenticalDirectories(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"a.mkv": "1"})
+	stdout, stderr, code := run(t, source, target)
+	if code != 0 {
+		t.Fatalf("exit status = %d, stderr = %q", code, stderr)
+	}
+	if !strings.Contains(stdout, "No differences found.") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestDifferencesFound(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{})
+	stdout, stderr, code := run(t, source, target)
+	if code != 1 || stderr != "" {
+		t.Fatalf("exit status = %d, stderr = %q, want 1 and nothing", code, stderr)
+	}
+	if !strings.Contains(stdout, "MISSING  a.mkv") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestPairArgs(t *testing.T) {
+	source, target := fixture(t, nil, nil)
+	_, stderr, code := run(t, source, target, source)
+	wantError(t, stderr, code, "expected source/target directory pairs, got 3 arg(s)")
+}
+
+func TestCombinedJSON(t *testing.T) {
+	source1, target1 := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"a.mkv": "1"})
+	source2, target2 := fixture(t, map[string]string{"b.mkv": "1"}, map[string]string{})
+
+	stdout, _, code := run(t, "--combined-json", source1, target1, source2, target2)
+	if code != 1 {
+		t.Fatalf("exit status = %d, want 1 since the second pair differs", code)
+	}
+	var pairs []domain.PairReport
+	if err := json.Unmarshal([]byte(stdout), &pairs); err != nil {
+		t.Fatalf("stdout isn't a JSON array of reports: %v\n%s", err, stdout)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("got %d reports, want 2", len(pairs))
+	}
+	if pairs[0].Source != source1 || pairs[1].Target != target2 {
+		t.Errorf("pairs = %+v", pairs)
+	}
+	if len(pairs[0].Report.Items) != 0 || len(pairs[1].Report.Items) != 1 {
+		t.Errorf("items = %+v and %+v", pairs[0].Report.Items, pairs[1].Report.Items)
+	}
+}
+
+func TestCombinedJSONFormats(t *testing.T) {
+	source, target := fixture(t, nil, nil)
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-f", "json"}, ""},
+		{[]string{"-f", "csv"}, "--format csv can't be combined with --combined-json"},
+		{[]string{"-f", "json,html", "--output-dir", t.TempDir()}, "can't be combined with several formats"},
+		{[]string{"--summary-only"}, "--summary-only can't be combined with --combined-json"},
+		{[]string{"--changed-dirs"}, "--changed-dirs can't be combined with --combined-json"},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"--combined-json"}, tt.args...), source, target)
+		_, stderr, code := run(t, args...)
+		if tt.err == "" {
+			if code != 0 {
+				t.Errorf("%v: exit status %d, stderr %q", tt.args, code, stderr)
+			}
+			continue
+		}
+		if code != 1 || !strings.Contains(stderr, tt.err) {
+			t.Errorf("%v: exit status %d, stderr %q, want an error containing %q", tt.args, code, stderr, tt.err)
+		}
+	}
+}
+
+func TestCountRenamesAs(t *testing.T) {
+	source, target := fixture(t, map[string]string{"old.mkv": "123"}, map[string]string{"new.mkv": "123"})
+
+	stdout, _, _ := run(t, "--detect-renames", "--count-renames-as", "modified", source, target)
+	if !strings.Contains(stdout, "Modified: 1  Extra: 0  Renamed: 1") {
+		t.Errorf("stdout = %q, want the rename counted as modified too", stdout)
+	}
+	stdout, _, _ = run(t, "-f", "counts", "--detect-renames", "--count-renames-as", "modified", source, target)
+	if want := "MISSING=0 MODIFIED=1 EXTRA=0 RENAMED=1 TOTAL=1\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	_, stderr, code := run(t, "--count-renames-as", "both", source, target)
+	wantError(t, stderr, code, "invalid --count-renames-as: both (want separate|modified)")
+}
+
+func TestVerifySuperset(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  map[string]string
+		code    int
+		verdict string
+	}{
+		{
+			name:    "extra files are allowed",
+			target:  map[string]string{"a.mkv": "1", "b.mkv": "22", "new.mkv": "3"},
+			verdict: "Backup VERIFIED: ",
+		},
+		{
+			name:    "missing file",
+			target:  map[string]string{"a.mkv": "1"},
+			code:    1,
+			verdict: "Backup FAILED: ",
+		},
+		{
+			name:    "modified file",
+			target:  map[string]string{"a.mkv": "1", "b.mkv": "222"},
+			code:    1,
+			verdict: "is missing 0 and has 1 modified file(s)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, target := fixture(t, map[string]string{"a.mkv": "1", "b.mkv": "22"}, tt.target)
+			_, stderr, code := run(t, "--verify-superset", source, target)
+			if code != tt.code || !strings.Contains(stderr, tt.verdict) {
+				t.Errorf("exit status %d, stderr %q, want %d and %q", code, stderr, tt.code, tt.verdict)
+			}
+			if strings.Contains(stderr, "Error:") {
+				t.Errorf("stderr = %q, want only the verdict", stderr)
+			}
+		})
+	}
+}
+
+func TestSizeThresholdMap(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.flac": "12345", "b.mkv": "12345"},
+		map[string]string{"a.flac": "123456", "b.mkv": "123456"})
+
+	stdout, _, _ := run(t, "-f", "csv", "--size-threshold-map", ".flac=0,*=1", source, target)
+	if !strings.Contains(stdout, "MODIFIED,a.flac,") || strings.Contains(stdout, "b.mkv") {
+		t.Errorf("stdout = %q, want only a.flac modified", stdout)
+	}
+	_, stderr, code := run(t, "--size-threshold-map", ".flac", source, target)
+	wantError(t, stderr, code, "invalid --size-threshold-map")
+}
+
+func TestSizeThreshold(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": strings.Repeat("x", 1000), "b.mkv": strings.Repeat("x", 3000)},
+		map[string]string{"a.mkv": strings.Repeat("x", 1500), "b.mkv": "x"})
+
+	stdout, _, _ := run(t, "-f", "csv", "--size-threshold", "1KiB", source, target)
+	if strings.Contains(stdout, "a.mkv") || !strings.Contains(stdout, "MODIFIED,b.mkv,") {
+		t.Errorf("stdout = %q, want only b.mkv modified", stdout)
+	}
+	stdout, _, code := run(t, "-f", "csv", "--size-threshold", "3KB", source, target)
+	if code != 0 || strings.Contains(stdout, "MODIFIED") {
+		t.Errorf("exit status %d, stdout %q, want no modified files", code, stdout)
+	}
+	_, stderr, code := run(t, "--size-threshold", "5XB", source, target)
+	wantError(t, stderr, code, `invalid --size-threshold: invalid size "5XB"`)
+}
+
+func TestColorFlags(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, nil)
+
+	// Output that isn't a terminal is never colored unless asked for.
+	if stdout, _, _ := run(t, source, target); strings.Contains(stdout, "\x1b[") {
+		t.Errorf("redirected output is colored: %q", stdout)
+	}
+	if stdout, _, _ := run(t, "--color", "always", source, target); !strings.Contains(stdout, "\x1b[31mMISSING") {
+		t.Errorf("--color always output isn't colored: %q", stdout)
+	}
+	_, stderr, code := run(t, "--no-color", "--color", "always", source, target)
+	wantError(t, stderr, code, "--no-color can't be combined with --color=always")
+	_, stderr, code = run(t, "--color", "sometimes", source, target)
+	wantError(t, stderr, code, "invalid --color: sometimes (want auto|always|never)")
+}
+
+func TestHashManifest(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "abc"}, map[string]string{"a.mkv": "abc"})
+	manifest := filepath.Join(t.TempDir(), "hashes.json")
+
+	if _, stderr, code := run(t, "--hash", "--write-hash-manifest", manifest, source, target); code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	m, err := checksum.Load(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := m.Files["a.mkv"]
+	if !ok || entry.Size != 3 {
+		t.Fatalf("manifest = %+v, want an entry for a.mkv", m.Files)
+	}
+
+	// A run with the manifest uses its digest rather than rehashing the
+	// unchanged source file.
+	entry.SHA256 = strings.Repeat("0", 64)
+	m.Files["a.mkv"] = entry
+	if err := m.Save(manifest); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ := run(t, "-f", "csv", "--hash", "--hash-manifest", manifest, source, target)
+	if !strings.Contains(stdout, "MODIFIED,a.mkv,Content hash mismatch") {
+		t.Errorf("stdout = %q, want the manifest's digest used", stdout)
+	}
+
+	_, stderr, code := run(t, "--write-hash-manifest", manifest, source, target, source, target)
+	wantError(t, stderr, code, "--write-hash-manifest can only be used with a single source/target pair")
+}
+
+func TestChangedDirs(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a/one.mkv": "1", "b/two.mkv": "2"},
+		map[string]string{"b/two.mkv": "2"})
+
+	stdout, _, code := run(t, "--changed-dirs", "-f", "json", source, target)
+	if code != 1 || !strings.Contains(stdout, `"dir": "a"`) || strings.Contains(stdout, `"dir": "b"`) {
+		t.Errorf("exit status %d, stdout %q, want only directory a listed", code, stdout)
+	}
+	_, stderr, code := run(t, "--changed-dirs", "-f", "csv", source, target)
+	wantError(t, stderr, code, "--format csv can't be combined with --changed-dirs")
+}
+
+func TestZeroBytePolicy(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"both.mkv": "", "src.mkv": "", "tgt.mkv": "1"},
+		map[string]string{"both.mkv": "", "src.mkv": "1", "tgt.mkv": ""})
+	tests := []struct {
+		policy  string
+		want    []string
+		notWant []string
+	}{
+		{"match", []string{"MODIFIED,src.mkv,Size changed", "MODIFIED,tgt.mkv,Size changed"}, []string{"both.mkv"}},
+		{"flag", []string{"MODIFIED,both.mkv,Zero-byte file", "MODIFIED,src.mkv,Zero-byte file"}, nil},
+		{"ignore", []string{"EXTRA,src.mkv,", "MISSING,tgt.mkv,"}, []string{"both.mkv", "MODIFIED"}},
+	}
+	for _, tt := range tests {
+		stdout, _, _ := run(t, "-f", "csv", "--zero-byte-policy", tt.policy, source, target)
+		for _, want := range tt.want {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: stdout = %q, want %q", tt.policy, stdout, want)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(stdout, notWant) {
+				t.Errorf("%s: stdout = %q, want no %q", tt.policy, stdout, notWant)
+			}
+		}
+	}
+	_, stderr, code := run(t, "--zero-byte-policy", "skip", source, target)
+	wantError(t, stderr, code, "invalid --zero-byte-policy: skip (want match|flag|ignore)")
+}
+
+func TestVerboseStats(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "1"},
+		map[string]string{"a.mkv": "1", "b.mkv": "12"})
+
+	_, stderr, _ := run(t, "-v", source, target)
+	if !strings.Contains(stderr, "compare: 2 comparisons, 1 modified [Size changed: 1], 1 unchanged in ") {
+		t.Errorf("stderr = %q, want the comparison stats", stderr)
+	}
+	if _, stderr, _ := run(t, source, target); strings.Contains(stderr, "comparisons") {
+		t.Errorf("stderr = %q, want no stats without --verbose", stderr)
+	}
+}
+
+func TestSameDirectory(t *testing.T) {
+	source, _ := fixture(t, map[string]string{"a.mkv": "1"}, nil)
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(source, link); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+
+	for _, target := range []string{source, link} {
+		_, stderr, code := run(t, source, target)
+		wantError(t, stderr, code, "are the same directory (pass --allow-same to compare anyway)")
+
+		stdout, stderr, code := run(t, "--allow-same", source, target)
+		if code != 0 || !strings.Contains(stderr, "Warning: source ") || !strings.Contains(stdout, "No differences found.") {
+			t.Errorf("--allow-same: exit status %d, stdout %q, stderr %q, want a warning", code, stdout, stderr)
+		}
+	}
+}
+
+func TestMaxOpenFiles(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "2"},
+		map[string]string{"a.mkv": "1", "b.mkv": "3"})
+
+	stdout, _, _ := run(t, "-f", "csv", "--hash", "--max-open-files", "1", source, target)
+	if !strings.Contains(stdout, "MODIFIED,b.mkv,Content hash mismatch") || strings.Contains(stdout, "a.mkv") {
+		t.Errorf("stdout = %q, want only b.mkv modified", stdout)
+	}
+	_, stderr, code := run(t, "--max-open-files", "-1", source, target)
+	wantError(t, stderr, code, "invalid --max-open-files: -1 (must not be negative)")
+}
+
+func TestEditorLinks(t *testing.T) {
+	source, target := fixture(t, map[string]string{"show/a.mkv": "1"}, map[string]string{"b.mkv": "1"})
+
+	stdout, _, _ := run(t, "-f", "json", "--editor-links", "vscode", source, target)
+	var r domain.DiffReport
+	if err := json.Unmarshal([]byte(stdout), &r); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"b.mkv":      "vscode://file" + filepath.ToSlash(filepath.Join(target, "b.mkv")),
+		"show/a.mkv": "vscode://file" + filepath.ToSlash(filepath.Join(source, "show", "a.mkv")),
+	}
+	if len(r.Items) != len(want) {
+		t.Fatalf("items = %+v, want %d", r.Items, len(want))
+	}
+	for _, item := range r.Items {
+		if item.Link != want[item.Path] {
+			t.Errorf("%s link = %q, want %q", item.Path, item.Link, want[item.Path])
+		}
+	}
+	_, stderr, code := run(t, "--editor-links", "emacs", source, target)
+	wantError(t, stderr, code, "unknown editor: emacs")
+}
+
+func TestStemsOnly(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"show/ep1.mkv": "1", "movie.avi": "1", "same.mkv": "1"},
+		map[string]string{"show/ep1.mp4": "123", "other.mkv": "1", "same.mkv": "12"})
+
+	stdout, _, code := run(t, "-f", "csv", "--stems-only", source, target)
+	want := "type,path,reason,src_size,tgt_size\nEXTRA,other.mkv,,0,1\nMISSING,movie.avi,,1,0\n"
+	if code != 1 || stdout != want {
+		t.Errorf("exit status %d, stdout %q, want %q", code, stdout, want)
+	}
+	_, stderr, code := run(t, "--stems-only", "--hash", source, target)
+	wantError(t, stderr, code, "--stems-only can't be combined with --hash or --perceptual-images")
+}
+
+func TestCompareFileType(t *testing.T) {
+	source, target := fixture(t, map[string]string{"movie.mkv": "1234567"}, nil)
+	if err := os.Symlink(filepath.Join(source, "movie.mkv"), filepath.Join(target, "movie.mkv")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+
+	stdout, _, _ := run(t, "-f", "csv", "--compare-file-type", source, target)
+	if !strings.Contains(stdout, "MODIFIED,movie.mkv,Type differs: file vs symlink") {
+		t.Errorf("stdout = %q, want a type mismatch", stdout)
+	}
+	if stdout, _, _ := run(t, "-f", "csv", source, target); strings.Contains(stdout, "Type differs") {
+		t.Errorf("stdout = %q, want no type check without --compare-file-type", stdout)
+	}
+}
+
+func TestCountsFormat(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "1"},
+		map[string]string{"b.mkv": "12", "c.mkv": "1"})
+	stdout, _, code := run(t, "-f", "counts", source, target)
+	if want := "MISSING=1 MODIFIED=1 EXTRA=1 RENAMED=0 TOTAL=3\n"; code != 1 || stdout != want {
+		t.Errorf("exit status %d, stdout %q, want 1 and %q", code, stdout, want)
+	}
+}
+
+func TestCacheShards(t *testing.T) {
+	files := map[string]string{"a.mkv": "abc", "b.mkv": "de"}
+	source, target := fixture(t, files, files)
+	manifest := filepath.Join(t.TempDir(), "hashes.json")
+
+	_, stderr, code := run(t, "--hash", "--cache-shards", "2", "--write-hash-manifest", manifest, source, target)
+	if code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	m, err := checksum.LoadSharded(manifest, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 2 {
+		t.Errorf("manifest = %+v, want both files", m.Files)
+	}
+	stdout, stderr, code := run(t, "--hash", "--cache-shards", "2", "--hash-manifest", manifest, source, target)
+	if code != 0 || !strings.Contains(stdout, "No differences found.") {
+		t.Errorf("exit status %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+
+	_, stderr, code = run(t, "--cache-shards", "0", source, target)
+	wantError(t, stderr, code, "invalid --cache-shards: 0 (must be at least 1)")
+}
+
+func TestTextCompare(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.srt": "1\r\nHello\r\n", "b.srt": "1\nHello\n"},
+		map[string]string{"a.srt": "1\nHello\n", "b.srt": "1\nHullo\n"})
+
+	stdout, _, _ := run(t, "-f", "csv", "--text-compare", "srt", source, target)
+	for _, want := range []string{
+		"MODIFIED,a.srt,Line endings differ (content identical)",
+		"MODIFIED,b.srt,Text content differs",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout = %q, want %q", stdout, want)
+		}
+	}
+	if stdout, _, _ := run(t, "-f", "csv", source, target); !strings.Contains(stdout, "MODIFIED,a.srt,Size changed") {
+		t.Errorf("stdout = %q, want a size change without --text-compare", stdout)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	source1, target1 := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"a.mkv": "1"})
+	source2, target2 := fixture(t, map[string]string{"b.mkv": "1"}, map[string]string{"b.mkv": "1"})
+
+	for _, args := range [][]string{nil, {"--exit-zero"}} {
+		args = append(append([]string{"--deadline", "1ns"}, args...), source1, target1, source2, target2)
+		stdout, stderr, code := run(t, args...)
+		wantError(t, stderr, code, "--deadline reached before every file was compared; the report is incomplete")
+		// Every pair is reported, and none claims to be complete.
+		if n := strings.Count(stdout, "Incomplete: the run stopped"); n != 2 {
+			t.Errorf("%v: stdout = %q, want both pairs marked incomplete", args, stdout)
+		}
+		if strings.Contains(stdout, "No differences found.") {
+			t.Errorf("%v: stdout = %q, want no claim of no differences", args, stdout)
+		}
+		if n := strings.Count(stderr, "Warning: --deadline reached"); n != 2 {
+			t.Errorf("%v: stderr = %q, want a warning per pair", args, stderr)
+		}
+	}
+
+	if _, stderr, code := run(t, "--deadline", "1h", source1, target1); code != 0 {
+		t.Errorf("exit status %d, stderr %q, want a generous deadline to pass", code, stderr)
+	}
+}
+
+func TestSeparateExtChanges(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.avi": "1", "b.mkv": "1"},
+		map[string]string{"a.mkv": "1", "b.mkv": "12"})
+
+	stdout, _, _ := run(t, "-f", "counts", "--separate-ext-changes", source, target)
+	if want := "MISSING=0 MODIFIED=1 EXTRA=0 RENAMED=0 EXT_CHANGED=1 TOTAL=2\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	stdout, _, _ = run(t, "-f", "counts", source, target)
+	if want := "MISSING=0 MODIFIED=2 EXTRA=0 RENAMED=0 TOTAL=2\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+func TestTextPreview(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.srt": "1\nHello\n", "b.srt": "1\r\nBye\r\n"},
+		map[string]string{"a.srt": "1\nHullo\n", "b.srt": "1\nBye\n"})
+
+	stdout, _, _ := run(t, "--text-compare", "srt", "--text-preview", source, target)
+	if !strings.Contains(stdout, "\na.srt:\n  @@ -1,2 +1,2 @@\n   1\n  -Hello\n  +Hullo\n") {
+		t.Errorf("stdout = %q, want a preview of a.srt", stdout)
+	}
+	// A line-ending change would show every line, so it gets no preview.
+	if strings.Contains(stdout, "b.srt:\n") {
+		t.Errorf("stdout = %q, want no preview of b.srt", stdout)
+	}
+
+	_, stderr, code := run(t, "--text-preview=5", source, target)
+	wantError(t, stderr, code, "--text-preview requires --text-compare")
+}
+
+func TestRequireFullCoverage(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"a.mkv": "1"})
+	if err := os.Symlink("missing.mkv", filepath.Join(target, "broken.mkv")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+
+	// The broken link is skipped with a warning, but only fails the run with
+	// --require-full-coverage.
+	_, stderr, code := run(t, "--symlinks", "follow", source, target)
+	if code != 0 || !strings.Contains(stderr, "Warning: skipped "+filepath.Join(target, "broken.mkv")) {
+		t.Errorf("exit status %d, stderr %q, want 0 and a warning", code, stderr)
+	}
+	_, stderr, code = run(t, "--symlinks", "follow", "--require-full-coverage", source, target)
+	wantError(t, stderr, code, "1 path(s) couldn't be read, so the comparison is incomplete (--require-full-coverage)")
+
+	// A symlink cycle isn't followed, but nothing is left unread.
+	if err := os.Remove(filepath.Join(target, "broken.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(".", filepath.Join(target, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, code = run(t, "--symlinks", "follow", "--require-full-coverage", source, target)
+	if code != 0 || !strings.Contains(stderr, "Note: not following "+filepath.Join(target, "loop")+": symlink cycle") {
+		t.Errorf("exit status %d, stderr %q, want 0 and a note about the cycle", code, stderr)
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"Secret Show/ep1.mkv": "1", "Secret Show/ep2.mkv": "1"},
+		map[string]string{"Secret Show/ep2.mkv": "12"})
+
+	stdout, _, _ := run(t, "--anonymize", source, target)
+	if strings.Contains(stdout, "Secret") || strings.Contains(stdout, "ep1") || strings.Contains(stdout, source) {
+		t.Errorf("stdout = %q, want no names", stdout)
+	}
+	if !strings.Contains(stdout, "Source: source\nTarget: target\n") || !strings.Contains(stdout, "Missing: 1") {
+		t.Errorf("stdout = %q, want anonymous roots and the counts", stdout)
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "1", "show/ep1.mkv": "1"},
+		map[string]string{"b.mkv": "12", "c.mkv": "1", "show/ep1.mkv": "1"})
+
+	serial, _, _ := run(t, "-f", "csv", "--workers", "1", source, target)
+	stdout, _, code := run(t, "-f", "csv", "--workers", "8", source, target)
+	if code != 1 || stdout != serial {
+		t.Errorf("exit status %d, stdout %q, want 1 and %q", code, stdout, serial)
+	}
+	_, stderr, code := run(t, "--workers", "0", source, target)
+	wantError(t, stderr, code, "invalid --workers: 0 (must be at least 1)")
+}
+
+func TestCompareList(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"content.mkv": "abc", "touched.mkv": "abc", "same.mkv": "abc"},
+		map[string]string{"content.mkv": "xyz", "touched.mkv": "abc", "same.mkv": "abc"})
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, p := range []string{
+		filepath.Join(source, "content.mkv"), filepath.Join(target, "content.mkv"), filepath.Join(source, "touched.mkv"),
+	} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		compare string
+		want    []string
+	}{
+		{"size", nil},
+		{"size,hash", []string{"MODIFIED,content.mkv,Content hash mismatch"}},
+		{"size,mtime", []string{"MODIFIED,touched.mkv,Modification time differs"}},
+		{"mtime,hash", []string{
+			"MODIFIED,content.mkv,Content hash mismatch", "MODIFIED,touched.mkv,Modification time differs",
+		}},
+	}
+	for _, tt := range tests {
+		stdout, stderr, _ := run(t, "-f", "csv", "--compare", tt.compare, source, target)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")[1:]
+		if len(lines) != len(tt.want) {
+			t.Errorf("--compare %s: stdout %q, stderr %q, want %q", tt.compare, stdout, stderr, tt.want)
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.HasPrefix(lines[i], want) {
+				t.Errorf("--compare %s: line %q, want prefix %q", tt.compare, lines[i], want)
+			}
+		}
+	}
+
+	_, stderr, code := run(t, "--compare", "size,bogus", source, target)
+	wantError(t, stderr, code, "invalid --compare: bogus")
+}
+
+func TestIgnoreFile(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{".mddiffignore": "*.nfo\n", "a.nfo": "1", "b.mkv": "1", "samples/s.mkv": "1"},
+		map[string]string{"b.mkv": "1"})
+
+	// The target has no ignore file, so each tree uses its own rules.
+	stdout, _, _ := run(t, "-f", "csv", source, target)
+	if strings.Contains(stdout, "a.nfo") || !strings.Contains(stdout, "MISSING,samples/s.mkv,") {
+		t.Errorf("stdout = %q, want only samples/s.mkv missing", stdout)
+	}
+
+	ignoreFile := filepath.Join(t.TempDir(), "ignore")
+	if err := os.WriteFile(ignoreFile, []byte("samples/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ = run(t, "-f", "csv", "--ignore-file", ignoreFile, source, target)
+	if strings.Contains(stdout, "samples") || !strings.Contains(stdout, "MISSING,a.nfo,") {
+		t.Errorf("stdout = %q, want only a.nfo missing", stdout)
+	}
+}
+
+func TestMinSize(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"movie.mkv": strings.Repeat("x", 2000), "link.url": strings.Repeat("x", 100)},
+		map[string]string{"movie.mkv": strings.Repeat("x", 2000), "placeholder.mkv": ""})
+
+	stdout, _, code := run(t, "-f", "counts", "--min-size", "1KB", source, target)
+	if want := "MISSING=0 MODIFIED=0 EXTRA=0 RENAMED=0 TOTAL=0\n"; code != 0 || stdout != want {
+		t.Errorf("exit status %d, stdout %q, want 0 and %q", code, stdout, want)
+	}
+	stdout, _, _ = run(t, "-f", "counts", source, target)
+	if want := "MISSING=1 MODIFIED=0 EXTRA=1 RENAMED=0 TOTAL=2\n"; stdout != want {
+		t.Errorf("without --min-size, stdout %q, want %q", stdout, want)
+	}
+	_, stderr, code := run(t, "--min-size", "lots", source, target)
+	wantError(t, stderr, code, "invalid --min-size")
+}
+
+func TestIgnoreCase(t *testing.T) {
+	source, target := fixture(t, map[string]string{"Movie.mkv": "1"}, map[string]string{"movie.mkv": "1"})
+
+	stdout, _, _ := run(t, "-f", "counts", source, target)
+	if want := "MISSING=1 MODIFIED=0 EXTRA=1 RENAMED=0 TOTAL=2\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	stdout, _, _ = run(t, "-f", "csv", "--ignore-case", source, target)
+	if !strings.Contains(stdout, "MODIFIED,Movie.mkv,Case differs,") || strings.Contains(stdout, "MISSING") {
+		t.Errorf("stdout = %q, want Movie.mkv modified because its case differs", stdout)
+	}
+}
+
+func TestNormalizeUnicode(t *testing.T) {
+	const nfc, nfd = "Caf\u00e9.mkv", "Cafe\u0301.mkv"
+	source, target := fixture(t, map[string]string{nfd: "1"}, map[string]string{nfc: "1"})
+	if _, err := os.Stat(filepath.Join(target, nfd)); err == nil {
+		t.Skip("the filesystem normalizes names")
+	}
+
+	stdout, _, _ := run(t, "-f", "counts", source, target)
+	if want := "MISSING=1 MODIFIED=0 EXTRA=1 RENAMED=0 TOTAL=2\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	stdout, _, code := run(t, "-f", "counts", "--normalize-unicode", source, target)
+	if want := "MISSING=0 MODIFIED=0 EXTRA=0 RENAMED=0 TOTAL=0\n"; code != 0 || stdout != want {
+		t.Errorf("exit status %d, stdout %q, want 0 and %q", code, stdout, want)
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"show/s01/ep1.mkv": "123"},
+		map[string]string{"store/s01/ep1.mkv": "123", "show/.keep": ""})
+	link := filepath.Join(target, "show", "s01")
+	if err := os.Symlink(filepath.Join("..", "store", "s01"), link); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+
+	stdout, _, _ := run(t, "-f", "csv", "--ignore-name", ".keep,store", source, target)
+	if !strings.Contains(stdout, "MISSING,show/s01/ep1.mkv,") {
+		t.Errorf("stdout = %q, want show/s01/ep1.mkv missing behind the unfollowed link", stdout)
+	}
+	for _, flag := range []string{"--symlinks=follow", "--follow-symlinks"} {
+		stdout, stderr, code := run(t, "-f", "csv", "--ignore-name", ".keep,store", flag, source, target)
+		if code != 0 {
+			t.Errorf("%s: exit status %d, stdout %q, stderr %q, want no differences", flag, code, stdout, stderr)
+		}
+	}
+}
+
+func TestHashCache(t *testing.T) {
+	files := map[string]string{"a.mkv": "abc", "b.mkv": "de"}
+	source, target := fixture(t, files, files)
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+
+	for i := range 2 {
+		stdout, stderr, code := run(t, "--hash", "--hash-cache", cachePath, source, target)
+		if code != 0 || !strings.Contains(stdout, "No differences found.") {
+			t.Fatalf("run %d: exit status %d, stdout %q, stderr %q", i+1, code, stdout, stderr)
+		}
+	}
+	cache, err := checksum.LoadCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(source, "a.mkv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Lookup(filepath.Join(source, "a.mkv"), info.Size(), info.ModTime()); !ok {
+		t.Error("the cache has no entry for a.mkv")
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "show/ep1.mkv": "1", "show/extras/bts.mkv": "1"},
+		map[string]string{"a.mkv": "1", "show/ep1.mkv": "12", "show/extras/other.mkv": "1"})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "MISSING=1 MODIFIED=1 EXTRA=1 RENAMED=0 TOTAL=3\n"},
+		{[]string{"--max-depth", "1"}, "MISSING=0 MODIFIED=1 EXTRA=0 RENAMED=0 TOTAL=1\n"},
+		{[]string{"--max-depth", "0"}, "MISSING=0 MODIFIED=0 EXTRA=0 RENAMED=0 TOTAL=0\n"},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"-f", "counts"}, tt.args...), source, target)
+		if stdout, stderr, _ := run(t, args...); stdout != tt.want {
+			t.Errorf("%v: stdout %q, stderr %q, want %q", tt.args, stdout, stderr, tt.want)
+		}
+	}
+	_, stderr, code := run(t, "--max-depth", "-2", source, target)
+	wantError(t, stderr, code, "invalid --max-depth: -2 (want -1 or more)")
+}
+
+func TestPathFilters(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{
+			"Movies/a.mkv": "1", "Old Movies/b.mkv": "1", "Show/Season 1/ep1.mkv": "1", "Show/Specials/sp.mkv": "1",
+		},
+		nil)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"Movies/a.mkv", "Old Movies/b.mkv", "Show/Season 1/ep1.mkv", "Show/Specials/sp.mkv"}},
+		// Unanchored patterns match anywhere in the path.
+		{[]string{"--path-include", "Movies/"}, []string{"Movies/a.mkv", "Old Movies/b.mkv"}},
+		{[]string{"--path-include", "^Movies/"}, []string{"Movies/a.mkv"}},
+		{[]string{"--path-exclude", `/Season \d+/`}, []string{"Movies/a.mkv", "Old Movies/b.mkv", "Show/Specials/sp.mkv"}},
+		{[]string{"--path-include", "^Movies/", "--path-include", "^Show/"}, []string{
+			"Movies/a.mkv", "Show/Season 1/ep1.mkv", "Show/Specials/sp.mkv",
+		}},
+		// Excludes win over includes.
+		{[]string{"--path-include", "^Show/", "--path-exclude", "Season"}, []string{"Show/Specials/sp.mkv"}},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"-f", "csv"}, tt.args...), source, target)
+		stdout, stderr, code := run(t, args...)
+		if code != 1 {
+			t.Errorf("%q: exit status %d, stderr %q, want 1 since the summary still counts hidden items", tt.args, code, stderr)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n")[1:] {
+			got = append(got, strings.Split(line, ",")[1])
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: paths %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	_, stderr, code := run(t, "--path-exclude", "(unclosed", source, target)
+	wantError(t, stderr, code, `invalid --path-exclude "(unclosed"`)
+}
+
+func TestSort(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "12345", "c.mkv": "1"},
+		map[string]string{"c.mkv": "123", "d.mkv": "1234567"})
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"EXTRA,d.mkv", "MISSING,a.mkv", "MISSING,b.mkv", "MODIFIED,c.mkv"}},
+		{[]string{"--sort", "path"}, []string{"MISSING,a.mkv", "MISSING,b.mkv", "MODIFIED,c.mkv", "EXTRA,d.mkv"}},
+		{[]string{"--sort", "size"}, []string{"EXTRA,d.mkv", "MISSING,b.mkv", "MODIFIED,c.mkv", "MISSING,a.mkv"}},
+		{[]string{"--sort", "size", "--reverse"}, []string{
+			"MISSING,a.mkv", "MODIFIED,c.mkv", "MISSING,b.mkv", "EXTRA,d.mkv",
+		}},
+		{[]string{"--reverse"}, []string{"MODIFIED,c.mkv", "MISSING,b.mkv", "MISSING,a.mkv", "EXTRA,d.mkv"}},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"-f", "csv"}, tt.args...), source, target)
+		stdout, _, _ := run(t, args...)
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n")[1:] {
+			fields := strings.Split(line, ",")
+			got = append(got, fields[0]+","+fields[1])
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: items %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	// An HTML section is sorted by path unless --sort chose another order.
+	for _, tt := range []struct {
+		args  []string
+		first string
+	}{
+		{nil, "a.mkv"},
+		{[]string{"--sort", "size"}, "b.mkv"},
+	} {
+		stdout, _, _ := run(t, append(append([]string{"-f", "html"}, tt.args...), source, target)...)
+		missing := stdout[strings.Index(stdout, ">Missing ("):]
+		if a, b := strings.Index(missing, "a.mkv"), strings.Index(missing, "b.mkv"); (a < b) != (tt.first == "a.mkv") {
+			t.Errorf("%q: the missing section doesn't start with %s:\n%s", tt.args, tt.first, missing)
+		}
+	}
+
+	_, stderr, code := run(t, "--sort", "name", source, target)
+	wantError(t, stderr, code, "invalid --sort: name (want type|path|size)")
+}
+
+func TestHumanReadable(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": strings.Repeat("x", 1536)},
+		map[string]string{"b.mkv": strings.Repeat("x", 1023)})
+
+	stdout, _, _ := run(t, source, target)
+	if !strings.Contains(stdout, "Size: 1536 bytes") {
+		t.Errorf("stdout = %q, want raw byte counts by default", stdout)
+	}
+	for _, flag := range []string{"-H", "--human-readable"} {
+		stdout, _, _ := run(t, flag, source, target)
+		for _, want := range []string{"Size: 1.5 KiB", "Size: 1023 B"} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: stdout = %q, want %q", flag, stdout, want)
+			}
+		}
+	}
+
+	stdout, _, _ = run(t, "-f", "json", "-H", source, target)
+	var r struct {
+		Items []struct {
+			SrcSize      int64  `json:"src_size"`
+			SrcSizeHuman string `json:"src_size_human"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Items) != 2 || r.Items[1].SrcSize != 1536 || r.Items[1].SrcSizeHuman != "1.5 KiB" {
+		t.Errorf("items = %+v, want the missing a.mkv with raw and human sizes", r.Items)
+	}
+}
+
+func TestJUnitFormat(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "1"},
+		map[string]string{"b.mkv": "12", "c.mkv": "1"})
+
+	for _, tt := range []struct {
+		args    []string
+		skipped int
+	}{
+		{nil, 1},
+		{[]string{"--junit-passing"}, 0},
+	} {
+		args := append(append([]string{"-f", "junit"}, tt.args...), source, target)
+		stdout, stderr, code := run(t, args...)
+		if code != 1 {
+			t.Errorf("%q: exit status %d, stderr %q, want 1", tt.args, code, stderr)
+		}
+		var doc struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Skipped  int `xml:"skipped,attr"`
+		}
+		if err := xml.Unmarshal([]byte(stdout), &doc); err != nil {
+			t.Fatalf("%q: output doesn't parse: %v\n%s", tt.args, err, stdout)
+		}
+		if doc.Tests != 3 || doc.Failures != 2 || doc.Skipped != tt.skipped {
+			t.Errorf("%q: testsuites = %+v, want 3 tests, 2 failures, %d skipped", tt.args, doc, tt.skipped)
+		}
+	}
+}
+
+func TestDetectMoves(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"Movie.mkv": "12345", "a/ep1.mkv": "1"},
+		map[string]string{"Movies/Movie.mkv": "12345", "b/ep1.mkv": "1"})
+
+	stdout, _, _ := run(t, "-f", "counts", source, target)
+	if want := "MISSING=2 MODIFIED=0 EXTRA=2 RENAMED=0 TOTAL=4\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	stdout, _, code := run(t, "-f", "csv", "--detect-moves", source, target)
+	for _, want := range []string{"MOVED,Movie.mkv,", "MOVED,a/ep1.mkv,"} {
+		if code != 1 || !strings.Contains(stdout, want) || strings.Contains(stdout, "MISSING") {
+			t.Errorf("exit status %d, stdout %q, want 1 and %q", code, stdout, want)
+		}
+	}
+
+	// Under --hash a move must also keep its content.
+	if err := os.WriteFile(filepath.Join(target, "b", "ep1.mkv"), []byte("2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ = run(t, "-f", "csv", "--detect-moves", "--hash", source, target)
+	for _, want := range []string{"MOVED,Movie.mkv,", "MISSING,a/ep1.mkv,", "EXTRA,b/ep1.mkv,"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("with --hash, stdout %q, want %q", stdout, want)
+		}
+	}
+}
+
+func TestSlashPaths(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"show/s01/ep1.mkv": "1", "show/s01/ep2.mkv": "1"},
+		map[string]string{"show/s01/ep1.mkv": "12", "show/s02/ep1.mkv": "1"})
+
+	stdout, _, _ := run(t, "-f", "json", source, target)
+	var r domain.DiffReport
+	if err := json.Unmarshal([]byte(stdout), &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Items) != 3 {
+		t.Fatalf("items = %+v, want 3", r.Items)
+	}
+	for _, item := range r.Items {
+		if strings.Contains(item.Path, `\`) || !strings.HasPrefix(item.Path, "show/s0") {
+			t.Errorf("item path %q, want forward slashes", item.Path)
+		}
+	}
+}
+
+func TestNoRecursion(t *testing.T) {
+	source, target := fixture(t,
+		map[string]string{"a.mkv": "1", "b.mkv": "1", "show/ep1.mkv": "1", "gone/ep1.mkv": "1"},
+		map[string]string{"a.mkv": "12", "b.mkv": "1", "show/ep2.mkv": "1"})
+
+	// Only top-level entries are compared; subdirectories still appear.
+	stdout, _, _ := run(t, "-f", "csv", "--no-recursion", source, target)
+	want := "type,path,reason,src_size,tgt_size\n" +
+		"MISSING,gone,,0,0\n" +
+		"MODIFIED,a.mkv,Size changed: +1 bytes,1,2\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	if same, _, _ := run(t, "-f", "csv", "--no-recursion", "--max-depth", "0", source, target); same != want {
+		t.Errorf("with --max-depth 0, stdout = %q, want %q", same, want)
+	}
+
+	_, stderr, code := run(t, "--no-recursion", "--max-depth", "2", source, target)
+	wantError(t, stderr, code, "--no-recursion can't be combined with --max-depth=2")
+}
+
+func TestSeparateEmptyDirs(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"a.mkv": "1"})
+	if err := os.Mkdir(filepath.Join(source, "extras"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(target, "samples"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, _ := run(t, "-f", "csv", source, target)
+	if !strings.Contains(stdout, "MISSING,extras,") || !strings.Contains(stdout, "EXTRA,samples,") {
+		t.Errorf("stdout = %q, want the directories missing and extra by default", stdout)
+	}
+	stdout, _, code := run(t, "-f", "csv", "--separate-empty-dirs", source, target)
+	want := "type,path,reason,src_size,tgt_size\n" +
+		"EMPTY_DIR,extras,Empty directory missing from the target,0,0\n" +
+		"EMPTY_DIR,samples,Empty directory only in the target,0,0\n"
+	if code != 1 || stdout != want {
+		t.Errorf("exit status %d, stdout %q, want 1 and %q", code, stdout, want)
+	}
+
+	// An empty directory fails the run by its side.
+	tests := []struct {
+		failOn string
+		code   int
+	}{
+		{"missing", 1},
+		{"extra", 1},
+		{"modified", 0},
+	}
+	for _, tt := range tests {
+		_, _, code := run(t, "-f", "counts", "--separate-empty-dirs", "--fail-on", tt.failOn, source, target)
+		if code != tt.code {
+			t.Errorf("--fail-on %s: exit status %d, want %d", tt.failOn, code, tt.code)
+		}
+	}
+
+	stdout, _, _ = run(t, "-f", "csv", "--separate-empty-dirs", "--only", "emptydir",
+		"--path-include", "^s", source, target)
+	if !strings.HasSuffix(stdout, "\nEMPTY_DIR,samples,Empty directory only in the target,0,0\n") ||
+		strings.Contains(stdout, "extras") {
+		t.Errorf("with --only emptydir, stdout %q, want only samples", stdout)
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1"}, map[string]string{"a.mkv": "1", "b.mkv": "2"})
+	dir := filepath.Join(t.TempDir(), "out")
+
+	stdout, stderr, code := run(t, "--format", "json,markdown", "--output-dir", dir, source, target)
+	if code != 1 || stdout != "" {
+		t.Fatalf("exit status %d, stdout %q, stderr %q, want 1 and nothing on stdout", code, stdout, stderr)
+	}
+	var r domain.DiffReport
+	data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil || r.Summary.TotalExtra != 1 {
+		t.Errorf("report.json = %s, want one extra file", data)
+	}
+	md, err := os.ReadFile(filepath.Join(dir, "report.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(md), "| EXTRA | b.mkv | Size: 1 bytes |\n") {
+		t.Errorf("report.md = %s, want the extra file listed", md)
+	}
+
+	_, stderr, code = run(t, "--format", "json,markdown", source, target)
+	wantError(t, stderr, code, "--format json,markdown writes more than one report and requires --output-dir")
+}
+
+func TestSummaryOnlyJSON(t *testing.T) {
+	source, target := fixture(t, map[string]string{"a.mkv": "1", "b.mkv": "12"}, map[string]string{"b.mkv": "12"})
+
+	stdout, _, _ := run(t, "-f", "json", "--summary-only", "-H", source, target)
+	var got struct {
+		Items   []domain.DiffItem `json:"items"`
+		Summary struct {
+			TotalMissing      int    `json:"total_missing"`
+			MissingBytesHuman string `json:"missing_bytes_human"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("stdout isn't a JSON object: %v\n%s", err, stdout)
+	}
+	if got.Items != nil || got.Summary.TotalMissing != 1 || got.Summary.MissingBytesHuman != "1 B" {
+		t.Errorf("stdout = %s, want only the summary, with formatted sizes", stdout)
+	}
+	if !strings.Contains(stdout, "\n  \"summary\": {\n") {
+		t.Errorf("stdout isn't indented like the full report:\n%s", stdout)
+	}
+}
+
+func TestShowMatchedSampled(t *testing.T) {
+	files := map[string]string{"a.mkv": "1", "b.mkv": "2", "c.mkv": "3", "d.mkv": "4"}
+	source, target := fixture(t, files, files)
+
+	stdout, stderr, code := run(t, "-f", "csv", "--hash", "--sample-percent", "50", "--show-matched", source, target)
+	if code != 0 {
+		t.Fatalf("exit status %d, stderr %q", code, stderr)
+	}
+	rows := strings.Split(strings.TrimSpace(stdout), "\n")[1:]
+	if len(rows) != 4 || strings.Count(stdout, "MATCHED,") != 4 || strings.Count(stdout, diff.NotSampledReason) != 2 {
+		t.Errorf("stdout = %q, want 4 matched files, 2 of them not verified", stdout)
+	}
+}
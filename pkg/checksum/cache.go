@@ -0,0 +1,88 @@
+package checksum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache remembers the digests of files hashed by earlier runs, keyed by
+// absolute path, so that files whose size and modification time haven't
+// changed aren't read again. Unlike a Manifest it is tied to the machine it
+// was written on. It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	files map[string]Entry
+	dirty bool
+}
+
+// LoadCache reads a cache written by Cache.Save. A missing file yields an
+// empty cache, so the first run can create it.
+func LoadCache(path string) (*Cache, error) {
+	c := &Cache{files: make(map[string]Entry)}
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading hash cache: %w", err)
+	}
+	m := Manifest{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing hash cache %s: %w", path, err)
+	}
+	for p, e := range m.Files {
+		c.files[p] = e
+	}
+	return c, nil
+}
+
+// Lookup returns the stored digest of the file at path if its size and
+// modification time still match the entry.
+func (c *Cache) Lookup(path string, size int64, modTime time.Time) (string, bool) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.files[key]
+	if !ok || entry.Size != size || !entry.ModTime.Equal(modTime) {
+		return "", false
+	}
+	return entry.SHA256, true
+}
+
+// Store records sum as the digest of the file at path, replacing any stale
+// entry.
+func (c *Cache) Store(path string, size int64, modTime time.Time, sum string) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[key] = Entry{Size: size, ModTime: modTime, SHA256: sum}
+	c.dirty = true
+}
+
+// Save writes c to path in the Manifest format. It does nothing if no entry
+// was stored since c was loaded.
+func (c *Cache) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	m := &Manifest{Files: c.files}
+	if err := m.Save(path); err != nil {
+		return fmt.Errorf("writing hash cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
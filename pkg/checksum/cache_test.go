@@ -0,0 +1,77 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.json")
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	c, err := LoadCache(path)
+	if err != nil {
+		t.Fatalf("LoadCache of a missing file: %v", err)
+	}
+	if _, ok := c.Lookup("a.mkv", 5, mtime); ok {
+		t.Error("Lookup hit in an empty cache")
+	}
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Save of an unchanged cache wrote the file: %v", err)
+	}
+
+	c.Store("a.mkv", 5, mtime, helloSHA256)
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Relative and absolute paths share an entry.
+	abs, err := filepath.Abs("a.mkv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		path  string
+		size  int64
+		mtime time.Time
+		ok    bool
+	}{
+		{"unchanged", "a.mkv", 5, mtime, true},
+		{"absolute path", abs, 5, mtime, true},
+		{"size changed", "a.mkv", 6, mtime, false},
+		{"modified", "a.mkv", 5, mtime.Add(time.Second), false},
+		{"other file", "b.mkv", 5, mtime, false},
+	}
+	for _, tt := range tests {
+		sum, ok := loaded.Lookup(tt.path, tt.size, tt.mtime)
+		if ok != tt.ok || (ok && sum != helloSHA256) {
+			t.Errorf("%s: Lookup = %q, %v, want %v", tt.name, sum, ok, tt.ok)
+		}
+	}
+
+	// A new digest replaces a stale entry.
+	loaded.Store("a.mkv", 6, mtime, "other")
+	if sum, ok := loaded.Lookup("a.mkv", 6, mtime); !ok || sum != "other" {
+		t.Errorf("Lookup after Store = %q, %v", sum, ok)
+	}
+	if _, ok := loaded.Lookup("a.mkv", 5, mtime); ok {
+		t.Error("Lookup returned the replaced entry")
+	}
+}
+
+func TestLoadCacheInvalid(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "cache.json", "not json")
+	if _, err := LoadCache(path); err == nil {
+		t.Error("LoadCache of an invalid file succeeded")
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Package checksum computes file digests and stores them in portable manifests.
+*/
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"mddiff/pkg/domain"
+)
+
+// HashFile returns the hex-encoded SHA-256 digest of the file at path.
+func HashFile(path string) (string, error) {
+	return SumFile(path, sha256.New())
+}
+
+// SumFile returns the hex-encoded digest of the file at path computed with h.
+func SumFile(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from a directory walk
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Entry is the recorded state of a single file.
+type Entry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// Manifest maps paths, relative to the root of the tree they were recorded
+// from, to their last known digest. Because paths are relative, a manifest
+// can be reused on another machine that mounts the tree elsewhere.
+type Manifest struct {
+	Files map[string]Entry `json:"files"`
+}
+
+// Load reads a manifest written by Save.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("reading hash manifest: %w", err)
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing hash manifest %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]Entry)
+	}
+	return m, nil
+}
+
+// Save writes m to path as JSON.
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Apply fills in Asset.Hash for every file in tree whose size and modification
+// time still match its manifest entry. Files that changed since the manifest
+// was written are left alone so they get rehashed. It returns the number of
+// assets that reused a stored hash.
+func (m *Manifest) Apply(tree *domain.DirectoryTree) int {
+	hits := 0
+	for path, asset := range tree.Assets {
+		entry, ok := m.Files[path]
+		if asset.IsDir || !ok || entry.Size != asset.Size || !entry.ModTime.Equal(asset.ModTime) {
+			continue
+		}
+		asset.Hash = entry.SHA256
+		tree.Assets[path] = asset
+		hits++
+	}
+	return hits
+}
+
+// Build hashes every file in tree, reusing Asset.Hash where it is already
+// set, and returns the resulting manifest.
+func Build(tree *domain.DirectoryTree) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]Entry, len(tree.Assets))}
+	for path, asset := range tree.Assets {
+		if asset.IsDir {
+			continue
+		}
+		sum := asset.Hash
+		if sum == "" {
+			var err error
+			if sum, err = HashFile(asset.AbsPath); err != nil {
+				return nil, fmt.Errorf("hashing %s: %w", path, err)
+			}
+		}
+		m.Files[path] = Entry{Size: asset.Size, ModTime: asset.ModTime, SHA256: sum}
+	}
+	return m, nil
+}
@@ -0,0 +1,96 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mddiff/pkg/domain"
+)
+
+// helloSHA256 is the SHA-256 digest of "hello".
+const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+// writeFile writes content to name under dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHashFile(t *testing.T) {
+	sum, err := HashFile(writeFile(t, t.TempDir(), "a.txt", "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != helloSHA256 {
+		t.Errorf("HashFile = %s, want %s", sum, helloSHA256)
+	}
+	if _, err := HashFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("HashFile of a missing file succeeded")
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tree := &domain.DirectoryTree{Assets: map[string]domain.Asset{
+		"a.txt": {Path: "a.txt", AbsPath: writeFile(t, dir, "a.txt", "hello"), Size: 5, ModTime: mtime},
+		"b.txt": {Path: "b.txt", Size: 3, ModTime: mtime, Hash: "known"},
+		"sub":   {Path: "sub", IsDir: true},
+	}}
+
+	m, err := Build(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 2 || m.Files["a.txt"].SHA256 != helloSHA256 || m.Files["b.txt"].SHA256 != "known" {
+		t.Fatalf("Build = %+v", m.Files)
+	}
+
+	path := filepath.Join(dir, "manifest.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Files["a.txt"]; got.SHA256 != helloSHA256 || got.Size != 5 || !got.ModTime.Equal(mtime) {
+		t.Errorf("loaded entry = %+v", got)
+	}
+}
+
+func TestManifestApply(t *testing.T) {
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := &Manifest{Files: map[string]Entry{
+		"same.txt":    {Size: 5, ModTime: mtime, SHA256: "s1"},
+		"resized.txt": {Size: 5, ModTime: mtime, SHA256: "s2"},
+		"touched.txt": {Size: 5, ModTime: mtime, SHA256: "s3"},
+	}}
+	tree := &domain.DirectoryTree{Assets: map[string]domain.Asset{
+		"same.txt":    {Path: "same.txt", Size: 5, ModTime: mtime},
+		"resized.txt": {Path: "resized.txt", Size: 6, ModTime: mtime},
+		"touched.txt": {Path: "touched.txt", Size: 5, ModTime: mtime.Add(time.Second)},
+		"new.txt":     {Path: "new.txt", Size: 5, ModTime: mtime},
+	}}
+
+	if hits := m.Apply(tree); hits != 1 {
+		t.Errorf("Apply reused %d hashes, want 1", hits)
+	}
+	for path, want := range map[string]string{"same.txt": "s1", "resized.txt": "", "touched.txt": "", "new.txt": ""} {
+		if got := tree.Assets[path].Hash; got != want {
+			t.Errorf("%s: Hash = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(writeFile(t, t.TempDir(), "m.json", "{")); err == nil {
+		t.Error("Load of invalid JSON succeeded")
+	}
+}
@@ -0,0 +1,66 @@
+package checksum
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// compareChunk is how much of each file FirstDifference compares at a time.
+const compareChunk = 256 * 1024
+
+// FirstDifference reads the files at a and b side by side and returns the
+// offset of the first byte where they differ, or -1 if their contents are
+// identical. If one file is a prefix of the other, the offset is the shorter
+// file's length. It stops reading at the first difference.
+func FirstDifference(a, b string) (int64, error) {
+	fa, err := os.Open(a) // #nosec G304 -- path comes from a directory walk
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = fa.Close() }()
+	fb, err := os.Open(b) // #nosec G304 -- path comes from a directory walk
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = fb.Close() }()
+
+	bufA := make([]byte, compareChunk)
+	bufB := make([]byte, compareChunk)
+	var offset int64
+	for {
+		na, err := readChunk(fa, bufA)
+		if err != nil {
+			return 0, err
+		}
+		nb, err := readChunk(fb, bufB)
+		if err != nil {
+			return 0, err
+		}
+		n := min(na, nb)
+		if !bytes.Equal(bufA[:n], bufB[:n]) {
+			for i := range n {
+				if bufA[i] != bufB[i] {
+					return offset + int64(i), nil
+				}
+			}
+		}
+		if na != nb {
+			return offset + int64(n), nil
+		}
+		if na < compareChunk {
+			return -1, nil
+		}
+		offset += int64(n)
+	}
+}
+
+// readChunk fills buf from r, returning fewer bytes only at the end of r.
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return n, err
+}
@@ -0,0 +1,69 @@
+package checksum
+
+import "sync"
+
+// fallbackMaxOpenFiles is used when the process's open file limit can't be
+// determined.
+const fallbackMaxOpenFiles = 64
+
+// Limiter caps the number of files held open for hashing at once, so that
+// concurrent hashing can't exhaust the process's file descriptors. It is safe
+// for concurrent use. A nil *Limiter imposes no limit.
+type Limiter struct {
+	tokens chan struct{}
+	// pair serializes taking two slots at once, so two callers can't each
+	// hold one slot while waiting for a second.
+	pair sync.Mutex
+}
+
+// NewLimiter returns a Limiter allowing n files open at once. If n is zero or
+// negative, DefaultMaxOpenFiles is used.
+func NewLimiter(n int) *Limiter {
+	if n <= 0 {
+		n = DefaultMaxOpenFiles()
+	}
+	return &Limiter{tokens: make(chan struct{}, n)}
+}
+
+// HashFile is like the package-level HashFile but waits for a free slot
+// before opening path.
+func (l *Limiter) HashFile(path string) (string, error) {
+	if l == nil {
+		return HashFile(path)
+	}
+	l.tokens <- struct{}{}
+	defer func() { <-l.tokens }()
+	return HashFile(path)
+}
+
+// QuickHashFile is like the package-level QuickHashFile but waits for a free
+// slot before opening path.
+func (l *Limiter) QuickHashFile(path string, n int64) (string, error) {
+	if l == nil {
+		return QuickHashFile(path, n)
+	}
+	l.tokens <- struct{}{}
+	defer func() { <-l.tokens }()
+	return QuickHashFile(path, n)
+}
+
+// FirstDifference is like the package-level FirstDifference but waits for
+// free slots before opening a and b.
+func (l *Limiter) FirstDifference(a, b string) (int64, error) {
+	if l == nil {
+		return FirstDifference(a, b)
+	}
+	// A limit of one still lets a comparison open its two files.
+	slots := min(2, cap(l.tokens))
+	l.pair.Lock()
+	for range slots {
+		l.tokens <- struct{}{}
+	}
+	l.pair.Unlock()
+	defer func() {
+		for range slots {
+			<-l.tokens
+		}
+	}()
+	return FirstDifference(a, b)
+}
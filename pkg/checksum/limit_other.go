@@ -0,0 +1,9 @@
+//go:build !unix
+
+package checksum
+
+// DefaultMaxOpenFiles returns a conservative fixed limit on platforms without
+// RLIMIT_NOFILE.
+func DefaultMaxOpenFiles() int {
+	return fallbackMaxOpenFiles
+}
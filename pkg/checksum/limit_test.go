@@ -0,0 +1,92 @@
+package checksum
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterWaitsForSlot(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "a.txt", "hello")
+	l := NewLimiter(1)
+
+	// Take the only slot, as another hash in progress would.
+	l.tokens <- struct{}{}
+	done := make(chan string)
+	go func() {
+		sum, _ := l.HashFile(path)
+		done <- sum
+	}()
+	select {
+	case <-done:
+		t.Fatal("HashFile opened a file while no slot was free")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-l.tokens
+	if sum := <-done; sum != helloSHA256 {
+		t.Errorf("HashFile = %s, want %s", sum, helloSHA256)
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.txt", "hello")
+	b := writeFile(t, dir, "b.txt", "hellp")
+
+	const limit = 2
+	l := NewLimiter(limit)
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			var err error
+			switch i % 3 {
+			case 0:
+				_, err = l.HashFile(a)
+			case 1:
+				_, err = l.QuickHashFile(a, 2)
+			default:
+				var offset int64
+				offset, err = l.FirstDifference(a, b)
+				if err == nil && offset != 4 {
+					t.Errorf("FirstDifference = %d, want 4", offset)
+				}
+			}
+			if err != nil {
+				t.Error(err)
+			}
+			if n := len(l.tokens); n > limit {
+				t.Errorf("%d slots taken, want at most %d", n, limit)
+			}
+		})
+	}
+	wg.Wait()
+	if n := len(l.tokens); n != 0 {
+		t.Errorf("%d slots still taken after every hash finished", n)
+	}
+}
+
+func TestLimiterSingleSlotComparison(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.txt", "hello")
+	// A limit of one must not deadlock a comparison that opens two files.
+	if _, err := NewLimiter(1).FirstDifference(a, a); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	sum, err := l.HashFile(writeFile(t, t.TempDir(), "a.txt", "hello"))
+	if err != nil || sum != helloSHA256 {
+		t.Errorf("HashFile = %s, %v, want %s", sum, err, helloSHA256)
+	}
+}
+
+func TestDefaultMaxOpenFiles(t *testing.T) {
+	if n := DefaultMaxOpenFiles(); n < 1 || n > 1024 {
+		t.Errorf("DefaultMaxOpenFiles = %d, want 1 to 1024", n)
+	}
+	if l := NewLimiter(0); cap(l.tokens) != DefaultMaxOpenFiles() {
+		t.Errorf("NewLimiter(0) allows %d files, want the default", cap(l.tokens))
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package checksum
+
+import "syscall"
+
+// DefaultMaxOpenFiles returns half of the soft RLIMIT_NOFILE, leaving the
+// rest for the scanner, the Go runtime and anything else the process has open.
+func DefaultMaxOpenFiles() int {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return fallbackMaxOpenFiles
+	}
+	n := uint64(rl.Cur) / 2 // #nosec G115 -- the soft limit is never negative
+	switch {
+	case n < 1:
+		return 1
+	case n > 1024:
+		return 1024
+	default:
+		return int(n)
+	}
+}
@@ -0,0 +1,66 @@
+package checksum
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+)
+
+// ShardOf returns which of n shards path belongs to. The mapping depends only
+// on path and n, so it is the same across runs and machines.
+func ShardOf(path string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(filepath.ToSlash(path)))
+	return int(h.Sum64() % uint64(n)) // #nosec G115 -- the result is below n
+}
+
+// ShardPath returns the file that holds shard i of the manifest at path, e.g.
+// hashes.2.json for hashes.json.
+func ShardPath(path string, i int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), i, ext)
+}
+
+// LoadSharded reads a manifest saved with SaveSharded. With n of 1 it is the
+// same as Load.
+func LoadSharded(path string, n int) (*Manifest, error) {
+	if n <= 1 {
+		return Load(path)
+	}
+	m := &Manifest{Files: make(map[string]Entry)}
+	for i := 0; i < n; i++ {
+		shard, err := Load(ShardPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		for p, e := range shard.Files {
+			m.Files[p] = e
+		}
+	}
+	return m, nil
+}
+
+// SaveSharded splits m across n files named by ShardPath, assigning each file
+// with ShardOf. With n of 1 it is the same as Save.
+func (m *Manifest) SaveSharded(path string, n int) error {
+	if n <= 1 {
+		return m.Save(path)
+	}
+	shards := make([]*Manifest, n)
+	for i := range shards {
+		shards[i] = &Manifest{Files: make(map[string]Entry)}
+	}
+	for p, e := range m.Files {
+		shards[ShardOf(p, n)].Files[p] = e
+	}
+	for i, shard := range shards {
+		if err := shard.Save(ShardPath(path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
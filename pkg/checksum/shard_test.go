@@ -0,0 +1,99 @@
+package checksum
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShardOf(t *testing.T) {
+	// The mapping must never change, or existing shards would be misread.
+	tests := []struct {
+		path string
+		n    int
+		want int
+	}{
+		{"a.mkv", 16, 8},
+		{"a.mkv", 7, 0},
+		{"show/s01/ep1.mkv", 16, 2},
+		{"show/s01/ep1.mkv", 7, 1},
+		{"show/s01/ep1.mkv", 1, 0},
+		{"show/s01/ep1.mkv", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := ShardOf(tt.path, tt.n); got != tt.want {
+			t.Errorf("ShardOf(%q, %d) = %d, want %d", tt.path, tt.n, got, tt.want)
+		}
+	}
+	for _, p := range []string{"a.mkv", "show/s01/ep1.mkv", "movies/Film (2020).mkv"} {
+		first := ShardOf(p, 16)
+		if first < 0 || first >= 16 {
+			t.Errorf("ShardOf(%q, 16) = %d, want 0 to 15", p, first)
+		}
+		for range 3 {
+			if got := ShardOf(p, 16); got != first {
+				t.Errorf("ShardOf(%q, 16) = %d, then %d", p, first, got)
+			}
+		}
+	}
+}
+
+func TestShardOfDistribution(t *testing.T) {
+	const n, paths = 8, 8000
+	counts := make([]int, n)
+	for i := range paths {
+		counts[ShardOf(fmt.Sprintf("show %d/s%02d/ep%03d.mkv", i/500, i/50%10, i%50), n)]++
+	}
+	// Each shard should hold close to paths/n entries.
+	for shard, c := range counts {
+		if c < paths/n*8/10 || c > paths/n*12/10 {
+			t.Errorf("shard %d holds %d of %d paths, want about %d: %v", shard, c, paths, paths/n, counts)
+		}
+	}
+}
+
+func TestShardPath(t *testing.T) {
+	if got, want := ShardPath("/cache/hashes.json", 2), "/cache/hashes.2.json"; got != want {
+		t.Errorf("ShardPath = %s, want %s", got, want)
+	}
+	if got, want := ShardPath("hashes", 0), "hashes.0"; got != want {
+		t.Errorf("ShardPath = %s, want %s", got, want)
+	}
+}
+
+func TestSaveLoadSharded(t *testing.T) {
+	m := &Manifest{Files: make(map[string]Entry)}
+	for i := range 20 {
+		m.Files[fmt.Sprintf("file%d.mkv", i)] = Entry{Size: int64(i), SHA256: helloSHA256}
+	}
+	path := filepath.Join(t.TempDir(), "hashes.json")
+	if err := m.SaveSharded(path, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("SaveSharded wrote the unsharded file %s", path)
+	}
+	for i := range 3 {
+		shard, err := Load(ShardPath(path, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p := range shard.Files {
+			if ShardOf(p, 3) != i {
+				t.Errorf("%s is in shard %d, want %d", p, i, ShardOf(p, 3))
+			}
+		}
+	}
+
+	loaded, err := LoadSharded(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Files) != 20 || loaded.Files["file7.mkv"].Size != 7 {
+		t.Errorf("LoadSharded = %+v", loaded.Files)
+	}
+	if _, err := LoadSharded(path, 4); err == nil {
+		t.Error("LoadSharded with a missing shard succeeded")
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Package config loads mddiff settings from YAML files.
+*/
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DirConfigName is the file a directory can carry at its root to describe how
+// it should be compared. A file of the same name in the working or home
+// directory holds the user's defaults.
+const DirConfigName = ".mddiff.yaml"
+
+// Config holds settings that can be supplied by a config file. Pointer fields
+// are nil when the file doesn't set them, so callers can tell "unset" from a
+// zero value.
+type Config struct {
+	IgnoreExt     []string `yaml:"ignore_ext"`
+	IgnoreNames   []string `yaml:"ignore_names"`
+	Hash          *bool    `yaml:"hash"`
+	SizeThreshold *int64   `yaml:"size_threshold"`
+	// Format and Compare take the same values as --format and --compare.
+	Format  []string `yaml:"format"`
+	Compare []string `yaml:"compare"`
+}
+
+// Load reads the config file at path. A missing file yields an empty Config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is chosen by the user
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadDir reads the DirConfigName file at the root of dir, if there is one.
+func LoadDir(dir string) (*Config, error) {
+	return Load(filepath.Join(dir, DirConfigName))
+}
+
+// Search returns the path of the DirConfigName file in the first of dirs that
+// has one, or "" if none does. Empty dirs are skipped.
+func Search(dirs ...string) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, DirConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Merge returns the settings of base overridden by those set in over. Ignore
+// lists are concatenated, base first, so an entry in over can negate one in
+// base with a "!" prefix.
+func Merge(base, over *Config) *Config {
+	out := *base
+	out.IgnoreExt = append(append([]string{}, base.IgnoreExt...), over.IgnoreExt...)
+	out.IgnoreNames = append(append([]string{}, base.IgnoreNames...), over.IgnoreNames...)
+	if over.Hash != nil {
+		out.Hash = over.Hash
+	}
+	if over.SizeThreshold != nil {
+		out.SizeThreshold = over.SizeThreshold
+	}
+	if over.Format != nil {
+		out.Format = over.Format
+	}
+	if over.Compare != nil {
+		out.Compare = over.Compare
+	}
+	return &out
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, DirConfigName)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, `
+ignore_ext: [.nfo, txt]
+ignore_names: ["@eaDir"]
+hash: true
+size_threshold: 4096
+format: [json]
+compare: [size, mtime]
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.IgnoreExt, []string{".nfo", "txt"}) || !slices.Equal(cfg.IgnoreNames, []string{"@eaDir"}) {
+		t.Errorf("ignore lists = %q, %q", cfg.IgnoreExt, cfg.IgnoreNames)
+	}
+	if cfg.Hash == nil || !*cfg.Hash || cfg.SizeThreshold == nil || *cfg.SizeThreshold != 4096 {
+		t.Errorf("hash, size_threshold = %v, %v", cfg.Hash, cfg.SizeThreshold)
+	}
+	if !slices.Equal(cfg.Format, []string{"json"}) || !slices.Equal(cfg.Compare, []string{"size", "mtime"}) {
+		t.Errorf("format, compare = %q, %q", cfg.Format, cfg.Compare)
+	}
+
+	fromDir, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fromDir.IgnoreExt, cfg.IgnoreExt) {
+		t.Errorf("LoadDir read %+v, want %+v", fromDir, cfg)
+	}
+}
+
+func TestLoadUnset(t *testing.T) {
+	cfg, err := Load(writeConfig(t, t.TempDir(), "ignore_ext: [.nfo]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Hash != nil || cfg.SizeThreshold != nil || cfg.Format != nil || cfg.Compare != nil {
+		t.Errorf("unset settings = %+v, want nil", cfg)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	cfg, err := LoadDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg == nil || cfg.IgnoreExt != nil || cfg.Hash != nil {
+		t.Errorf("config of a directory without one = %+v, want empty", cfg)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(writeConfig(t, t.TempDir(), "hash: [not, a, bool]\n")); err == nil {
+		t.Error("Load of invalid YAML succeeded")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	yes, no := true, false
+	size := int64(10)
+	base := &Config{
+		IgnoreExt:   []string{".nfo"},
+		IgnoreNames: []string{"a"},
+		Hash:        &yes,
+		Format:      []string{"json"},
+		Compare:     []string{"size"},
+	}
+	over := &Config{
+		IgnoreExt:     []string{"!.nfo"},
+		Hash:          &no,
+		SizeThreshold: &size,
+		Compare:       []string{"content"},
+	}
+
+	got := Merge(base, over)
+	if !slices.Equal(got.IgnoreExt, []string{".nfo", "!.nfo"}) || !slices.Equal(got.IgnoreNames, []string{"a"}) {
+		t.Errorf("ignore lists = %q, %q", got.IgnoreExt, got.IgnoreNames)
+	}
+	if *got.Hash || *got.SizeThreshold != 10 {
+		t.Errorf("hash, size_threshold = %v, %v, want over's", *got.Hash, *got.SizeThreshold)
+	}
+	if !slices.Equal(got.Format, []string{"json"}) || !slices.Equal(got.Compare, []string{"content"}) {
+		t.Errorf("format, compare = %q, %q", got.Format, got.Compare)
+	}
+	if !slices.Equal(base.IgnoreExt, []string{".nfo"}) {
+		t.Errorf("Merge changed base's IgnoreExt to %q", base.IgnoreExt)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	cwd, home, empty := t.TempDir(), t.TempDir(), t.TempDir()
+	homeConfig := writeConfig(t, home, "hash: true\n")
+	if err := os.Mkdir(filepath.Join(empty, DirConfigName), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Search(cwd, home); got != homeConfig {
+		t.Errorf("Search = %q, want %q", got, homeConfig)
+	}
+	cwdConfig := writeConfig(t, cwd, "hash: false\n")
+	tests := []struct {
+		dirs []string
+		want string
+	}{
+		{[]string{cwd, home}, cwdConfig},
+		{[]string{"", home}, homeConfig},
+		// A directory with the config's name isn't a config.
+		{[]string{empty, home}, homeConfig},
+		{[]string{empty}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Search(tt.dirs...); got != tt.want {
+			t.Errorf("Search(%q) = %q, want %q", tt.dirs, got, tt.want)
+		}
+	}
+}
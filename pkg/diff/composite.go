@@ -0,0 +1,26 @@
+package diff
+
+import "mddiff/pkg/domain"
+
+// CompositeComparator considers assets modified if any of its comparators
+// does. Comparators run in order and the first to report a modification
+// supplies the reason, so cheaper checks should come first.
+type CompositeComparator struct {
+	Comparators []domain.AssetComparator
+}
+
+// NewCompositeComparator returns a comparator that runs comparators in order.
+func NewCompositeComparator(comparators ...domain.AssetComparator) *CompositeComparator {
+	return &CompositeComparator{Comparators: comparators}
+}
+
+// Compare returns the result of the first comparator that reports src and tgt
+// as modified.
+func (c *CompositeComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	for _, comparator := range c.Comparators {
+		if isModified, reason := comparator.Compare(src, tgt); isModified {
+			return true, reason
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// fixedComparator reports every pair as modified with reason, or as
+// unchanged when reason is empty, and counts its calls.
+type fixedComparator struct {
+	reason string
+	calls  int
+}
+
+func (c *fixedComparator) Compare(_, _ domain.Asset) (bool, string) {
+	c.calls++
+	return c.reason != "", c.reason
+}
+
+func TestCompositeComparator(t *testing.T) {
+	tests := []struct {
+		name       string
+		reasons    []string
+		wantReason string
+		wantCalls  []int
+	}{
+		{"none", nil, "", nil},
+		{"all unchanged", []string{"", ""}, "", []int{1, 1}},
+		{"first reason wins", []string{"", "size", "hash"}, "size", []int{1, 1, 0}},
+		{"short-circuits", []string{"size", "hash"}, "size", []int{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comparators []domain.AssetComparator
+			var fixed []*fixedComparator
+			for _, reason := range tt.reasons {
+				c := &fixedComparator{reason: reason}
+				fixed = append(fixed, c)
+				comparators = append(comparators, c)
+			}
+			modified, reason := NewCompositeComparator(comparators...).Compare(file("a.mkv", 1), file("a.mkv", 1))
+			if modified != (tt.wantReason != "") || reason != tt.wantReason {
+				t.Errorf("Compare = %v, %q, want %v, %q", modified, reason, tt.wantReason != "", tt.wantReason)
+			}
+			var calls []int
+			for _, c := range fixed {
+				calls = append(calls, c.calls)
+			}
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package diff
+
+import (
+	"fmt"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/domain"
+)
+
+// ContentComparator compares assets byte for byte, reading both files side by
+// side and stopping at the first difference. Unlike HashComparator it trusts
+// neither digests nor sizes, and it reports where the files diverge. Like
+// HashComparator, it reads each file from its Asset.AbsPath.
+type ContentComparator struct {
+	// Limiter, when set, caps how many files are open at once.
+	Limiter *checksum.Limiter
+}
+
+// Compare reports whether the contents of src and tgt differ.
+func (c *ContentComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir {
+		return false, ""
+	}
+	if src.Size != tgt.Size {
+		return true, fmt.Sprintf("Content differs in size (%d -> %d bytes)", src.Size, tgt.Size)
+	}
+	// Assets from a manifest or a remote host have no file on this machine.
+	if src.AbsPath == "" {
+		return true, "Unable to read source: no local file"
+	}
+	if tgt.AbsPath == "" {
+		return true, "Unable to read target: no local file"
+	}
+
+	offset, err := c.Limiter.FirstDifference(src.AbsPath, tgt.AbsPath)
+	if err != nil {
+		return true, fmt.Sprintf("Unable to compare content: %v", err)
+	}
+	if offset >= 0 {
+		return true, fmt.Sprintf("Content differs at byte %d", offset)
+	}
+	return false, ""
+}
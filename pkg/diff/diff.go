@@ -0,0 +1,553 @@
+/*
+Package diff compares two scanned directory trees.
+*/
+package diff
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+
+	"mddiff/pkg/domain"
+)
+
+// Engine compares a source tree against a target tree.
+type Engine struct {
+	comparator domain.AssetComparator
+
+	// Verifier, when set, re-checks assets that comparator considers unchanged,
+	// e.g. by hashing their content.
+	Verifier domain.AssetComparator
+	// SamplePercent limits verification to a random subset of the matched
+	// assets. Zero or 100 verifies every match.
+	SamplePercent float64
+	// SampleSeed seeds the sampling RNG so a run can be reproduced.
+	SampleSeed int64
+	// DuplicatePatterns, when set, enables reporting files in either tree
+	// whose stem is another file's stem plus a matching suffix.
+	DuplicatePatterns []*regexp.Regexp
+	// DetectMoves collapses MISSING/EXTRA pairs with the same name, extension
+	// and size in different directories into MOVED items. It runs before
+	// DetectRenames.
+	DetectMoves bool
+	// DetectRenames collapses MISSING/EXTRA pairs that look like the same
+	// file under a new name into RENAMED items.
+	DetectRenames bool
+	// RenameVerifier, when set, confirms each rename DetectRenames finds and
+	// each move DetectMoves finds, e.g. by hashing both files. A pair it
+	// reports as modified stays MISSING and EXTRA.
+	RenameVerifier domain.AssetComparator
+	// SeparateExtChanges reports modifications that the comparator attributes
+	// to an extension change as EXT_CHANGED items rather than MODIFIED.
+	SeparateExtChanges bool
+	// SeparateEmptyDirs reports empty directories found on only one side as
+	// EMPTY_DIR items rather than MISSING or EXTRA.
+	SeparateEmptyDirs bool
+	// CountRenamesAsModified adds renames to Summary.TotalModified as well as
+	// Summary.TotalRenamed.
+	CountRenamesAsModified bool
+	// IgnoreCase matches assets whose directory and stem differ only in case,
+	// e.g. after a sync through a case-insensitive filesystem. Such a pair is
+	// reported as modified with CaseDiffersReason unless the comparator finds
+	// another difference.
+	IgnoreCase bool
+	// NormalizeUnicode matches assets whose directory and stem are the same
+	// once normalized to NFC, such as a name in the decomposed (NFD) form
+	// macOS filesystems use and the same name in the composed form Linux
+	// tools produce.
+	NormalizeUnicode bool
+	// ShowMatched adds a MATCHED item for every file found unchanged, after
+	// verification. On large trees this makes the report as long as the
+	// number of files, so it is off by default. With SamplePercent, the files
+	// left out of the sample are given NotSampledReason.
+	ShowMatched bool
+}
+
+// CaseDiffersReason is given for assets matched by IgnoreCase whose paths
+// differ in case.
+const CaseDiffersReason = "Case differs"
+
+// NotSampledReason is given for MATCHED items that SamplePercent left out of
+// verification, so they were only compared by the Comparator.
+const NotSampledReason = "Not verified: outside the sample"
+
+// NewEngine returns an Engine that uses comparator to detect modifications.
+func NewEngine(comparator domain.AssetComparator) *Engine {
+	return &Engine{comparator: comparator}
+}
+
+// Diff compares source against target. Assets are matched by identity (their
+// directory and stem), so a file whose extension changed is reported as
+// modified rather than as a missing/extra pair. Items are sorted by type, then
+// path.
+func (e *Engine) Diff(source, target *domain.DirectoryTree) *domain.DiffReport {
+	return e.DiffContext(context.Background(), source, target)
+}
+
+// DiffContext is like Diff but stops comparing assets once ctx is done. The
+// report then only covers the assets compared so far and has Truncated set.
+func (e *Engine) DiffContext(ctx context.Context, source, target *domain.DirectoryTree) *domain.DiffReport {
+	report := &domain.DiffReport{
+		SchemaVersion: domain.ReportSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		SourceDir:     source.RootPath,
+		TargetDir:     target.RootPath,
+		Items:         []domain.DiffItem{},
+	}
+	report.Summary.TotalSkipped = len(source.ScanErrors) + len(target.ScanErrors)
+
+	sourceParents := parentDirs(source)
+	targetParents := parentDirs(target)
+
+	pairs := matchAssets(source, target, e.identity)
+	matched := make(map[string]bool, len(pairs))
+	for _, tgt := range pairs {
+		matched[tgt.Path] = true
+	}
+
+	var unchanged [][2]domain.Asset
+	for _, src := range source.Assets {
+		if ctx.Err() != nil {
+			report.Truncated = true
+			sumBytes(report)
+			sortItems(report.Items)
+			return report
+		}
+		tgt, ok := pairs[src.Path]
+		if !ok {
+			// A non-empty directory is represented by its contents.
+			if src.IsDir && sourceParents[src.Path] {
+				continue
+			}
+			if src.IsDir && e.SeparateEmptyDirs {
+				report.Items = append(report.Items, domain.DiffItem{
+					Type:   domain.EmptyDir,
+					Path:   src.Path,
+					Reason: domain.EmptyDirInSource,
+				})
+				report.Summary.TotalEmptyDirs++
+				continue
+			}
+			report.Items = append(report.Items, domain.DiffItem{
+				Type:    domain.Missing,
+				Path:    src.Path,
+				SrcSize: src.Size,
+			})
+			report.Summary.TotalMissing++
+			continue
+		}
+		if !src.IsDir {
+			report.Summary.TotalPaired++
+		}
+
+		isModified, reason := e.comparator.Compare(src, tgt)
+		if !isModified && e.caseDiffers(src, tgt) {
+			isModified, reason = true, CaseDiffersReason
+		}
+		if isModified {
+			item := modifiedItem(src, tgt, reason)
+			if e.SeparateExtChanges && reasonKind(reason) == extChangedReason {
+				item.Type = domain.ExtChanged
+				report.Summary.TotalExtChanged++
+			} else {
+				report.Summary.TotalModified++
+			}
+			report.Items = append(report.Items, item)
+			continue
+		}
+		if !src.IsDir {
+			unchanged = append(unchanged, [2]domain.Asset{src, tgt})
+		}
+	}
+
+	for _, tgt := range target.Assets {
+		if matched[tgt.Path] || (tgt.IsDir && targetParents[tgt.Path]) {
+			continue
+		}
+		if tgt.IsDir && e.SeparateEmptyDirs {
+			report.Items = append(report.Items, domain.DiffItem{
+				Type:   domain.EmptyDir,
+				Path:   tgt.Path,
+				Reason: domain.EmptyDirInTarget,
+			})
+			report.Summary.TotalEmptyDirs++
+			continue
+		}
+		report.Items = append(report.Items, domain.DiffItem{
+			Type:    domain.Extra,
+			Path:    tgt.Path,
+			TgtSize: tgt.Size,
+		})
+		report.Summary.TotalExtra++
+	}
+
+	if e.DetectMoves {
+		e.collapseMoves(report, source, target)
+	}
+	if e.DetectRenames {
+		e.collapseRenames(report, source, target)
+	}
+
+	var sampled map[string]bool
+	if e.Verifier != nil {
+		sampled = e.verify(ctx, report, unchanged)
+	}
+	// A truncated run may not have verified every match.
+	if e.ShowMatched && !report.Truncated {
+		addMatched(report, unchanged, sampled)
+	}
+
+	if len(e.DuplicatePatterns) > 0 {
+		report.Duplicates = append(
+			findDuplicates(source, "source", e.DuplicatePatterns),
+			findDuplicates(target, "target", e.DuplicatePatterns)...,
+		)
+	}
+
+	sumBytes(report)
+	sortItems(report.Items)
+	return report
+}
+
+// sumBytes totals the sizes of the report's items into its summary. It runs
+// once the items are final, since rename detection replaces missing and extra
+// items.
+func sumBytes(report *domain.DiffReport) {
+	for _, item := range report.Items {
+		switch item.Type {
+		case domain.Missing:
+			report.Summary.MissingBytes += item.SrcSize
+		case domain.Extra:
+			report.Summary.ExtraBytes += item.TgtSize
+		case domain.Modified, domain.ExtChanged:
+			report.Summary.ModifiedBytes += item.SizeDelta
+		}
+	}
+}
+
+// sortItems orders items by type, then path, so reports don't depend on map
+// iteration order.
+func sortItems(items []domain.DiffItem) {
+	SortItems(items, SortByType, false)
+}
+
+// verify runs the Verifier over the unchanged pairs, or a sample of them. When
+// it samples, it returns the source paths of the pairs in the sample.
+func (e *Engine) verify(
+	ctx context.Context, report *domain.DiffReport, unchanged [][2]domain.Asset,
+) (sampled map[string]bool) {
+	// Sort so the sample chosen for a given seed doesn't depend on map order.
+	sort.Slice(unchanged, func(i, j int) bool {
+		return unchanged[i][0].Path < unchanged[j][0].Path
+	})
+
+	selected := unchanged
+	if e.SamplePercent > 0 && e.SamplePercent < 100 {
+		indices := sampleIndices(len(unchanged), e.SamplePercent, e.SampleSeed)
+		selected = make([][2]domain.Asset, 0, len(indices))
+		sampled = make(map[string]bool, len(indices))
+		for _, i := range indices {
+			selected = append(selected, unchanged[i])
+			sampled[unchanged[i][0].Path] = true
+		}
+		report.Sampling = &domain.Sampling{
+			Percent:  e.SamplePercent,
+			Seed:     e.SampleSeed,
+			Eligible: len(unchanged),
+			Sampled:  len(selected),
+		}
+	}
+
+	for _, pair := range selected {
+		if ctx.Err() != nil {
+			report.Truncated = true
+			return sampled
+		}
+		src, tgt := pair[0], pair[1]
+		if isModified, reason := e.Verifier.Compare(src, tgt); isModified {
+			report.Items = append(report.Items, modifiedItem(src, tgt, reason))
+			report.Summary.TotalModified++
+		}
+	}
+	return sampled
+}
+
+// addMatched adds a MATCHED item for each unchanged pair that verification
+// didn't find modified. sampled, when not nil, holds the source paths of the
+// pairs that were verified; the others are marked with NotSampledReason.
+func addMatched(report *domain.DiffReport, unchanged [][2]domain.Asset, sampled map[string]bool) {
+	modified := make(map[string]bool)
+	for _, item := range report.Items {
+		if item.Type == domain.Modified {
+			modified[item.Path] = true
+		}
+	}
+	for _, pair := range unchanged {
+		src, tgt := pair[0], pair[1]
+		if modified[src.Path] {
+			continue
+		}
+		item := domain.DiffItem{
+			Type:    domain.Matched,
+			Path:    src.Path,
+			SrcSize: src.Size,
+			TgtSize: tgt.Size,
+		}
+		if sampled != nil && !sampled[src.Path] {
+			item.Reason = NotSampledReason
+		}
+		report.Items = append(report.Items, item)
+	}
+}
+
+func modifiedItem(src, tgt domain.Asset, reason string) domain.DiffItem {
+	return domain.DiffItem{
+		Type:      domain.Modified,
+		Path:      src.Path,
+		Reason:    reason,
+		SrcSize:   src.Size,
+		TgtSize:   tgt.Size,
+		SizeDelta: tgt.Size - src.Size,
+	}
+}
+
+// makeIdentity keys an asset by its directory and stem so that files can be
+// matched across extension changes. Directories keep their full path, with a
+// trailing slash so they never collide with a file's stem.
+func makeIdentity(asset domain.Asset) string {
+	if asset.IsDir {
+		return asset.Path + "/"
+	}
+	stem := strings.TrimSuffix(path.Base(asset.Path), asset.Ext)
+	return path.Join(path.Dir(asset.Path), stem)
+}
+
+// identity returns the key the engine matches asset by: its makeIdentity,
+// normalized to NFC when NormalizeUnicode is set and lowercased when
+// IgnoreCase is set.
+func (e *Engine) identity(asset domain.Asset) string {
+	id := e.normalized(asset)
+	if e.IgnoreCase {
+		return strings.ToLower(id)
+	}
+	return id
+}
+
+// normalized returns the makeIdentity of asset, normalized to NFC when
+// NormalizeUnicode is set.
+func (e *Engine) normalized(asset domain.Asset) string {
+	if e.NormalizeUnicode {
+		return norm.NFC.String(makeIdentity(asset))
+	}
+	return makeIdentity(asset)
+}
+
+// caseDiffers reports whether src and tgt, matched by IgnoreCase, have names
+// that differ in case. Only the name's own case counts, so renaming a
+// directory's case reports the directory rather than everything in it.
+func (e *Engine) caseDiffers(src, tgt domain.Asset) bool {
+	return e.IgnoreCase && path.Base(e.normalized(src)) != path.Base(e.normalized(tgt))
+}
+
+// matchAssets pairs each source asset with the target asset it should be
+// compared against, keyed by source path. Assets are grouped by identity, since
+// a stem can have several files, e.g. movie.mkv and its movie.srt sidecar.
+// Within a group, files with the same path are paired first, then files with
+// the same extension, then files whose extensions differ only in case. If
+// exactly one file is then left on each side, they are paired as an extension
+// change; any others are left unmatched. Paths only differ within a group when
+// identity ignores case.
+func matchAssets(source, target *domain.DirectoryTree, identity func(domain.Asset) string) map[string]domain.Asset {
+	sourceGroups := groupByIdentity(source, identity)
+	targetGroups := groupByIdentity(target, identity)
+
+	pairs := make(map[string]domain.Asset, len(source.Assets))
+	for id, srcs := range sourceGroups {
+		tgts := targetGroups[id]
+		if len(tgts) == 0 {
+			continue
+		}
+		srcs, tgts = pairBy(srcs, tgts, pairs, func(a, b domain.Asset) bool { return a.Path == b.Path })
+		srcs, tgts = pairBy(srcs, tgts, pairs, func(a, b domain.Asset) bool { return a.Ext == b.Ext })
+		srcs, tgts = pairBy(srcs, tgts, pairs, func(a, b domain.Asset) bool {
+			return strings.EqualFold(a.Ext, b.Ext)
+		})
+		if len(srcs) == 1 && len(tgts) == 1 {
+			pairs[srcs[0].Path] = tgts[0]
+		}
+	}
+	return pairs
+}
+
+// pairBy records in pairs each source asset that has a target asset for which
+// match is true, and returns the assets left unpaired on either side.
+func pairBy(srcs, tgts []domain.Asset, pairs map[string]domain.Asset,
+	match func(a, b domain.Asset) bool,
+) (restSrcs, restTgts []domain.Asset) {
+	used := make([]bool, len(tgts))
+	for _, src := range srcs {
+		paired := false
+		for i, tgt := range tgts {
+			if !used[i] && match(src, tgt) {
+				pairs[src.Path] = tgt
+				used[i] = true
+				paired = true
+				break
+			}
+		}
+		if !paired {
+			restSrcs = append(restSrcs, src)
+		}
+	}
+	for i, tgt := range tgts {
+		if !used[i] {
+			restTgts = append(restTgts, tgt)
+		}
+	}
+	return restSrcs, restTgts
+}
+
+// groupByIdentity groups tree's assets by identity, each group sorted by path.
+func groupByIdentity(tree *domain.DirectoryTree, identity func(domain.Asset) string) map[string][]domain.Asset {
+	groups := make(map[string][]domain.Asset)
+	for _, asset := range tree.Assets {
+		id := identity(asset)
+		groups[id] = append(groups[id], asset)
+	}
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return group[i].Path < group[j].Path })
+	}
+	return groups
+}
+
+// parentDirs returns the set of directories in tree that contain at least one
+// other asset.
+func parentDirs(tree *domain.DirectoryTree) map[string]bool {
+	parents := make(map[string]bool)
+	for _, asset := range tree.Assets {
+		parents[path.Dir(asset.Path)] = true
+	}
+	return parents
+}
+
+// NopComparator never reports a modification. With it, an Engine only reports
+// which identities (directory and stem) are missing or extra.
+type NopComparator struct{}
+
+// Compare implements domain.AssetComparator.
+func (NopComparator) Compare(_, _ domain.Asset) (isModified bool, reason string) {
+	return false, ""
+}
+
+// BasicComparator compares assets by extension and size.
+type BasicComparator struct {
+	// SizeThreshold is the absolute size difference, in bytes, tolerated
+	// before a file is considered modified, whichever side is larger.
+	SizeThreshold int64
+	// SizeThresholdPercent, when positive, replaces SizeThreshold with a
+	// tolerance of this percentage of the larger file.
+	SizeThresholdPercent float64
+	// ExtThresholds overrides SizeThreshold per lowercased extension. The
+	// DefaultThresholdKey entry, if present, applies to unlisted extensions.
+	ExtThresholds map[string]Threshold
+	// FlagZeroByte reports a file as modified whenever either side is empty,
+	// since an empty file is often a placeholder or a failed download.
+	FlagZeroByte bool
+	// CompareFileType reports a file as modified when it is a symlink on one
+	// side and a regular file on the other.
+	CompareFileType bool
+	// CompareLinkTargets compares two symbolic links by where they point
+	// rather than by size.
+	CompareLinkTargets bool
+	// EquivalentExts lists extensions that name the same format, such as
+	// .jpg and .jpeg. A change between two of them isn't a modification.
+	EquivalentExts ExtEquivalence
+	// LowercaseExtInReason shows extensions in lowercase in reasons. It only
+	// affects display; extensions are still matched as they are on disk.
+	LowercaseExtInReason bool
+}
+
+// threshold returns the size threshold that applies to files with ext.
+func (c *BasicComparator) threshold(ext string) Threshold {
+	if t, ok := c.ExtThresholds[strings.ToLower(ext)]; ok {
+		return t
+	}
+	if t, ok := c.ExtThresholds[DefaultThresholdKey]; ok {
+		return t
+	}
+	if c.SizeThresholdPercent > 0 {
+		return Threshold{Percent: c.SizeThresholdPercent, IsPercent: true}
+	}
+	return Threshold{Bytes: c.SizeThreshold}
+}
+
+// SymlinkChangedReason starts the reason given for a symbolic link that points
+// somewhere else, with CompareLinkTargets.
+const SymlinkChangedReason = "Symlink target changed"
+
+// extChangedReason starts the reason given for an extension change.
+const extChangedReason = "Extension changed"
+
+func (c *BasicComparator) extReason(srcExt, tgtExt string) string {
+	if !c.LowercaseExtInReason {
+		return fmt.Sprintf("%s: %s -> %s", extChangedReason, srcExt, tgtExt)
+	}
+	srcExt, tgtExt = strings.ToLower(srcExt), strings.ToLower(tgtExt)
+	if srcExt == tgtExt {
+		return fmt.Sprintf("%s: %s (case only)", extChangedReason, srcExt)
+	}
+	return fmt.Sprintf("%s: %s -> %s", extChangedReason, srcExt, tgtExt)
+}
+
+func fileType(asset domain.Asset) string {
+	if asset.IsSymlink {
+		return "symlink"
+	}
+	return "file"
+}
+
+// Compare reports whether src and tgt differ in extension or size.
+func (c *BasicComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir {
+		return false, ""
+	}
+
+	if c.CompareFileType && src.IsSymlink != tgt.IsSymlink {
+		return true, fmt.Sprintf("Type differs: %s vs %s", fileType(src), fileType(tgt))
+	}
+
+	if c.FlagZeroByte && (src.Size == 0 || tgt.Size == 0) {
+		return true, "Zero-byte file"
+	}
+
+	if src.Ext != tgt.Ext && !c.EquivalentExts.Equivalent(src.Ext, tgt.Ext) {
+		return true, c.extReason(src.Ext, tgt.Ext)
+	}
+
+	if c.CompareLinkTargets && src.IsSymlink && tgt.IsSymlink {
+		if src.LinkTarget != tgt.LinkTarget {
+			return true, fmt.Sprintf("%s: %s -> %s", SymlinkChangedReason, src.LinkTarget, tgt.LinkTarget)
+		}
+		return false, ""
+	}
+
+	if t := c.threshold(src.Ext); t.Exceeded(src.Size, tgt.Size) {
+		delta := tgt.Size - src.Size
+		if t.IsPercent {
+			pct := percentDelta(src.Size, tgt.Size)
+			if delta < 0 {
+				pct = -pct
+			}
+			return true, fmt.Sprintf("%s: %+d bytes (%+.1f%%)", domain.SizeChangedReason, delta, pct)
+		}
+		return true, fmt.Sprintf("%s: %+d bytes", domain.SizeChangedReason, delta)
+	}
+
+	return false, ""
+}
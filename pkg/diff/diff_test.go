@@ -0,0 +1,513 @@
+package diff
+
+import (
+	"context"
+	"path"
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// file returns a file asset at p with size bytes.
+func file(p string, size int64) domain.Asset {
+	return domain.Asset{Path: p, Ext: path.Ext(p), Size: size}
+}
+
+// dir returns a directory asset at p.
+func dir(p string) domain.Asset {
+	return domain.Asset{Path: p, IsDir: true}
+}
+
+// symlink returns a symbolic link asset at p with size bytes.
+func symlink(p string, size int64) domain.Asset {
+	a := file(p, size)
+	a.IsSymlink = true
+	return a
+}
+
+// tree returns a DirectoryTree rooted at root holding assets.
+func tree(root string, assets ...domain.Asset) *domain.DirectoryTree {
+	t := &domain.DirectoryTree{RootPath: root, Assets: make(map[string]domain.Asset)}
+	for _, a := range assets {
+		t.Assets[a.Path] = a
+		if !a.IsDir {
+			t.FileCount++
+			t.TotalSize += a.Size
+		}
+	}
+	return t
+}
+
+// itemKey is the part of a DiffItem most tests check.
+type itemKey struct {
+	Type domain.DiffType
+	Path string
+}
+
+func keys(items []domain.DiffItem) []itemKey {
+	out := make([]itemKey, len(items))
+	for i, item := range items {
+		out[i] = itemKey{item.Type, item.Path}
+	}
+	return out
+}
+
+func TestEngineDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		source []domain.Asset
+		target []domain.Asset
+		want   []itemKey
+	}{
+		{
+			name:   "identical",
+			source: []domain.Asset{file("a.mkv", 10), dir("d"), file("d/b.mkv", 20)},
+			target: []domain.Asset{file("a.mkv", 10), dir("d"), file("d/b.mkv", 20)},
+			want:   []itemKey{},
+		},
+		{
+			name:   "missing and extra",
+			source: []domain.Asset{file("a.mkv", 10), file("b.mkv", 20)},
+			target: []domain.Asset{file("b.mkv", 20), file("c.mkv", 30)},
+			want:   []itemKey{{domain.Extra, "c.mkv"}, {domain.Missing, "a.mkv"}},
+		},
+		{
+			name:   "size change",
+			source: []domain.Asset{file("a.mkv", 10)},
+			target: []domain.Asset{file("a.mkv", 11)},
+			want:   []itemKey{{domain.Modified, "a.mkv"}},
+		},
+		{
+			name:   "extension change",
+			source: []domain.Asset{file("a.avi", 10)},
+			target: []domain.Asset{file("a.mkv", 10)},
+			want:   []itemKey{{domain.Modified, "a.avi"}},
+		},
+		{
+			name:   "missing directory is represented by its contents",
+			source: []domain.Asset{dir("d"), file("d/a.mkv", 10)},
+			target: []domain.Asset{},
+			want:   []itemKey{{domain.Missing, "d/a.mkv"}},
+		},
+		{
+			name:   "empty directory",
+			source: []domain.Asset{dir("d")},
+			target: []domain.Asset{dir("e")},
+			want:   []itemKey{{domain.Extra, "e"}, {domain.Missing, "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&BasicComparator{})
+			r := engine.Diff(tree("src", tt.source...), tree("tgt", tt.target...))
+			if got := keys(r.Items); !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if r.SourceDir != "src" || r.TargetDir != "tgt" {
+				t.Errorf("dirs = %q, %q, want src, tgt", r.SourceDir, r.TargetDir)
+			}
+		})
+	}
+}
+
+func TestEngineDiffSummary(t *testing.T) {
+	source := tree("src", file("a.mkv", 10), file("b.mkv", 20), file("c.mkv", 30))
+	target := tree("tgt", file("b.mkv", 25), file("c.mkv", 30), file("d.mkv", 40))
+
+	r := NewEngine(&BasicComparator{}).Diff(source, target)
+	want := domain.Summary{
+		TotalMissing:  1,
+		TotalModified: 1,
+		TotalExtra:    1,
+		TotalPaired:   2,
+		MissingBytes:  10,
+		ExtraBytes:    40,
+		ModifiedBytes: 5,
+	}
+	if r.Summary != want {
+		t.Errorf("summary = %+v, want %+v", r.Summary, want)
+	}
+}
+
+func TestBasicComparator(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        BasicComparator
+		src, tgt domain.Asset
+		modified bool
+		reason   string
+	}{
+		{"same", BasicComparator{}, file("a.mkv", 10), file("a.mkv", 10), false, ""},
+		{"grew", BasicComparator{}, file("a.mkv", 10), file("a.mkv", 12), true, "Size changed: +2 bytes"},
+		{"shrank", BasicComparator{}, file("a.mkv", 10), file("a.mkv", 7), true, "Size changed: -3 bytes"},
+		{
+			"extension", BasicComparator{}, file("a.avi", 10), file("a.mkv", 10),
+			true, "Extension changed: .avi -> .mkv",
+		},
+		{"directories", BasicComparator{}, dir("d"), dir("d"), false, ""},
+		{"within threshold", BasicComparator{SizeThreshold: 2}, file("a.mkv", 10), file("a.mkv", 12), false, ""},
+		{"shrank within threshold", BasicComparator{SizeThreshold: 2}, file("a.mkv", 12), file("a.mkv", 10), false, ""},
+		{
+			"beyond threshold", BasicComparator{SizeThreshold: 2}, file("a.mkv", 10), file("a.mkv", 13),
+			true, "Size changed: +3 bytes",
+		},
+		{"both empty", BasicComparator{}, file("a.mkv", 0), file("a.mkv", 0), false, ""},
+		{
+			"both empty flagged", BasicComparator{FlagZeroByte: true}, file("a.mkv", 0), file("a.mkv", 0),
+			true, "Zero-byte file",
+		},
+		{
+			"one empty flagged", BasicComparator{FlagZeroByte: true}, file("a.mkv", 10), file("a.mkv", 0),
+			true, "Zero-byte file",
+		},
+		{
+			"extension case", BasicComparator{}, file("a.MKV", 10), file("a.mp4", 10),
+			true, "Extension changed: .MKV -> .mp4",
+		},
+		{
+			"extension case lowercased", BasicComparator{LowercaseExtInReason: true}, file("a.MKV", 10),
+			file("a.mp4", 10), true, "Extension changed: .mkv -> .mp4",
+		},
+		{
+			"extension case only", BasicComparator{LowercaseExtInReason: true}, file("a.MKV", 10),
+			file("a.mkv", 10), true, "Extension changed: .mkv (case only)",
+		},
+		{"symlink ignored", BasicComparator{}, file("a.mkv", 10), symlink("a.mkv", 10), false, ""},
+		{
+			"file vs symlink", BasicComparator{CompareFileType: true}, file("a.mkv", 10), symlink("a.mkv", 10),
+			true, "Type differs: file vs symlink",
+		},
+		{
+			"symlink vs file", BasicComparator{CompareFileType: true}, symlink("a.mkv", 10), file("a.mkv", 10),
+			true, "Type differs: symlink vs file",
+		},
+		{"both symlinks", BasicComparator{CompareFileType: true}, symlink("a.mkv", 10), symlink("a.mkv", 10), false, ""},
+		{"neither empty flagged", BasicComparator{FlagZeroByte: true}, file("a.mkv", 10), file("a.mkv", 10), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified, reason := tt.c.Compare(tt.src, tt.tgt)
+			if modified != tt.modified || reason != tt.reason {
+				t.Errorf("Compare = %v, %q, want %v, %q", modified, reason, tt.modified, tt.reason)
+			}
+		})
+	}
+}
+
+// countingComparator records the source paths it is asked to compare and
+// reports those in modified as modified.
+type countingComparator struct {
+	compared []string
+	modified map[string]bool
+}
+
+func (c *countingComparator) Compare(src, _ domain.Asset) (bool, string) {
+	c.compared = append(c.compared, src.Path)
+	if c.modified[src.Path] {
+		return true, "Content hash mismatch"
+	}
+	return false, ""
+}
+
+func TestEngineVerifier(t *testing.T) {
+	source := tree("src", file("a.mkv", 10), file("b.mkv", 20), file("c.mkv", 30))
+	target := tree("tgt", file("a.mkv", 10), file("b.mkv", 20), file("c.mkv", 31))
+
+	verifier := &countingComparator{modified: map[string]bool{"b.mkv": true}}
+	engine := NewEngine(&BasicComparator{})
+	engine.Verifier = verifier
+	r := engine.Diff(source, target)
+
+	// c.mkv was already found modified, so only the unchanged pairs are
+	// verified.
+	if want := []string{"a.mkv", "b.mkv"}; !slices.Equal(verifier.compared, want) {
+		t.Errorf("verified %v, want %v", verifier.compared, want)
+	}
+	want := []itemKey{{domain.Modified, "b.mkv"}, {domain.Modified, "c.mkv"}}
+	if got := keys(r.Items); !slices.Equal(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+	if r.Summary.TotalModified != 2 {
+		t.Errorf("TotalModified = %d, want 2", r.Summary.TotalModified)
+	}
+	if r.Sampling != nil {
+		t.Errorf("Sampling = %+v, want nil without SamplePercent", r.Sampling)
+	}
+}
+
+func TestEngineSampledVerification(t *testing.T) {
+	var assets []domain.Asset
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		assets = append(assets, file(name+".mkv", 10))
+	}
+	source, target := tree("src", assets...), tree("tgt", assets...)
+
+	run := func(seed int64) ([]string, *domain.Sampling) {
+		verifier := &countingComparator{}
+		engine := NewEngine(&BasicComparator{})
+		engine.Verifier = verifier
+		engine.SamplePercent = 30
+		engine.SampleSeed = seed
+		r := engine.Diff(source, target)
+		return verifier.compared, r.Sampling
+	}
+
+	first, sampling := run(42)
+	if len(first) != 3 {
+		t.Fatalf("verified %d files, want 3 (30%% of 10)", len(first))
+	}
+	want := domain.Sampling{Percent: 30, Seed: 42, Eligible: 10, Sampled: 3}
+	if sampling == nil || *sampling != want {
+		t.Errorf("Sampling = %+v, want %+v", sampling, want)
+	}
+	if again, _ := run(42); !slices.Equal(again, first) {
+		t.Errorf("seed 42 sampled %v, then %v", first, again)
+	}
+}
+
+func TestEngineShowMatchedSampled(t *testing.T) {
+	var assets []domain.Asset
+	for _, name := range []string{"a", "b", "c", "d"} {
+		assets = append(assets, file(name+".mkv", 10))
+	}
+	source, target := tree("src", assets...), tree("tgt", assets...)
+
+	verifier := &countingComparator{}
+	engine := NewEngine(&BasicComparator{})
+	engine.Verifier = verifier
+	engine.ShowMatched = true
+	engine.SamplePercent = 50
+	engine.SampleSeed = 1
+	r := engine.Diff(source, target)
+
+	if len(r.Items) != 4 || len(verifier.compared) != 2 {
+		t.Fatalf("items = %+v after verifying %v, want 4 matched and 2 verified", r.Items, verifier.compared)
+	}
+	for _, item := range r.Items {
+		wantReason := NotSampledReason
+		if slices.Contains(verifier.compared, item.Path) {
+			wantReason = ""
+		}
+		if item.Type != domain.Matched || item.Reason != wantReason {
+			t.Errorf("item %+v, want MATCHED with reason %q", item, wantReason)
+		}
+	}
+
+	// Without sampling, every match is verified.
+	engine.SamplePercent = 0
+	for _, item := range engine.Diff(source, target).Items {
+		if item.Reason != "" {
+			t.Errorf("item %+v, want no reason without sampling", item)
+		}
+	}
+}
+
+func TestSampleIndices(t *testing.T) {
+	tests := []struct {
+		n       int
+		percent float64
+		want    int
+	}{
+		{0, 50, 0},
+		{10, 0, 0},
+		{10, 100, 10},
+		{10, 25, 3},
+		{1000, 0.01, 1},
+	}
+	for _, tt := range tests {
+		got := sampleIndices(tt.n, tt.percent, 1)
+		if len(got) != tt.want {
+			t.Errorf("sampleIndices(%d, %v) chose %d indices, want %d", tt.n, tt.percent, len(got), tt.want)
+		}
+		if !slices.IsSorted(got) {
+			t.Errorf("sampleIndices(%d, %v) = %v, want ascending", tt.n, tt.percent, got)
+		}
+	}
+}
+
+func TestNopComparator(t *testing.T) {
+	source := tree("src", file("show/ep1.mkv", 1), file("movie.avi", 1), file("same.mkv", 1))
+	target := tree("tgt", file("show/ep1.mp4", 3), file("other.mkv", 1), file("same.mkv", 2))
+
+	r := NewEngine(NopComparator{}).Diff(source, target)
+	want := []itemKey{{domain.Extra, "other.mkv"}, {domain.Missing, "movie.avi"}}
+	if got := keys(r.Items); !slices.Equal(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+}
+
+// cancelingComparator cancels a context on its first comparison, as a
+// deadline passing mid-diff would.
+type cancelingComparator struct {
+	cancel   context.CancelFunc
+	compared int
+}
+
+func (c *cancelingComparator) Compare(_, _ domain.Asset) (bool, string) {
+	c.compared++
+	c.cancel()
+	return true, "Content hash mismatch"
+}
+
+func TestEngineDiffContext(t *testing.T) {
+	var assets []domain.Asset
+	for _, p := range []string{"a.mkv", "b.mkv", "c.mkv", "d.mkv"} {
+		assets = append(assets, file(p, 1))
+	}
+	source, target := tree("src", assets...), tree("tgt", assets...)
+
+	if r := NewEngine(&BasicComparator{}).DiffContext(context.Background(), source, target); r.Truncated {
+		t.Errorf("report without a deadline is truncated: %+v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := NewEngine(&BasicComparator{}).DiffContext(ctx, source, target)
+	if !r.Truncated || len(r.Items) != 0 || r.Summary.TotalPaired != 0 {
+		t.Errorf("report after cancellation = %+v, want an empty truncated report", r)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	c := &cancelingComparator{cancel: cancel}
+	r = NewEngine(c).DiffContext(ctx, source, target)
+	if !r.Truncated || c.compared != 1 || len(r.Items) != 1 || r.Summary.TotalModified != 1 {
+		t.Errorf("report = %+v after %d comparisons, want the one comparison before the cancellation", r, c.compared)
+	}
+}
+
+func TestEngineSeparateExtChanges(t *testing.T) {
+	source := tree("src", file("a.avi", 10), file("b.mkv", 10))
+	target := tree("tgt", file("a.mkv", 10), file("b.mkv", 12))
+
+	tests := []struct {
+		separate bool
+		want     []itemKey
+	}{
+		{false, []itemKey{{domain.Modified, "a.avi"}, {domain.Modified, "b.mkv"}}},
+		{true, []itemKey{{domain.ExtChanged, "a.avi"}, {domain.Modified, "b.mkv"}}},
+	}
+	for _, tt := range tests {
+		engine := NewEngine(&BasicComparator{})
+		engine.SeparateExtChanges = tt.separate
+		r := engine.Diff(source, target)
+		if got := keys(r.Items); !slices.Equal(got, tt.want) {
+			t.Errorf("SeparateExtChanges=%v: items = %v, want %v", tt.separate, got, tt.want)
+		}
+		wantExt, wantModified := 0, 2
+		if tt.separate {
+			wantExt, wantModified = 1, 1
+		}
+		if r.Summary.TotalExtChanged != wantExt || r.Summary.TotalModified != wantModified {
+			t.Errorf("SeparateExtChanges=%v: summary = %+v", tt.separate, r.Summary)
+		}
+	}
+}
+
+func TestEngineDiffOrder(t *testing.T) {
+	source := tree("src",
+		file("z.mkv", 1), file("b/gone.mkv", 1), file("a/gone.mkv", 1), file("m.mkv", 1), file("c.mkv", 1),
+	)
+	target := tree("tgt",
+		file("y.mkv", 1), file("a/new.mkv", 1), file("m.mkv", 2), file("c.mkv", 3), file("b.mkv", 1),
+	)
+	want := []itemKey{
+		{domain.Extra, "a/new.mkv"}, {domain.Extra, "b.mkv"}, {domain.Extra, "y.mkv"},
+		{domain.Missing, "a/gone.mkv"}, {domain.Missing, "b/gone.mkv"}, {domain.Missing, "z.mkv"},
+		{domain.Modified, "c.mkv"}, {domain.Modified, "m.mkv"},
+	}
+	// Map iteration order varies between runs, so diff repeatedly.
+	for range 20 {
+		r := NewEngine(&BasicComparator{}).Diff(source, target)
+		if got := keys(r.Items); !slices.Equal(got, want) {
+			t.Fatalf("items = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEngineDiffSidecars(t *testing.T) {
+	tests := []struct {
+		name   string
+		source []domain.Asset
+		target []domain.Asset
+		want   []itemKey
+	}{
+		{
+			name:   "sidecar added",
+			source: []domain.Asset{file("movie.mkv", 10)},
+			target: []domain.Asset{file("movie.mkv", 10), file("movie.srt", 2)},
+			want:   []itemKey{{domain.Extra, "movie.srt"}},
+		},
+		{
+			name:   "sidecar removed",
+			source: []domain.Asset{file("movie.mkv", 10), file("movie.srt", 2)},
+			target: []domain.Asset{file("movie.mkv", 10)},
+			want:   []itemKey{{domain.Missing, "movie.srt"}},
+		},
+		{
+			name:   "sidecar modified",
+			source: []domain.Asset{file("movie.mkv", 10), file("movie.srt", 2)},
+			target: []domain.Asset{file("movie.srt", 3), file("movie.mkv", 10)},
+			want:   []itemKey{{domain.Modified, "movie.srt"}},
+		},
+		{
+			name:   "extension case differs",
+			source: []domain.Asset{file("movie.MKV", 10), file("movie.srt", 2)},
+			target: []domain.Asset{file("movie.mkv", 10), file("movie.srt", 2)},
+			want:   []itemKey{{domain.Modified, "movie.MKV"}},
+		},
+		{
+			name:   "single file left on each side",
+			source: []domain.Asset{file("movie.avi", 10), file("movie.srt", 2)},
+			target: []domain.Asset{file("movie.mkv", 10), file("movie.srt", 2)},
+			want:   []itemKey{{domain.Modified, "movie.avi"}},
+		},
+		{
+			name:   "several files left on each side",
+			source: []domain.Asset{file("movie.avi", 10), file("movie.sub", 2)},
+			target: []domain.Asset{file("movie.mkv", 10), file("movie.srt", 2)},
+			want: []itemKey{
+				{domain.Extra, "movie.mkv"}, {domain.Extra, "movie.srt"},
+				{domain.Missing, "movie.avi"}, {domain.Missing, "movie.sub"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewEngine(&BasicComparator{}).Diff(tree("src", tt.source...), tree("tgt", tt.target...))
+			if got := keys(r.Items); !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineSeparateEmptyDirs(t *testing.T) {
+	source := tree("src", dir("empty"), dir("show"), file("show/ep1.mkv", 1), file("gone.mkv", 1))
+	target := tree("tgt", dir("new"), dir("show"), file("show/ep1.mkv", 1), dir("full"), file("full/a.mkv", 1))
+
+	r := NewEngine(&BasicComparator{}).Diff(source, target)
+	want := []itemKey{
+		{domain.Extra, "full/a.mkv"}, {domain.Extra, "new"}, {domain.Missing, "empty"}, {domain.Missing, "gone.mkv"},
+	}
+	if got := keys(r.Items); !slices.Equal(got, want) {
+		t.Errorf("without SeparateEmptyDirs, items = %v, want %v", got, want)
+	}
+
+	engine := NewEngine(&BasicComparator{})
+	engine.SeparateEmptyDirs = true
+	r = engine.Diff(source, target)
+	wantItems := []domain.DiffItem{
+		{Type: domain.EmptyDir, Path: "empty", Reason: domain.EmptyDirInSource},
+		{Type: domain.EmptyDir, Path: "new", Reason: domain.EmptyDirInTarget},
+		{Type: domain.Extra, Path: "full/a.mkv", TgtSize: 1},
+		{Type: domain.Missing, Path: "gone.mkv", SrcSize: 1},
+	}
+	if !slices.Equal(r.Items, wantItems) {
+		t.Errorf("items = %+v, want %+v", r.Items, wantItems)
+	}
+	if s := r.Summary; s.TotalEmptyDirs != 2 || s.TotalMissing != 1 || s.TotalExtra != 1 {
+		t.Errorf("summary = %+v", s)
+	}
+}
@@ -0,0 +1,58 @@
+package diff
+
+import (
+	"path"
+	"regexp"
+	"sort"
+
+	"mddiff/pkg/domain"
+)
+
+// DefaultDuplicatePatterns match the suffixes that file managers and download
+// tools commonly append when a file is copied next to an existing one.
+var DefaultDuplicatePatterns = []string{
+	` \(\d+\)$`,
+	`\.copy$`,
+	` - Copy( \(\d+\))?$`,
+}
+
+// dupeKey identifies a file by its stem and extension, so a copy is only
+// matched to a file of the same type.
+type dupeKey struct {
+	id  string
+	ext string
+}
+
+// findDuplicates flags files in tree whose stem is another file's stem in the
+// same directory plus a suffix matching one of patterns, and whose extension
+// is the same, so "Movie (1).srt" isn't taken for a copy of "Movie.mkv".
+func findDuplicates(tree *domain.DirectoryTree, side string, patterns []*regexp.Regexp) []domain.Duplicate {
+	index := make(map[dupeKey]domain.Asset, len(tree.Assets))
+	for _, asset := range tree.Assets {
+		if !asset.IsDir {
+			index[dupeKey{makeIdentity(asset), asset.Ext}] = asset
+		}
+	}
+
+	var dupes []domain.Duplicate
+	for key, asset := range index {
+		dir, stem := path.Split(key.id)
+		for _, pattern := range patterns {
+			base := pattern.ReplaceAllString(stem, "")
+			if base == stem || base == "" {
+				continue
+			}
+			if original, ok := index[dupeKey{path.Join(dir, base), key.ext}]; ok {
+				dupes = append(dupes, domain.Duplicate{
+					Side:     side,
+					Path:     asset.Path,
+					Original: original.Path,
+				})
+				break
+			}
+		}
+	}
+
+	sort.Slice(dupes, func(i, j int) bool { return dupes[i].Path < dupes[j].Path })
+	return dupes
+}
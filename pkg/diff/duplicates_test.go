@@ -0,0 +1,86 @@
+package diff
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func defaultDuplicatePatterns(t *testing.T) []*regexp.Regexp {
+	t.Helper()
+	var res []*regexp.Regexp
+	for _, p := range DefaultDuplicatePatterns {
+		res = append(res, regexp.MustCompile(p))
+	}
+	return res
+}
+
+func TestFindDuplicates(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []domain.Asset
+		want   []domain.Duplicate
+	}{
+		{
+			name:   "numbered copy",
+			assets: []domain.Asset{file("Movie.mkv", 10), file("Movie (1).mkv", 10)},
+			want:   []domain.Duplicate{{Side: "source", Path: "Movie (1).mkv", Original: "Movie.mkv"}},
+		},
+		{
+			name: "copy suffixes",
+			assets: []domain.Asset{
+				file("a.mkv", 1), file("a - Copy.mkv", 1), file("a - Copy (2).mkv", 1), file("a.copy.mkv", 1),
+			},
+			want: []domain.Duplicate{
+				// The first pattern that leaves an existing file wins.
+				{Side: "source", Path: "a - Copy (2).mkv", Original: "a - Copy.mkv"},
+				{Side: "source", Path: "a - Copy.mkv", Original: "a.mkv"},
+				{Side: "source", Path: "a.copy.mkv", Original: "a.mkv"},
+			},
+		},
+		{
+			name:   "no original",
+			assets: []domain.Asset{file("Movie (1).mkv", 10)},
+		},
+		{
+			name:   "original in another directory",
+			assets: []domain.Asset{file("a/Movie.mkv", 10), file("b/Movie (1).mkv", 10)},
+		},
+		{
+			name:   "different extension",
+			assets: []domain.Asset{file("Movie.mkv", 10), file("Movie (1).srt", 10)},
+		},
+		{
+			name:   "directories are ignored",
+			assets: []domain.Asset{dir("Season"), dir("Season (1)")},
+		},
+	}
+	patterns := defaultDuplicatePatterns(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findDuplicates(tree("src", tt.assets...), "source", patterns)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findDuplicates = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineDuplicates(t *testing.T) {
+	source := tree("src", file("a.mkv", 1))
+	target := tree("tgt", file("a.mkv", 1), file("a (1).mkv", 1))
+
+	engine := NewEngine(&BasicComparator{})
+	if r := engine.Diff(source, target); r.Duplicates != nil {
+		t.Errorf("Duplicates = %+v without DuplicatePatterns", r.Duplicates)
+	}
+
+	engine.DuplicatePatterns = defaultDuplicatePatterns(t)
+	r := engine.Diff(source, target)
+	want := []domain.Duplicate{{Side: "target", Path: "a (1).mkv", Original: "a.mkv"}}
+	if !slices.Equal(r.Duplicates, want) {
+		t.Errorf("Duplicates = %+v, want %+v", r.Duplicates, want)
+	}
+}
@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultExtEquivalents are extensions that name the same format.
+const DefaultExtEquivalents = ".jpg=.jpeg,.tif=.tiff,.mpg=.mpeg"
+
+// ExtEquivalence groups extensions that name the same format, such as .jpg
+// and .jpeg. It maps each lowercased extension to the first extension of its
+// group.
+type ExtEquivalence map[string]string
+
+// Add puts exts in one group, merging any groups they already belong to.
+func (e ExtEquivalence) Add(exts ...string) {
+	if len(exts) == 0 {
+		return
+	}
+	group := e.group(normalizeExt(exts[0]))
+	for _, ext := range exts[1:] {
+		old := e.group(normalizeExt(ext))
+		for member, g := range e {
+			if g == old {
+				e[member] = group
+			}
+		}
+		e[old] = group
+	}
+	e[group] = group
+}
+
+// group returns the group of ext, which is ext itself when it has none.
+func (e ExtEquivalence) group(ext string) string {
+	if g, ok := e[ext]; ok {
+		return g
+	}
+	return ext
+}
+
+// Equivalent reports whether a and b are different extensions in the same
+// group. Extensions that only differ in case aren't equivalent, so case-only
+// changes are still reported.
+func (e ExtEquivalence) Equivalent(a, b string) bool {
+	a, b = normalizeExt(a), normalizeExt(b)
+	if a == b {
+		return false
+	}
+	ga, ok := e[a]
+	return ok && ga == e[b]
+}
+
+// ParseExtEquivalence parses a comma-separated list of groups such as
+// ".jpg=.jpeg,.mpeg=.mpg". A group can list more than two extensions, e.g.
+// ".tif=.tiff=.TIF". Extensions are lowercased and given a leading dot if
+// they lack one.
+func ParseExtEquivalence(s string) (ExtEquivalence, error) {
+	e := make(ExtEquivalence)
+	for entry := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		exts := strings.Split(entry, "=")
+		if len(exts) < 2 {
+			return nil, fmt.Errorf("invalid equivalence %q (want .ext=.ext)", entry)
+		}
+		for _, ext := range exts {
+			if strings.TrimSpace(ext) == "" {
+				return nil, fmt.Errorf("invalid equivalence %q (empty extension)", entry)
+			}
+		}
+		e.Add(exts...)
+	}
+	return e, nil
+}
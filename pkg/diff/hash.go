@@ -0,0 +1,68 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/domain"
+)
+
+// HashComparator compares assets by the SHA-256 digest of their content. An
+// asset whose Hash is already set, e.g. from a hash manifest, isn't rehashed.
+type HashComparator struct {
+	// Limiter, when set, caps how many files are open at once while hashing.
+	Limiter *checksum.Limiter
+	// Cache, when set, is consulted before hashing a file and updated with
+	// every digest computed.
+	Cache *checksum.Cache
+}
+
+// Compare reports whether src and tgt have different content.
+func (c *HashComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir {
+		return false, ""
+	}
+
+	srcHash, err := c.assetHash(src)
+	if err != nil {
+		return true, fmt.Sprintf("Unable to hash source: %v", err)
+	}
+	tgtHash, err := c.assetHash(tgt)
+	if err != nil {
+		return true, fmt.Sprintf("Unable to hash target: %v", err)
+	}
+
+	if srcHash != tgtHash {
+		return true, "Content hash mismatch"
+	}
+	return false, ""
+}
+
+// Hash returns the digest of asset's content, using Asset.Hash or the cache
+// when they have it.
+func (c *HashComparator) Hash(asset domain.Asset) (string, error) {
+	return c.assetHash(asset)
+}
+
+func (c *HashComparator) assetHash(asset domain.Asset) (string, error) {
+	if asset.Hash != "" {
+		return asset.Hash, nil
+	}
+	// Assets from a manifest or a remote host have no file on this machine.
+	if asset.AbsPath == "" {
+		return "", errors.New("no local file")
+	}
+	if c.Cache == nil {
+		return c.Limiter.HashFile(asset.AbsPath)
+	}
+	if sum, ok := c.Cache.Lookup(asset.AbsPath, asset.Size, asset.ModTime); ok {
+		return sum, nil
+	}
+	sum, err := c.Limiter.HashFile(asset.AbsPath)
+	if err != nil {
+		return "", err
+	}
+	c.Cache.Store(asset.AbsPath, asset.Size, asset.ModTime, sum)
+	return sum, nil
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/domain"
+)
+
+func TestHashComparator(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name, content string) domain.Asset {
+		path := filepath.Join(tmp, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		a := file(name, int64(len(content)))
+		a.AbsPath = path
+		return a
+	}
+	a, b, c := write("a.txt", "hello"), write("b.txt", "hello"), write("c.txt", "world")
+	missing := file("missing.txt", 5)
+	missing.AbsPath = filepath.Join(tmp, "missing.txt")
+	// An asset with a known hash, e.g. from a hash manifest, isn't read.
+	known := file("known.txt", 5)
+	known.Hash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	tests := []struct {
+		name     string
+		src, tgt domain.Asset
+		modified bool
+		reason   string
+	}{
+		{"same content", a, b, false, ""},
+		{"different content", a, c, true, "Content hash mismatch"},
+		{"known hash", known, a, false, ""},
+		{"unreadable source", missing, a, true, ""},
+		{"no local file", a, file("b.txt", 5), true, "Unable to hash target: no local file"},
+		{"directories", dir("d"), dir("d"), false, ""},
+	}
+	hasher := &HashComparator{}
+	for _, tt := range tests {
+		modified, reason := hasher.Compare(tt.src, tt.tgt)
+		if modified != tt.modified || (tt.reason != "" && reason != tt.reason) {
+			t.Errorf("%s: Compare = %v, %q, want %v, %q", tt.name, modified, reason, tt.modified, tt.reason)
+		}
+	}
+}
+
+func TestHashComparatorCache(t *testing.T) {
+	tmp := t.TempDir()
+	assets := make([]domain.Asset, 2)
+	for i, name := range []string{"src.mkv", "tgt.mkv"} {
+		path := filepath.Join(tmp, name)
+		if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assets[i] = file(name, info.Size())
+		assets[i].AbsPath = path
+		assets[i].ModTime = info.ModTime()
+	}
+	src, tgt := assets[0], assets[1]
+
+	cachePath := filepath.Join(tmp, "cache.json")
+	cache, err := checksum.LoadCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modified, reason := (&HashComparator{Cache: cache}).Compare(src, tgt); modified {
+		t.Fatalf("first run: Compare = %v, %q", modified, reason)
+	}
+	if err := cache.Save(cachePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run reads the digests from the cache, so it doesn't need the
+	// files at all.
+	for _, a := range assets {
+		if err := os.Remove(a.AbsPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache, err = checksum.LoadCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher := &HashComparator{Cache: cache}
+	if modified, reason := hasher.Compare(src, tgt); modified {
+		t.Errorf("second run: Compare = %v, %q, want the cached digests to match", modified, reason)
+	}
+
+	// A changed modification time invalidates the entry, so the missing file
+	// is read again.
+	src.ModTime = src.ModTime.Add(time.Second)
+	if modified, reason := hasher.Compare(src, tgt); !modified || !strings.HasPrefix(reason, "Unable to hash source") {
+		t.Errorf("after touching: Compare = %v, %q, want a hashing error", modified, reason)
+	}
+}
@@ -0,0 +1,142 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestEngineIgnoreCase(t *testing.T) {
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		source     []domain.Asset
+		target     []domain.Asset
+		want       []domain.DiffItem
+	}{
+		{
+			name:   "case-sensitive by default",
+			source: []domain.Asset{file("Movie.mkv", 10)},
+			target: []domain.Asset{file("movie.mkv", 10)},
+			want: []domain.DiffItem{
+				{Type: domain.Extra, Path: "movie.mkv", TgtSize: 10},
+				{Type: domain.Missing, Path: "Movie.mkv", SrcSize: 10},
+			},
+		},
+		{
+			name:       "name case differs",
+			ignoreCase: true,
+			source:     []domain.Asset{file("Movie.mkv", 10)},
+			target:     []domain.Asset{file("movie.mkv", 10)},
+			want: []domain.DiffItem{{
+				Type: domain.Modified, Path: "Movie.mkv", Reason: CaseDiffersReason, SrcSize: 10, TgtSize: 10,
+			}},
+		},
+		{
+			name:       "comparator's reason wins",
+			ignoreCase: true,
+			source:     []domain.Asset{file("Movie.mkv", 10)},
+			target:     []domain.Asset{file("MOVIE.mkv", 12)},
+			want: []domain.DiffItem{{
+				Type: domain.Modified, Path: "Movie.mkv", Reason: "Size changed: +2 bytes",
+				SrcSize: 10, TgtSize: 12, SizeDelta: 2,
+			}},
+		},
+		{
+			name:       "directory case differs",
+			ignoreCase: true,
+			source:     []domain.Asset{dir("Show"), file("Show/ep1.mkv", 10)},
+			target:     []domain.Asset{dir("show"), file("show/ep1.mkv", 10)},
+			want:       []domain.DiffItem{{Type: domain.Modified, Path: "Show", Reason: CaseDiffersReason}},
+		},
+		{
+			name:       "exact match preferred",
+			ignoreCase: true,
+			source:     []domain.Asset{file("a.mkv", 10), file("A.mkv", 10)},
+			target:     []domain.Asset{file("a.mkv", 10)},
+			want:       []domain.DiffItem{{Type: domain.Missing, Path: "A.mkv", SrcSize: 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&BasicComparator{})
+			engine.IgnoreCase = tt.ignoreCase
+			r := engine.Diff(tree("src", tt.source...), tree("tgt", tt.target...))
+			if !slices.Equal(r.Items, tt.want) {
+				t.Errorf("items = %+v, want %+v", r.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineNormalizeUnicode(t *testing.T) {
+	const (
+		nfc = "Caf\u00e9"  // é as one code point
+		nfd = "Cafe\u0301" // e and a combining acute accent
+	)
+	tests := []struct {
+		name       string
+		normalize  bool
+		ignoreCase bool
+		source     []domain.Asset
+		target     []domain.Asset
+		want       []itemKey
+	}{
+		{
+			name:   "forms differ by default",
+			source: []domain.Asset{file(nfd+".mkv", 10)},
+			target: []domain.Asset{file(nfc+".mkv", 10)},
+			want:   []itemKey{{domain.Extra, nfc + ".mkv"}, {domain.Missing, nfd + ".mkv"}},
+		},
+		{
+			name:      "normalized file",
+			normalize: true,
+			source:    []domain.Asset{file(nfd+".mkv", 10)},
+			target:    []domain.Asset{file(nfc+".mkv", 10)},
+		},
+		{
+			name:      "normalized directory",
+			normalize: true,
+			source:    []domain.Asset{dir(nfd), file(nfd+"/a.mkv", 10)},
+			target:    []domain.Asset{dir(nfc), file(nfc+"/a.mkv", 12)},
+			want:      []itemKey{{domain.Modified, nfd + "/a.mkv"}},
+		},
+		{
+			name:       "only real case differences with ignore case",
+			normalize:  true,
+			ignoreCase: true,
+			source:     []domain.Asset{file(nfd+".mkv", 10), file("CAFÉ.srt", 1)},
+			target:     []domain.Asset{file(nfc+".mkv", 10), file("café.srt", 1)},
+			want:       []itemKey{{domain.Modified, "CAFÉ.srt"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&BasicComparator{})
+			engine.NormalizeUnicode = tt.normalize
+			engine.IgnoreCase = tt.ignoreCase
+			r := engine.Diff(tree("src", tt.source...), tree("tgt", tt.target...))
+			if got := keys(r.Items); !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeIdentity(t *testing.T) {
+	tests := []struct {
+		asset domain.Asset
+		want  string
+	}{
+		{file("movie.mkv", 1), "movie"},
+		{file("show/s01/ep1.mkv", 1), "show/s01/ep1"},
+		{file("show/archive.tar.gz", 1), "show/archive.tar"},
+		{dir("show/s01"), "show/s01/"},
+	}
+	for _, tt := range tests {
+		if got := makeIdentity(tt.asset); got != tt.want {
+			t.Errorf("makeIdentity(%q) = %q, want %q", tt.asset.Path, got, tt.want)
+		}
+	}
+}
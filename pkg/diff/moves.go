@@ -0,0 +1,88 @@
+package diff
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+type moveKey struct {
+	name string
+	size int64
+}
+
+// collapseMoves pairs MISSING and EXTRA files that have the same name,
+// extension and size but sit in different directories, replacing each pair
+// with a single MOVED item. Names are compared like identities, so
+// IgnoreCase and NormalizeUnicode apply. When several files could pair, they
+// are paired in path order so the result is stable across runs. With
+// RenameVerifier set, a pair must also pass it.
+func (e *Engine) collapseMoves(report *domain.DiffReport, source, target *domain.DirectoryTree) {
+	key := func(asset domain.Asset) moveKey {
+		ext := asset.Ext
+		if e.IgnoreCase {
+			ext = strings.ToLower(ext)
+		}
+		return moveKey{path.Base(e.identity(asset)) + ext, asset.Size}
+	}
+
+	extras := make(map[moveKey][]int)
+	var missing []int
+	for i, item := range report.Items {
+		switch item.Type {
+		case domain.Extra:
+			if asset := target.Assets[item.Path]; !asset.IsDir {
+				extras[key(asset)] = append(extras[key(asset)], i)
+			}
+		case domain.Missing:
+			if asset := source.Assets[item.Path]; !asset.IsDir {
+				missing = append(missing, i)
+			}
+		}
+	}
+	byPath := func(indices []int) {
+		sort.Slice(indices, func(a, b int) bool {
+			return report.Items[indices[a]].Path < report.Items[indices[b]].Path
+		})
+	}
+	byPath(missing)
+	for _, indices := range extras {
+		byPath(indices)
+	}
+
+	drop := make(map[int]bool)
+	for _, mi := range missing {
+		src := source.Assets[report.Items[mi].Path]
+		k := key(src)
+		candidates := extras[k]
+		at := slices.IndexFunc(candidates, func(ei int) bool {
+			if e.RenameVerifier == nil {
+				return true
+			}
+			isModified, _ := e.RenameVerifier.Compare(src, target.Assets[report.Items[ei].Path])
+			return !isModified
+		})
+		if at < 0 {
+			continue
+		}
+		ei := candidates[at]
+		extras[k] = slices.Delete(candidates, at, at+1)
+
+		report.Items[mi] = domain.DiffItem{
+			Type:    domain.Moved,
+			Path:    src.Path,
+			NewPath: report.Items[ei].Path,
+			SrcSize: src.Size,
+			TgtSize: src.Size,
+		}
+		drop[ei] = true
+
+		report.Summary.TotalMissing--
+		report.Summary.TotalExtra--
+		report.Summary.TotalMoved++
+	}
+	report.Items = dropItems(report.Items, drop)
+}
@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestDetectMoves(t *testing.T) {
+	tests := []struct {
+		name   string
+		source []domain.Asset
+		target []domain.Asset
+		want   []domain.DiffItem
+	}{
+		{
+			name:   "between subfolders",
+			source: []domain.Asset{dir("a"), file("a/Movie.mkv", 10)},
+			target: []domain.Asset{dir("b"), file("b/Movie.mkv", 10)},
+			want: []domain.DiffItem{
+				{Type: domain.Moved, Path: "a/Movie.mkv", NewPath: "b/Movie.mkv", SrcSize: 10, TgtSize: 10},
+			},
+		},
+		{
+			name:   "from the root",
+			source: []domain.Asset{file("Movie.mkv", 10)},
+			target: []domain.Asset{dir("Movies"), file("Movies/Movie.mkv", 10)},
+			want: []domain.DiffItem{
+				{Type: domain.Moved, Path: "Movie.mkv", NewPath: "Movies/Movie.mkv", SrcSize: 10, TgtSize: 10},
+			},
+		},
+		{
+			name:   "different size",
+			source: []domain.Asset{file("a/Movie.mkv", 10)},
+			target: []domain.Asset{file("b/Movie.mkv", 11)},
+			want: []domain.DiffItem{
+				{Type: domain.Extra, Path: "b/Movie.mkv", TgtSize: 11},
+				{Type: domain.Missing, Path: "a/Movie.mkv", SrcSize: 10},
+			},
+		},
+		{
+			name:   "different extension",
+			source: []domain.Asset{file("a/Movie.mkv", 10)},
+			target: []domain.Asset{file("b/Movie.mp4", 10)},
+			want: []domain.DiffItem{
+				{Type: domain.Extra, Path: "b/Movie.mp4", TgtSize: 10},
+				{Type: domain.Missing, Path: "a/Movie.mkv", SrcSize: 10},
+			},
+		},
+		{
+			name:   "candidates pair in path order",
+			source: []domain.Asset{file("b/x.mkv", 1), file("a/x.mkv", 1)},
+			target: []domain.Asset{file("d/x.mkv", 1), file("c/x.mkv", 1)},
+			want: []domain.DiffItem{
+				{Type: domain.Moved, Path: "a/x.mkv", NewPath: "c/x.mkv", SrcSize: 1, TgtSize: 1},
+				{Type: domain.Moved, Path: "b/x.mkv", NewPath: "d/x.mkv", SrcSize: 1, TgtSize: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&BasicComparator{})
+			engine.DetectMoves = true
+			r := engine.Diff(tree("src", tt.source...), tree("tgt", tt.target...))
+			if !slices.Equal(r.Items, tt.want) {
+				t.Errorf("items = %+v, want %+v", r.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectMovesBeforeRenames(t *testing.T) {
+	source := tree("src", file("a/Movie.mkv", 10))
+	target := tree("tgt", file("a/Other.mkv", 10), file("b/Movie.mkv", 10))
+
+	engine := NewEngine(&BasicComparator{})
+	engine.DetectMoves = true
+	engine.DetectRenames = true
+	r := engine.Diff(source, target)
+	want := []itemKey{{domain.Extra, "a/Other.mkv"}, {domain.Moved, "a/Movie.mkv"}}
+	if got := keys(r.Items); !slices.Equal(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+	if s := r.Summary; s.TotalMoved != 1 || s.TotalRenamed != 0 || s.TotalMissing != 0 || s.TotalExtra != 1 {
+		t.Errorf("summary = %+v", s)
+	}
+}
+
+func TestDetectMovesVerifier(t *testing.T) {
+	source := tree("src", file("a/x.mkv", 1), file("b/x.mkv", 1))
+	target := tree("tgt", file("c/x.mkv", 1))
+
+	// Only b/x.mkv holds the same content as c/x.mkv.
+	engine := NewEngine(&BasicComparator{})
+	engine.DetectMoves = true
+	engine.RenameVerifier = sameContent{"a/x.mkv": "y", "b/x.mkv": "x", "c/x.mkv": "x"}
+	r := engine.Diff(source, target)
+	want := []itemKey{{domain.Missing, "a/x.mkv"}, {domain.Moved, "b/x.mkv"}}
+	if got := keys(r.Items); !slices.Equal(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+}
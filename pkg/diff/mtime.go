@@ -0,0 +1,37 @@
+package diff
+
+import (
+	"fmt"
+	"time"
+
+	"mddiff/pkg/domain"
+)
+
+// DefaultMTimeTolerance absorbs the 2-second timestamp resolution of FAT and
+// exFAT filesystems, common on external drives.
+const DefaultMTimeTolerance = 2 * time.Second
+
+// MTimeComparator compares assets by modification time, catching re-encodes
+// that happen to keep the same size.
+type MTimeComparator struct {
+	// Tolerance is how far apart the two modification times may be before the
+	// assets are considered different.
+	Tolerance time.Duration
+}
+
+// Compare reports whether the modification times of src and tgt differ by more
+// than c.Tolerance.
+func (c *MTimeComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir {
+		return false, ""
+	}
+	delta := src.ModTime.Sub(tgt.ModTime)
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta <= c.Tolerance {
+		return false, ""
+	}
+	return true, fmt.Sprintf("Modification time differs: %s vs %s",
+		src.ModTime.Format(time.RFC3339), tgt.ModTime.Format(time.RFC3339))
+}
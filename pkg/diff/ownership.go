@@ -0,0 +1,39 @@
+package diff
+
+import (
+	"fmt"
+
+	"mddiff/pkg/domain"
+)
+
+// OwnershipComparator reports a file as modified when its owner or group
+// differs between the trees. Files that the wrapped comparator already
+// considers modified keep its reason, and files without recorded ownership
+// are left to it entirely.
+type OwnershipComparator struct {
+	inner domain.AssetComparator
+}
+
+// NewOwnershipComparator returns an OwnershipComparator that first consults
+// inner.
+func NewOwnershipComparator(inner domain.AssetComparator) *OwnershipComparator {
+	return &OwnershipComparator{inner: inner}
+}
+
+// Compare implements domain.AssetComparator.
+func (c *OwnershipComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if isModified, reason := c.inner.Compare(src, tgt); isModified {
+		return true, reason
+	}
+	if src.IsDir || tgt.IsDir || src.Owner == nil || tgt.Owner == nil {
+		return false, ""
+	}
+
+	if src.Owner.UID != tgt.Owner.UID {
+		return true, fmt.Sprintf("Owner differs: %d -> %d", src.Owner.UID, tgt.Owner.UID)
+	}
+	if src.Owner.GID != tgt.Owner.GID {
+		return true, fmt.Sprintf("Group differs: %d -> %d", src.Owner.GID, tgt.Owner.GID)
+	}
+	return false, ""
+}
@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestOwnershipComparator(t *testing.T) {
+	owned := func(a domain.Asset, uid, gid uint32) domain.Asset {
+		a.Owner = &domain.Owner{UID: uid, GID: gid}
+		return a
+	}
+	tests := []struct {
+		name     string
+		src, tgt domain.Asset
+		modified bool
+		reason   string
+	}{
+		{"same owner", owned(file("a", 1), 1000, 100), owned(file("a", 1), 1000, 100), false, ""},
+		{"owner", owned(file("a", 1), 1000, 100), owned(file("a", 1), 0, 100), true, "Owner differs: 1000 -> 0"},
+		{"group", owned(file("a", 1), 1000, 100), owned(file("a", 1), 1000, 0), true, "Group differs: 100 -> 0"},
+		{"both", owned(file("a", 1), 1000, 100), owned(file("a", 1), 0, 0), true, "Owner differs: 1000 -> 0"},
+		{"unknown owner", file("a", 1), owned(file("a", 1), 0, 0), false, ""},
+		{"directories", owned(dir("d"), 1000, 100), owned(dir("d"), 0, 0), false, ""},
+		{
+			"inner decides first", owned(file("a", 1), 1000, 100), owned(file("a", 2), 0, 0),
+			true, "Size changed: +1 bytes",
+		},
+	}
+	c := NewOwnershipComparator(&BasicComparator{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified, reason := c.Compare(tt.src, tt.tgt)
+			if modified != tt.modified || reason != tt.reason {
+				t.Errorf("Compare = %v, %q, want %v, %q", modified, reason, tt.modified, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // Register the GIF decoder.
+	_ "image/jpeg" // Register the JPEG decoder.
+	_ "image/png"  // Register the PNG decoder.
+	"math/bits"
+	"os"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+// DefaultMaxImageDistance is the largest Hamming distance between two 64-bit
+// difference hashes at which images are still considered the same picture.
+const DefaultMaxImageDistance = 10
+
+// imageExts are the extensions PerceptualComparator will try to decode.
+var imageExts = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+}
+
+// PerceptualComparator treats two images as unchanged when they look alike,
+// even if they were rescaled or recompressed, by comparing difference hashes
+// (dHash) of their pixels. Everything else, including images that fail to
+// decode, is left to the wrapped comparator.
+type PerceptualComparator struct {
+	inner domain.AssetComparator
+
+	// MaxDistance is the number of differing hash bits tolerated.
+	MaxDistance int
+}
+
+// NewPerceptualComparator returns a PerceptualComparator that delegates
+// non-images to inner and uses DefaultMaxImageDistance.
+func NewPerceptualComparator(inner domain.AssetComparator) *PerceptualComparator {
+	return &PerceptualComparator{inner: inner, MaxDistance: DefaultMaxImageDistance}
+}
+
+// Compare implements domain.AssetComparator.
+func (c *PerceptualComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir || !imageExts[strings.ToLower(src.Ext)] || !imageExts[strings.ToLower(tgt.Ext)] {
+		return c.inner.Compare(src, tgt)
+	}
+
+	srcHash, err := imageHash(src.AbsPath)
+	if err != nil {
+		return c.inner.Compare(src, tgt)
+	}
+	tgtHash, err := imageHash(tgt.AbsPath)
+	if err != nil {
+		return c.inner.Compare(src, tgt)
+	}
+
+	if d := bits.OnesCount64(srcHash ^ tgtHash); d > c.MaxDistance {
+		return true, fmt.Sprintf("Image content differs: distance %d", d)
+	}
+	return false, ""
+}
+
+func imageHash(path string) (uint64, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from a directory walk
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+	return dHash(img), nil
+}
+
+// dHash shrinks img to a 9x8 grayscale grid and sets one bit per pair of
+// horizontally adjacent cells, depending on which is brighter. Scaling and
+// recompression barely move these gradients, so similar images get hashes a
+// few bits apart.
+func dHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	var grid [h][w]uint64
+
+	b := img.Bounds()
+	counts := [h][w]uint64{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		gy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gx := (x - b.Min.X) * w / b.Dx()
+			r, g, bl, _ := img.At(x, y).RGBA()
+			// ITU-R 601 luma, in the 16-bit range RGBA returns.
+			grid[gy][gx] += (299*uint64(r) + 587*uint64(g) + 114*uint64(bl)) / 1000
+			counts[gy][gx]++
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if mean(grid[y][x], counts[y][x]) > mean(grid[y][x+1], counts[y][x+1]) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+func mean(sum, n uint64) uint64 {
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// gradient returns a w x h image that brightens from left to right, or from
+// right to left when reversed.
+func gradient(w, h int, reversed bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			v := x * 255 / (w - 1)
+			if reversed {
+				v = 255 - v
+			}
+			// A little vertical variation keeps the rows from being identical.
+			img.SetGray(x, y, color.Gray{Y: uint8(v*3/4 + y*63/(h-1))}) // #nosec G115 -- at most 254
+		}
+	}
+	return img
+}
+
+func TestPerceptualComparator(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name string, encode func(io.Writer) error) domain.Asset {
+		path := filepath.Join(tmp, name)
+		f, err := os.Create(path) // #nosec G304 -- a test file in a temporary directory
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := encode(f); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := file(name, info.Size())
+		a.AbsPath = path
+		return a
+	}
+	pngOf := func(img image.Image) func(io.Writer) error {
+		return func(w io.Writer) error { return png.Encode(w, img) }
+	}
+
+	original := write("poster.png", pngOf(gradient(90, 80, false)))
+	recompressed := write("poster.jpg", func(w io.Writer) error {
+		return jpeg.Encode(w, gradient(90, 80, false), &jpeg.Options{Quality: 20})
+	})
+	rescaled := write("small.png", pngOf(gradient(45, 40, false)))
+	different := write("other.png", pngOf(gradient(90, 80, true)))
+	broken := write("broken.png", func(w io.Writer) error {
+		_, err := io.WriteString(w, "not an image")
+		return err
+	})
+
+	tests := []struct {
+		name     string
+		src, tgt domain.Asset
+		modified bool
+		reason   string
+	}{
+		{"recompressed", original, recompressed, false, ""},
+		{"rescaled", original, rescaled, false, ""},
+		{"different image", original, different, true, "Image content differs: distance "},
+		// The wrapped comparator sees these, and reports every pair modified.
+		{"undecodable", original, broken, true, "inner"},
+		{"not an image", file("a.mkv", 1), file("a.mkv", 1), true, "inner"},
+	}
+	inner := modifiedComparator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified, reason := NewPerceptualComparator(inner).Compare(tt.src, tt.tgt)
+			if modified != tt.modified || !strings.HasPrefix(reason, tt.reason) {
+				t.Errorf("Compare = %v, %q, want %v, %q", modified, reason, tt.modified, tt.reason)
+			}
+		})
+	}
+}
+
+// modifiedComparator reports every pair as modified, so tests can tell when a
+// decorator fell back to it.
+type modifiedComparator struct{}
+
+func (modifiedComparator) Compare(_, _ domain.Asset) (bool, string) {
+	return true, "inner"
+}
@@ -0,0 +1,49 @@
+package diff
+
+import (
+	"fmt"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/domain"
+)
+
+// DefaultQuickHashBytes is how much of each end of a file QuickHashComparator
+// reads by default.
+const DefaultQuickHashBytes = 4 * 1000 * 1000
+
+// QuickHashComparator compares assets by a fingerprint of their size and the
+// first and last Bytes of their content. It catches truncation and most
+// corruption of large files at a fraction of the cost of HashComparator, but
+// it is probabilistic: files that differ only in the middle look the same.
+type QuickHashComparator struct {
+	// Bytes is how much to read from each end of a file. Zero uses
+	// DefaultQuickHashBytes.
+	Bytes int64
+	// Limiter, when set, caps how many files are open at once while hashing.
+	Limiter *checksum.Limiter
+}
+
+// Compare reports whether the fingerprints of src and tgt differ.
+func (c *QuickHashComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir {
+		return false, ""
+	}
+	n := c.Bytes
+	if n <= 0 {
+		n = DefaultQuickHashBytes
+	}
+
+	srcHash, err := c.Limiter.QuickHashFile(src.AbsPath, n)
+	if err != nil {
+		return true, fmt.Sprintf("Unable to quick-hash source: %v", err)
+	}
+	tgtHash, err := c.Limiter.QuickHashFile(tgt.AbsPath, n)
+	if err != nil {
+		return true, fmt.Sprintf("Unable to quick-hash target: %v", err)
+	}
+
+	if srcHash != tgtHash {
+		return true, "Quick hash mismatch (start, end or size differ)"
+	}
+	return false, ""
+}
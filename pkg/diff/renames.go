@@ -0,0 +1,95 @@
+package diff
+
+import (
+	"slices"
+	"sort"
+
+	"mddiff/pkg/domain"
+)
+
+type renameKey struct {
+	ext  string
+	size int64
+}
+
+// collapseRenames pairs MISSING and EXTRA files that share an extension and a
+// non-zero size, replacing each pair with a single RENAMED item. Candidates
+// are paired in path order so the result is stable across runs. With
+// RenameVerifier set, a pair must also pass it.
+func (e *Engine) collapseRenames(report *domain.DiffReport, source, target *domain.DirectoryTree) {
+	extras := make(map[renameKey][]int)
+	var missing []int
+	for i, item := range report.Items {
+		switch item.Type {
+		case domain.Extra:
+			if asset := target.Assets[item.Path]; !asset.IsDir && asset.Size > 0 {
+				key := renameKey{asset.Ext, asset.Size}
+				extras[key] = append(extras[key], i)
+			}
+		case domain.Missing:
+			if asset := source.Assets[item.Path]; !asset.IsDir && asset.Size > 0 {
+				missing = append(missing, i)
+			}
+		}
+	}
+	byPath := func(indices []int) {
+		sort.Slice(indices, func(a, b int) bool {
+			return report.Items[indices[a]].Path < report.Items[indices[b]].Path
+		})
+	}
+	byPath(missing)
+	for _, indices := range extras {
+		byPath(indices)
+	}
+
+	drop := make(map[int]bool)
+	for _, mi := range missing {
+		src := source.Assets[report.Items[mi].Path]
+		key := renameKey{src.Ext, src.Size}
+		candidates := extras[key]
+		at := slices.IndexFunc(candidates, func(ei int) bool {
+			if e.RenameVerifier == nil {
+				return true
+			}
+			isModified, _ := e.RenameVerifier.Compare(src, target.Assets[report.Items[ei].Path])
+			return !isModified
+		})
+		if at < 0 {
+			continue
+		}
+		ei := candidates[at]
+		extras[key] = slices.Delete(candidates, at, at+1)
+
+		report.Items[mi] = domain.DiffItem{
+			Type:    domain.Renamed,
+			Path:    src.Path,
+			NewPath: report.Items[ei].Path,
+			SrcSize: src.Size,
+			TgtSize: src.Size,
+		}
+		drop[ei] = true
+
+		report.Summary.TotalMissing--
+		report.Summary.TotalExtra--
+		report.Summary.TotalRenamed++
+		if e.CountRenamesAsModified {
+			report.Summary.TotalModified++
+		}
+	}
+
+	report.Items = dropItems(report.Items, drop)
+}
+
+// dropItems removes the items whose indices are in drop, in place.
+func dropItems(items []domain.DiffItem, drop map[int]bool) []domain.DiffItem {
+	if len(drop) == 0 {
+		return items
+	}
+	kept := items[:0]
+	for i, item := range items {
+		if !drop[i] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
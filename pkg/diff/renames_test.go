@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestDetectRenames(t *testing.T) {
+	tests := []struct {
+		name   string
+		source []domain.Asset
+		target []domain.Asset
+		want   []domain.DiffItem
+	}{
+		{
+			name:   "same extension and size",
+			source: []domain.Asset{file("old.mkv", 10)},
+			target: []domain.Asset{file("new.mkv", 10)},
+			want:   []domain.DiffItem{{Type: domain.Renamed, Path: "old.mkv", NewPath: "new.mkv", SrcSize: 10, TgtSize: 10}},
+		},
+		{
+			name:   "different size",
+			source: []domain.Asset{file("old.mkv", 10)},
+			target: []domain.Asset{file("new.mkv", 11)},
+			want: []domain.DiffItem{
+				{Type: domain.Extra, Path: "new.mkv", TgtSize: 11},
+				{Type: domain.Missing, Path: "old.mkv", SrcSize: 10},
+			},
+		},
+		{
+			name:   "different extension",
+			source: []domain.Asset{file("old.mkv", 10)},
+			target: []domain.Asset{file("new.mp4", 10)},
+			want: []domain.DiffItem{
+				{Type: domain.Extra, Path: "new.mp4", TgtSize: 10},
+				{Type: domain.Missing, Path: "old.mkv", SrcSize: 10},
+			},
+		},
+		{
+			name:   "empty files aren't paired",
+			source: []domain.Asset{file("old.mkv", 0)},
+			target: []domain.Asset{file("new.mkv", 0)},
+			want: []domain.DiffItem{
+				{Type: domain.Extra, Path: "new.mkv"},
+				{Type: domain.Missing, Path: "old.mkv"},
+			},
+		},
+		{
+			name:   "candidates pair in path order",
+			source: []domain.Asset{file("b.mkv", 10), file("a.mkv", 10)},
+			target: []domain.Asset{file("d.mkv", 10), file("c.mkv", 10)},
+			want: []domain.DiffItem{
+				{Type: domain.Renamed, Path: "a.mkv", NewPath: "c.mkv", SrcSize: 10, TgtSize: 10},
+				{Type: domain.Renamed, Path: "b.mkv", NewPath: "d.mkv", SrcSize: 10, TgtSize: 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&BasicComparator{})
+			engine.DetectRenames = true
+			r := engine.Diff(tree("src", tt.source...), tree("tgt", tt.target...))
+			if !slices.Equal(r.Items, tt.want) {
+				t.Errorf("items = %+v, want %+v", r.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountRenamesAsModified(t *testing.T) {
+	source := tree("src", file("old.mkv", 10))
+	target := tree("tgt", file("new.mkv", 10))
+	tests := []struct {
+		asModified   bool
+		wantModified int
+	}{
+		{false, 0},
+		{true, 1},
+	}
+	for _, tt := range tests {
+		engine := NewEngine(&BasicComparator{})
+		engine.DetectRenames = true
+		engine.CountRenamesAsModified = tt.asModified
+		s := engine.Diff(source, target).Summary
+		if s.TotalRenamed != 1 || s.TotalModified != tt.wantModified || s.TotalMissing != 0 || s.TotalExtra != 0 {
+			t.Errorf("CountRenamesAsModified=%v: summary = %+v", tt.asModified, s)
+		}
+	}
+}
+
+func TestRenameVerifier(t *testing.T) {
+	source := tree("src", file("a.mkv", 10), file("b.mkv", 10))
+	target := tree("tgt", file("c.mkv", 10), file("d.mkv", 10))
+
+	// Only b.mkv and c.mkv hold the same content.
+	engine := NewEngine(&BasicComparator{})
+	engine.DetectRenames = true
+	engine.RenameVerifier = sameContent{"b.mkv": "x", "c.mkv": "x", "a.mkv": "y", "d.mkv": "z"}
+	r := engine.Diff(source, target)
+
+	want := []itemKey{{domain.Extra, "d.mkv"}, {domain.Missing, "a.mkv"}, {domain.Renamed, "b.mkv"}}
+	if got := keys(r.Items); !slices.Equal(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+}
+
+// sameContent is a comparator that considers two assets modified unless
+// their paths map to the same content.
+type sameContent map[string]string
+
+func (c sameContent) Compare(src, tgt domain.Asset) (bool, string) {
+	if c[src.Path] != c[tgt.Path] {
+		return true, "Content hash mismatch"
+	}
+	return false, ""
+}
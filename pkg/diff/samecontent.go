@@ -0,0 +1,63 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"mddiff/pkg/domain"
+)
+
+// GroupDuplicates groups the files in tree that have the same size, largest
+// first, leaving out files that have no twin. When hasher is set, files of the
+// same size are also hashed and only those with the same content are grouped.
+// Groups and the paths within them are sorted, so the result doesn't depend on
+// map order.
+func GroupDuplicates(tree *domain.DirectoryTree, hasher *HashComparator) ([]domain.DuplicateGroup, error) {
+	bySize := make(map[int64][]domain.Asset)
+	for _, asset := range tree.Assets {
+		if !asset.IsDir && !asset.IsSymlink {
+			bySize[asset.Size] = append(bySize[asset.Size], asset)
+		}
+	}
+
+	var groups []domain.DuplicateGroup
+	for size, assets := range bySize {
+		if len(assets) < 2 {
+			continue
+		}
+		if hasher == nil {
+			groups = append(groups, newDuplicateGroup(size, "", assets))
+			continue
+		}
+		byHash := make(map[string][]domain.Asset)
+		for _, asset := range assets {
+			sum, err := hasher.Hash(asset)
+			if err != nil {
+				return nil, fmt.Errorf("hashing %s: %w", asset.Path, err)
+			}
+			byHash[sum] = append(byHash[sum], asset)
+		}
+		for sum, same := range byHash {
+			if len(same) > 1 {
+				groups = append(groups, newDuplicateGroup(size, sum, same))
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Paths[0] < groups[j].Paths[0]
+	})
+	return groups, nil
+}
+
+func newDuplicateGroup(size int64, sum string, assets []domain.Asset) domain.DuplicateGroup {
+	g := domain.DuplicateGroup{Size: size, Hash: sum, Paths: make([]string, len(assets))}
+	for i, asset := range assets {
+		g.Paths[i] = asset.Path
+	}
+	sort.Strings(g.Paths)
+	return g
+}
@@ -0,0 +1,22 @@
+package diff
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// sampleIndices picks roughly percent% of n indices using an RNG seeded with
+// seed. The same inputs always yield the same indices, returned in ascending
+// order. At least one index is chosen whenever n and percent are positive.
+func sampleIndices(n int, percent float64, seed int64) []int {
+	k := min(int(math.Ceil(float64(n)*percent/100)), n)
+	if k <= 0 {
+		return nil
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(seed), 0)) // #nosec G404 -- sampling, not security
+	indices := rng.Perm(n)[:k]
+	sort.Ints(indices)
+	return indices
+}
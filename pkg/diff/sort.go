@@ -0,0 +1,50 @@
+package diff
+
+import (
+	"sort"
+
+	"mddiff/pkg/domain"
+)
+
+// SortKey orders report items for SortItems.
+type SortKey string
+
+// Supported sort keys.
+const (
+	// SortByType groups items by type, then orders them by path. It is the
+	// order the engine produces.
+	SortByType SortKey = "type"
+	// SortByPath orders items by path, then type.
+	SortByPath SortKey = "path"
+	// SortBySize puts the largest items first, by the larger of SrcSize and
+	// TgtSize, then orders them by type and path.
+	SortBySize SortKey = "size"
+)
+
+// SortItems sorts items by key, reversing the order when reverse is set. Ties
+// are broken so that the result never depends on the input order. An unknown
+// key sorts like SortByType.
+func SortItems(items []domain.DiffItem, key SortKey, reverse bool) {
+	less := func(a, b domain.DiffItem) bool {
+		switch key {
+		case SortByPath:
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+		case SortBySize:
+			if sa, sb := max(a.SrcSize, a.TgtSize), max(b.SrcSize, b.TgtSize); sa != sb {
+				return sa > sb
+			}
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Path < b.Path
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if reverse {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+}
@@ -0,0 +1,57 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestSortItems(t *testing.T) {
+	items := []domain.DiffItem{
+		{Type: domain.Modified, Path: "b.mkv", SrcSize: 10, TgtSize: 500},
+		{Type: domain.Extra, Path: "c.mkv", TgtSize: 100},
+		{Type: domain.Missing, Path: "a.mkv", SrcSize: 300},
+		{Type: domain.Missing, Path: "b.mkv", SrcSize: 100},
+		{Type: domain.Extra, Path: "a.mkv", TgtSize: 100},
+	}
+	tests := []struct {
+		key     SortKey
+		reverse bool
+		want    []itemKey
+	}{
+		{SortByType, false, []itemKey{
+			{domain.Extra, "a.mkv"}, {domain.Extra, "c.mkv"},
+			{domain.Missing, "a.mkv"}, {domain.Missing, "b.mkv"}, {domain.Modified, "b.mkv"},
+		}},
+		{SortByPath, false, []itemKey{
+			{domain.Extra, "a.mkv"}, {domain.Missing, "a.mkv"},
+			{domain.Missing, "b.mkv"}, {domain.Modified, "b.mkv"}, {domain.Extra, "c.mkv"},
+		}},
+		// The larger of the two sizes counts; ties fall back to type and path.
+		{SortBySize, false, []itemKey{
+			{domain.Modified, "b.mkv"}, {domain.Missing, "a.mkv"},
+			{domain.Extra, "a.mkv"}, {domain.Extra, "c.mkv"}, {domain.Missing, "b.mkv"},
+		}},
+		{SortBySize, true, []itemKey{
+			{domain.Missing, "b.mkv"}, {domain.Extra, "c.mkv"}, {domain.Extra, "a.mkv"},
+			{domain.Missing, "a.mkv"}, {domain.Modified, "b.mkv"},
+		}},
+		{"", false, []itemKey{
+			{domain.Extra, "a.mkv"}, {domain.Extra, "c.mkv"},
+			{domain.Missing, "a.mkv"}, {domain.Missing, "b.mkv"}, {domain.Modified, "b.mkv"},
+		}},
+	}
+	for _, tt := range tests {
+		// Sort every rotation of the input to show the order doesn't depend
+		// on it.
+		for i := range items {
+			got := slices.Concat(items[i:], items[:i])
+			SortItems(got, tt.key, tt.reverse)
+			if !slices.Equal(keys(got), tt.want) {
+				t.Errorf("SortItems(%q, reverse %v) = %v, want %v", tt.key, tt.reverse, keys(got), tt.want)
+				break
+			}
+		}
+	}
+}
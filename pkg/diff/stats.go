@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"mddiff/pkg/domain"
+)
+
+// ComparatorStats summarizes the comparisons made through a StatsComparator.
+type ComparatorStats struct {
+	Calls     int
+	Modified  int
+	Unchanged int
+	// ByReason counts modifications by the kind of reason given, e.g.
+	// "Extension changed" or "Size changed".
+	ByReason map[string]int
+	Elapsed  time.Duration
+}
+
+// StatsComparator wraps an AssetComparator and records how many comparisons
+// it made, what they decided, and how long they took. It is safe for
+// concurrent use.
+type StatsComparator struct {
+	inner domain.AssetComparator
+
+	mu    sync.Mutex
+	stats ComparatorStats
+}
+
+// NewStatsComparator returns a StatsComparator that delegates to inner.
+func NewStatsComparator(inner domain.AssetComparator) *StatsComparator {
+	return &StatsComparator{
+		inner: inner,
+		stats: ComparatorStats{ByReason: make(map[string]int)},
+	}
+}
+
+// Compare delegates to the wrapped comparator and records the outcome.
+func (c *StatsComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	start := time.Now()
+	isModified, reason = c.inner.Compare(src, tgt)
+	elapsed := time.Since(start)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Calls++
+	c.stats.Elapsed += elapsed
+	if isModified {
+		c.stats.Modified++
+		c.stats.ByReason[reasonKind(reason)]++
+	} else {
+		c.stats.Unchanged++
+	}
+	return isModified, reason
+}
+
+// Stats returns a snapshot of the counters collected so far.
+func (c *StatsComparator) Stats() ComparatorStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := c.stats
+	snapshot.ByReason = make(map[string]int, len(c.stats.ByReason))
+	for k, v := range c.stats.ByReason {
+		snapshot.ByReason[k] = v
+	}
+	return snapshot
+}
+
+// reasonKind strips the details from a reason such as
+// "Extension changed: .mkv -> .mp4" so similar outcomes are counted together.
+func reasonKind(reason string) string {
+	kind, _, _ := strings.Cut(reason, ":")
+	return strings.TrimSpace(kind)
+}
@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"maps"
+	"sync"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestStatsComparator(t *testing.T) {
+	inner := &BasicComparator{}
+	c := NewStatsComparator(inner)
+	pairs := []struct{ src, tgt domain.Asset }{
+		{file("a.mkv", 10), file("a.mkv", 10)},
+		{file("b.mkv", 10), file("b.mkv", 12)},
+		{file("c.mkv", 10), file("c.mkv", 8)},
+		{file("d.avi", 10), file("d.mkv", 10)},
+		{dir("e"), dir("e")},
+	}
+	for _, p := range pairs {
+		gotModified, gotReason := c.Compare(p.src, p.tgt)
+		wantModified, wantReason := inner.Compare(p.src, p.tgt)
+		if gotModified != wantModified || gotReason != wantReason {
+			t.Errorf("Compare(%s) = %v, %q, want the wrapped %v, %q",
+				p.src.Path, gotModified, gotReason, wantModified, wantReason)
+		}
+	}
+
+	st := c.Stats()
+	if st.Calls != 5 || st.Modified != 3 || st.Unchanged != 2 {
+		t.Errorf("stats = %+v, want 5 calls, 3 modified and 2 unchanged", st)
+	}
+	want := map[string]int{"Size changed": 2, "Extension changed": 1}
+	if !maps.Equal(st.ByReason, want) {
+		t.Errorf("ByReason = %v, want %v", st.ByReason, want)
+	}
+
+	// Stats returns a snapshot that later comparisons don't change.
+	c.Compare(file("f.mkv", 1), file("f.mkv", 2))
+	if st.Calls != 5 || st.ByReason["Size changed"] != 2 {
+		t.Errorf("snapshot changed to %+v", st)
+	}
+}
+
+func TestStatsComparatorConcurrent(t *testing.T) {
+	c := NewStatsComparator(&BasicComparator{})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				c.Compare(file("a.mkv", 1), file("a.mkv", 2))
+			}
+		})
+	}
+	wg.Wait()
+	if st := c.Stats(); st.Calls != 800 || st.ByReason["Size changed"] != 800 {
+		t.Errorf("stats = %+v, want 800 calls", st)
+	}
+}
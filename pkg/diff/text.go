@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+
+	"mddiff/pkg/domain"
+)
+
+const (
+	// maxTextSize is the largest file TextComparator reads into memory.
+	// Larger files are left to the wrapped comparator.
+	maxTextSize = 16 << 20
+	// maxPreviewSize is the largest file TextPreview will diff.
+	maxPreviewSize = 1 << 20
+
+	// LineEndingsReason is the reason given for text files that only differ
+	// in their line endings.
+	LineEndingsReason = "Line endings differ (content identical)"
+)
+
+// TextComparator compares text files, such as subtitles, by content so that a
+// file that only changed line endings (CRLF vs LF) gets its own reason rather
+// than showing up as a size change. Files with other extensions, or that
+// can't be read, are left to the wrapped comparator.
+type TextComparator struct {
+	inner domain.AssetComparator
+	exts  map[string]bool
+}
+
+// NewTextComparator returns a TextComparator for the given extensions, which
+// are matched case-insensitively with or without a leading dot.
+func NewTextComparator(inner domain.AssetComparator, exts []string) *TextComparator {
+	c := &TextComparator{inner: inner, exts: make(map[string]bool, len(exts))}
+	for _, ext := range exts {
+		c.exts[normalizeExt(ext)] = true
+	}
+	return c
+}
+
+// Compare implements domain.AssetComparator.
+func (c *TextComparator) Compare(src, tgt domain.Asset) (isModified bool, reason string) {
+	if src.IsDir || tgt.IsDir || src.Ext != tgt.Ext || !c.exts[strings.ToLower(src.Ext)] ||
+		src.Size > maxTextSize || tgt.Size > maxTextSize {
+		return c.inner.Compare(src, tgt)
+	}
+
+	a, err := os.ReadFile(src.AbsPath) // #nosec G304 -- path comes from a directory walk
+	if err != nil {
+		return c.inner.Compare(src, tgt)
+	}
+	b, err := os.ReadFile(tgt.AbsPath) // #nosec G304 -- path comes from a directory walk
+	if err != nil {
+		return c.inner.Compare(src, tgt)
+	}
+
+	switch {
+	case bytes.Equal(a, b):
+		return false, ""
+	case bytes.Equal(normalizeNewlines(a), normalizeNewlines(b)):
+		return true, LineEndingsReason
+	default:
+		return true, "Text content differs"
+	}
+}
+
+// TextPreview returns up to maxLines lines of a unified diff between the files
+// at srcPath and tgtPath. It returns "" without an error when either file is
+// too large or looks binary.
+func TextPreview(srcPath, tgtPath string, maxLines int) (string, error) {
+	a, err := readPreviewable(srcPath)
+	if a == nil || err != nil {
+		return "", err
+	}
+	b, err := readPreviewable(tgtPath)
+	if b == nil || err != nil {
+		return "", err
+	}
+
+	unified := udiff.Unified("source", "target", string(a), string(b))
+	// Drop the "--- source" and "+++ target" header; the hunks start at "@@".
+	lines := strings.SplitAfter(strings.TrimSuffix(unified, "\n"), "\n")
+	if len(lines) >= 2 {
+		lines = lines[2:]
+	}
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines], "...\n")
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// readPreviewable reads the file at path, or returns nil if it is too large
+// or contains a NUL byte.
+func readPreviewable(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxPreviewSize {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from a directory walk
+	if err != nil || bytes.IndexByte(data, 0) >= 0 {
+		return nil, err
+	}
+	return data, nil
+}
+
+func normalizeNewlines(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+}
@@ -0,0 +1,95 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// writeAsset writes content to name under dir and returns it as a file asset.
+func writeAsset(t *testing.T, dir, name, content string) domain.Asset {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := file(name, int64(len(content)))
+	a.AbsPath = path
+	return a
+}
+
+func TestTextComparator(t *testing.T) {
+	tmp := t.TempDir()
+	lf := writeAsset(t, tmp, "lf.srt", "1\nHello\n")
+	crlf := writeAsset(t, tmp, "crlf.srt", "1\r\nHello\r\n")
+	changed := writeAsset(t, tmp, "changed.srt", "1\r\nHullo\r\n")
+	same := writeAsset(t, tmp, "same.srt", "1\nHello\n")
+	upper := writeAsset(t, tmp, "upper.SRT", "1\r\nHello\r\n")
+	other := writeAsset(t, tmp, "a.mkv", "1\r\nHello\r\n")
+	missing := file("missing.srt", 8)
+	missing.AbsPath = filepath.Join(tmp, "missing.srt")
+
+	tests := []struct {
+		name     string
+		src, tgt domain.Asset
+		modified bool
+		reason   string
+	}{
+		{"identical", lf, same, false, ""},
+		{"CRLF to LF", crlf, lf, true, LineEndingsReason},
+		{"LF to CRLF", lf, crlf, true, LineEndingsReason},
+		{"content changed", lf, changed, true, "Text content differs"},
+		{"extension case", upper, writeAsset(t, tmp, "lower.SRT", "1\nHello\n"), true, LineEndingsReason},
+		// The wrapped comparator sees these.
+		{"not a text extension", other, writeAsset(t, tmp, "b.mkv", "1\nHello\n"), true, "inner"},
+		{"different extensions", lf, other, true, "inner"},
+		{"unreadable", missing, lf, true, "inner"},
+		{"directories", dir("d"), dir("d"), true, "inner"},
+	}
+	c := NewTextComparator(modifiedComparator{}, []string{"SRT", ".txt"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified, reason := c.Compare(tt.src, tt.tgt)
+			if modified != tt.modified || reason != tt.reason {
+				t.Errorf("Compare = %v, %q, want %v, %q", modified, reason, tt.modified, tt.reason)
+			}
+		})
+	}
+}
+
+func TestTextPreview(t *testing.T) {
+	tmp := t.TempDir()
+	src := writeAsset(t, tmp, "a.srt", "1\nHello\n2\nWorld\n").AbsPath
+	tgt := writeAsset(t, tmp, "b.srt", "1\nHullo\n2\nWorld\n").AbsPath
+	binary := writeAsset(t, tmp, "c.srt", "1\nHello\x00\n").AbsPath
+	large := writeAsset(t, tmp, "d.srt", strings.Repeat("x", maxPreviewSize+1)).AbsPath
+
+	got, err := TextPreview(src, tgt, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "@@ -1,4 +1,4 @@\n 1\n-Hello\n+Hullo\n 2\n World"
+	if got != want {
+		t.Errorf("TextPreview = %q, want %q", got, want)
+	}
+
+	got, err = TextPreview(src, tgt, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "@@ -1,4 +1,4 @@\n 1\n...\n"; got != want {
+		t.Errorf("TextPreview limited to 2 lines = %q, want %q", got, want)
+	}
+
+	for _, other := range []string{binary, large} {
+		if got, err := TextPreview(src, other, 20); got != "" || err != nil {
+			t.Errorf("TextPreview(%s) = %q, %v, want nothing", filepath.Base(other), got, err)
+		}
+	}
+	if _, err := TextPreview(src, filepath.Join(tmp, "missing.srt"), 20); err == nil {
+		t.Error("TextPreview of a missing file succeeded")
+	}
+}
@@ -0,0 +1,138 @@
+package diff
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultThresholdKey sets the threshold for extensions not listed in a
+// threshold map.
+const DefaultThresholdKey = "*"
+
+// Threshold is a tolerated size difference between two files, given either
+// in bytes or as a percentage of the larger file.
+type Threshold struct {
+	Bytes   int64
+	Percent float64
+	// IsPercent selects Percent over Bytes.
+	IsPercent bool
+}
+
+// Exceeded reports whether the difference between sizes a and b is larger
+// than the threshold allows. With a percentage, an empty file is always
+// different from a non-empty one.
+func (t Threshold) Exceeded(a, b int64) bool {
+	delta := a - b
+	if delta < 0 {
+		delta = -delta
+	}
+	if !t.IsPercent {
+		return delta > t.Bytes
+	}
+	if delta == 0 {
+		return false
+	}
+	if a == 0 || b == 0 {
+		return true
+	}
+	return percentDelta(a, b) > t.Percent
+}
+
+// percentDelta returns the difference between sizes a and b as a percentage
+// of the larger one.
+func percentDelta(a, b int64) float64 {
+	delta := a - b
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta == 0 {
+		return 0
+	}
+	return float64(delta) / float64(max(a, b)) * 100
+}
+
+// sizeUnits maps the size suffixes accepted by ParseSize, lowercased, to their
+// multipliers. KB, MB, ... are decimal and KiB, MiB, ... binary.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// ParseSize parses a byte count with an optional, case-insensitive unit, such
+// as "512", "1KB", "5 MB" or "2GiB".
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	num := strings.TrimRightFunc(s, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	})
+	unit, ok := sizeUnits[strings.ToLower(s[len(num):])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q (unknown unit)", s)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || n < 0 || n*float64(unit) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// ParseThreshold parses a byte count ("512", "1MB"; see ParseSize) or a
+// percentage ("5%").
+func ParseThreshold(s string) (Threshold, error) {
+	s = strings.TrimSpace(s)
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		p, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil || p < 0 {
+			return Threshold{}, fmt.Errorf("invalid percentage threshold %q", s)
+		}
+		return Threshold{Percent: p, IsPercent: true}, nil
+	}
+
+	n, err := ParseSize(s)
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid byte threshold %q", s)
+	}
+	return Threshold{Bytes: n}, nil
+}
+
+// ParseThresholdMap parses a comma-separated list of ext=threshold pairs such
+// as ".flac=0,.mp4=5%". Extensions are lowercased and given a leading dot if
+// they lack one. The key "*" sets the threshold for unlisted extensions.
+func ParseThresholdMap(s string) (map[string]Threshold, error) {
+	thresholds := make(map[string]Threshold)
+	for entry := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		ext, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid threshold entry %q (want ext=value)", entry)
+		}
+		t, err := ParseThreshold(value)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[normalizeExt(ext)] = t
+	}
+	return thresholds, nil
+}
+
+// normalizeExt lowercases ext and ensures it starts with a dot. The default
+// threshold key is left as is.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && ext != DefaultThresholdKey && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,101 @@
+package diff
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseThresholdMap(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]Threshold
+		wantErr bool
+	}{
+		{in: "", want: map[string]Threshold{}},
+		{
+			in: ".flac=0,MP4=5%, *=1MB",
+			want: map[string]Threshold{
+				".flac": {Bytes: 0},
+				".mp4":  {Percent: 5, IsPercent: true},
+				"*":     {Bytes: 1e6},
+			},
+		},
+		{in: ".flac", wantErr: true},
+		{in: ".flac=lots", wantErr: true},
+		{in: ".mp4=-5%", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseThresholdMap(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseThresholdMap(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("ParseThresholdMap(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBasicComparatorExtThresholds(t *testing.T) {
+	thresholds, err := ParseThresholdMap(".flac=0,.mp4=10%,*=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &BasicComparator{SizeThreshold: 1000, ExtThresholds: thresholds}
+	tests := []struct {
+		name     string
+		src, tgt int64
+		modified bool
+	}{
+		{"a.flac", 100, 101, true},
+		{"a.FLAC", 100, 100, false},
+		{"a.mp4", 100, 109, false},
+		{"a.mp4", 100, 112, true},
+		// Unlisted extensions use the "*" entry rather than SizeThreshold.
+		{"a.mkv", 100, 105, false},
+		{"a.mkv", 100, 106, true},
+	}
+	for _, tt := range tests {
+		modified, reason := c.Compare(file(tt.name, tt.src), file(tt.name, tt.tgt))
+		if modified != tt.modified {
+			t.Errorf("%s %d -> %d: modified = %v (%q), want %v", tt.name, tt.src, tt.tgt, modified, reason, tt.modified)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "512", want: 512},
+		{in: "512B", want: 512},
+		{in: "1KB", want: 1000},
+		{in: "1kb", want: 1000},
+		{in: "5 MB", want: 5e6},
+		{in: "2GB", want: 2e9},
+		{in: "1TB", want: 1e12},
+		{in: "1KiB", want: 1024},
+		{in: "1.5KiB", want: 1536},
+		{in: "5MiB", want: 5 << 20},
+		{in: "2gib", want: 2 << 30},
+		{in: "1TiB", want: 1 << 40},
+		{in: "", wantErr: true},
+		{in: "KB", wantErr: true},
+		{in: "-1KB", wantErr: true},
+		{in: "5XB", wantErr: true},
+		{in: "99999999TB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
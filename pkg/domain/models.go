@@ -0,0 +1,236 @@
+/*
+Package domain holds the core types shared by the scanner, diff engine and
+reporters.
+*/
+package domain
+
+import "time"
+
+// Asset is a single file or directory discovered while scanning a tree.
+type Asset struct {
+	// Path is relative to the root of the scanned tree and separated by forward
+	// slashes on every OS.
+	Path string `json:"path"`
+	// AbsPath is the location of the asset on disk. It is not serialized.
+	AbsPath string    `json:"-"`
+	Ext     string    `json:"ext"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+	// IsSymlink is set for symbolic links, which are not followed. Size is
+	// then the size of the link itself.
+	IsSymlink bool `json:"is_symlink,omitempty"`
+	// LinkTarget is where a symbolic link points, as stored in the link.
+	LinkTarget string `json:"link_target,omitempty"`
+	// Owner is nil where the platform doesn't expose file ownership.
+	Owner *Owner `json:"owner,omitempty"`
+	// Hash is the SHA-256 digest of the content, when already known.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Owner is the numeric user and group that own a file.
+type Owner struct {
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+}
+
+// DirectoryTree is the result of scanning a single root directory.
+type DirectoryTree struct {
+	RootPath string           `json:"root_path"`
+	Assets   map[string]Asset `json:"assets"`
+	// FileCount and TotalSize count the files in Assets and their combined
+	// size in bytes. Directories aren't included.
+	FileCount int   `json:"file_count"`
+	TotalSize int64 `json:"total_size"`
+	// ScanErrors lists the paths that were skipped because they couldn't be
+	// read, when the scanner was asked to continue past errors.
+	ScanErrors []ScanError `json:"scan_errors,omitempty"`
+	// SymlinkCycles lists the symlinks that weren't followed because they
+	// lead back to a directory above them. They are skipped on purpose, so
+	// they aren't in ScanErrors.
+	SymlinkCycles []string `json:"symlink_cycles,omitempty"`
+}
+
+// ScanError is a path that couldn't be scanned.
+type ScanError struct {
+	// Path is relative to the root of the scanned tree.
+	Path string `json:"path"`
+	Err  string `json:"error"`
+}
+
+// DiffType classifies a difference between two trees.
+type DiffType string
+
+// Supported diff types.
+const (
+	Missing  DiffType = "MISSING"
+	Extra    DiffType = "EXTRA"
+	Modified DiffType = "MODIFIED"
+	Renamed  DiffType = "RENAMED"
+	// ExtChanged is a file whose extension changed but is otherwise
+	// unchanged, e.g. a remux. It is only used when the engine is asked to
+	// separate extension changes from other modifications.
+	ExtChanged DiffType = "EXT_CHANGED"
+	// Moved is a file found under the same name in a different directory. It
+	// is only used when the engine is asked to detect moves.
+	Moved DiffType = "MOVED"
+	// Matched is a file found unchanged in both trees. It is only used when
+	// the engine is asked to list matches, and isn't a difference.
+	Matched DiffType = "MATCHED"
+	// EmptyDir is an empty directory present on only one side; its Reason
+	// says which. It is only used when the engine is asked to separate empty
+	// directories from missing and extra files.
+	EmptyDir DiffType = "EMPTY_DIR"
+)
+
+// Reasons of EmptyDir items, saying which side the directory is on.
+const (
+	EmptyDirInSource = "Empty directory missing from the target"
+	EmptyDirInTarget = "Empty directory only in the target"
+)
+
+// SizeChangedReason starts the reason given for a file whose size changed,
+// which goes on to give the change in bytes.
+const SizeChangedReason = "Size changed"
+
+// DiffItem is a single difference between the source and target trees.
+type DiffItem struct {
+	Type    DiffType `json:"type"`
+	Path    string   `json:"path"`
+	NewPath string   `json:"new_path,omitempty"` // Target path of a RENAMED or MOVED item.
+	Reason  string   `json:"reason,omitempty"`
+	SrcSize int64    `json:"src_size,omitempty"`
+	TgtSize int64    `json:"tgt_size,omitempty"`
+	// SizeDelta is TgtSize minus SrcSize for MODIFIED and EXT_CHANGED items.
+	SizeDelta int64 `json:"size_delta,omitempty"`
+	// Link is an editor URI for the item's file, set with --editor-links.
+	Link string `json:"link,omitempty"`
+	// Preview is the start of a unified diff of a modified text file, set
+	// with --text-preview.
+	Preview string `json:"preview,omitempty"`
+}
+
+// Summary holds aggregate counts for a DiffReport.
+type Summary struct {
+	TotalMissing  int `json:"total_missing"`
+	TotalModified int `json:"total_modified"`
+	TotalExtra    int `json:"total_extra"`
+	// TotalPaired counts the source files that have a counterpart in the
+	// target with the same directory and name, ignoring the extension, and
+	// so were compared. It includes the files in TotalModified as well as the
+	// unchanged ones.
+	TotalPaired int `json:"total_paired"`
+	// TotalRenamed counts RENAMED items, which aren't in TotalMissing or
+	// TotalExtra. Renames are also included in TotalModified when counted as
+	// modifications.
+	TotalRenamed int `json:"total_renamed,omitempty"`
+	// TotalMoved counts MOVED items, which aren't in TotalMissing or
+	// TotalExtra.
+	TotalMoved int `json:"total_moved,omitempty"`
+	// TotalExtChanged counts EXT_CHANGED items, which aren't in TotalModified.
+	TotalExtChanged int `json:"total_ext_changed,omitempty"`
+	// TotalEmptyDirs counts EMPTY_DIR items, which aren't in TotalMissing or
+	// TotalExtra.
+	TotalEmptyDirs int `json:"total_empty_dirs,omitempty"`
+	// TotalSkipped counts the paths on either side that couldn't be read and
+	// so weren't compared, from DirectoryTree.ScanErrors.
+	TotalSkipped int `json:"total_skipped,omitempty"`
+	// MissingBytes and ExtraBytes are the combined sizes of the missing and
+	// extra files, i.e. how much would need copying to reconcile the trees.
+	MissingBytes int64 `json:"missing_bytes"`
+	ExtraBytes   int64 `json:"extra_bytes"`
+	// ModifiedBytes is the sum of the size changes of the modified files,
+	// negative when they shrank overall.
+	ModifiedBytes int64 `json:"modified_bytes"`
+}
+
+// Sampling describes a sampled content verification pass.
+type Sampling struct {
+	Percent float64 `json:"percent"`
+	Seed    int64   `json:"seed"`
+	// Eligible is the number of matched files that could have been verified.
+	Eligible int `json:"eligible"`
+	// Sampled is the number of matched files that were actually verified.
+	Sampled int `json:"sampled"`
+}
+
+// Coverage returns the fraction of eligible files that were verified, as a percentage.
+func (s *Sampling) Coverage() float64 {
+	if s.Eligible == 0 {
+		return 0
+	}
+	return float64(s.Sampled) / float64(s.Eligible) * 100
+}
+
+// DuplicateGroup is a set of files in one tree that have the same size, and
+// the same content when Hash is set.
+type DuplicateGroup struct {
+	Size int64 `json:"size"`
+	// Hash is the SHA-256 digest the files share, when they were hashed.
+	Hash string `json:"hash,omitempty"`
+	// Paths are relative to the root of the tree, sorted.
+	Paths []string `json:"paths"`
+}
+
+// Duplicate is a file whose name suggests it is an accidental copy of another
+// file in the same directory, e.g. "Movie (1).mkv" next to "Movie.mkv".
+type Duplicate struct {
+	// Side is "source" or "target".
+	Side     string `json:"side"`
+	Path     string `json:"path"`
+	Original string `json:"original"`
+}
+
+// ReportSchemaVersion identifies the shape of a serialized DiffReport. Bump
+// it whenever a field of the report, or of anything it contains, is added,
+// removed or changes meaning, so consumers can tell which shape they have.
+const ReportSchemaVersion = "2"
+
+// DiffReport is the full result of comparing two trees.
+type DiffReport struct {
+	// SchemaVersion is the ReportSchemaVersion of the build that produced the
+	// report.
+	SchemaVersion string `json:"schema_version"`
+	// GeneratedAt is when the comparison was made, in UTC.
+	GeneratedAt time.Time  `json:"generated_at"`
+	SourceDir   string     `json:"source_dir"`
+	TargetDir   string     `json:"target_dir"`
+	Items       []DiffItem `json:"items"`
+	Summary     Summary    `json:"summary"`
+	Sampling    *Sampling  `json:"sampling,omitempty"`
+	// Duplicates is only populated when duplicate detection is enabled.
+	Duplicates []Duplicate `json:"duplicates,omitempty"`
+	// Truncated is set when the run was stopped early, e.g. by a deadline, so
+	// the report is incomplete.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Differences returns the number of items that are differences, i.e. every
+// item except MATCHED ones.
+func (r *DiffReport) Differences() int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Type != Matched {
+			n++
+		}
+	}
+	return n
+}
+
+// PairReport tags a DiffReport with the source and target arguments that
+// produced it, so reports from a multi-pair run can be told apart.
+type PairReport struct {
+	Source string      `json:"source"`
+	Target string      `json:"target"`
+	Report *DiffReport `json:"report"`
+}
+
+// Scanner builds a DirectoryTree from a root path.
+type Scanner interface {
+	Scan(rootPath string) (*DirectoryTree, error)
+}
+
+// AssetComparator decides whether two assets with the same identity differ.
+type AssetComparator interface {
+	Compare(src, tgt Asset) (isModified bool, reason string)
+}
@@ -0,0 +1,110 @@
+package manifest
+
+import (
+	"crypto/md5" // #nosec G501 -- only for md5sum-compatible output
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/domain"
+)
+
+// Format is a layout the manifest command can write.
+type Format string
+
+// Supported manifest formats.
+const (
+	// FormatJSON is the full tree, as read by Read.
+	FormatJSON Format = "json"
+	// FormatMD5Sum and FormatSHA256Sum are "<digest>  <path>" lines that
+	// md5sum -c and sha256sum -c can check.
+	FormatMD5Sum    Format = "md5sum"
+	FormatSHA256Sum Format = "sha256sum"
+	// FormatTSV is a header and one "path, size, sha256" row per file.
+	FormatTSV Format = "tsv"
+)
+
+// WriteFormat writes tree to w in format. The line-based formats only list
+// files, sorted by path, with paths relative to the tree's root.
+func WriteFormat(w io.Writer, tree *domain.DirectoryTree, format Format) error {
+	switch format {
+	case FormatJSON:
+		return Write(w, tree)
+	case FormatMD5Sum, FormatSHA256Sum:
+		return writeSums(w, tree, format)
+	case FormatTSV:
+		return writeTSV(w, tree)
+	default:
+		return fmt.Errorf("unknown manifest format: %s", format)
+	}
+}
+
+func sortedFiles(tree *domain.DirectoryTree) []domain.Asset {
+	files := make([]domain.Asset, 0, len(tree.Assets))
+	for _, asset := range tree.Assets {
+		if !asset.IsDir {
+			files = append(files, asset)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+func sha256Of(asset domain.Asset) (string, error) {
+	if asset.Hash != "" {
+		return asset.Hash, nil
+	}
+	return checksum.HashFile(asset.AbsPath)
+}
+
+// writeSums writes coreutils checksum lines. Like coreutils, a path holding a
+// backslash or line break is escaped and its line marked with a leading
+// backslash.
+func writeSums(w io.Writer, tree *domain.DirectoryTree, format Format) error {
+	for _, asset := range sortedFiles(tree) {
+		var sum string
+		var err error
+		if format == FormatMD5Sum {
+			sum, err = checksum.SumFile(asset.AbsPath, md5.New()) // #nosec G401 -- not used for security
+		} else {
+			sum, err = sha256Of(asset)
+		}
+		if err != nil {
+			return fmt.Errorf("hashing %s: %w", asset.Path, err)
+		}
+
+		prefix := ""
+		path := asset.Path
+		if strings.ContainsAny(path, "\\\n\r") {
+			prefix = "\\"
+			path = sumEscaper.Replace(path)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s  %s\n", prefix, sum, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	sumEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+	tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+)
+
+func writeTSV(w io.Writer, tree *domain.DirectoryTree) error {
+	if _, err := fmt.Fprintln(w, "path\tsize\tsha256"); err != nil {
+		return err
+	}
+	for _, asset := range sortedFiles(tree) {
+		sum, err := sha256Of(asset)
+		if err != nil {
+			return fmt.Errorf("hashing %s: %w", asset.Path, err)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%s\n", tsvEscaper.Replace(asset.Path), asset.Size, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
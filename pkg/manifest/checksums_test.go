@@ -0,0 +1,90 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+const (
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+)
+
+// sampleTree returns a tree whose files all hold "hello", with names that need
+// escaping in some layouts.
+func sampleTree(t *testing.T) *domain.DirectoryTree {
+	t.Helper()
+	abs := filepath.Join(t.TempDir(), "hello")
+	if err := os.WriteFile(abs, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tree := &domain.DirectoryTree{Assets: make(map[string]domain.Asset)}
+	for _, p := range []string{"show/a b.mkv", "back\\slash.mkv", "tab\tname.mkv", "line\nbreak.mkv"} {
+		tree.Assets[p] = domain.Asset{Path: p, Size: 5, AbsPath: abs}
+	}
+	tree.Assets["show"] = domain.Asset{Path: "show", IsDir: true}
+	return tree
+}
+
+func TestWriteFormat(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			FormatMD5Sum,
+			"\\" + helloMD5 + "  back\\\\slash.mkv\n" +
+				"\\" + helloMD5 + "  line\\nbreak.mkv\n" +
+				helloMD5 + "  show/a b.mkv\n" +
+				helloMD5 + "  tab\tname.mkv\n",
+		},
+		{
+			FormatSHA256Sum,
+			"\\" + helloSHA256 + "  back\\\\slash.mkv\n" +
+				"\\" + helloSHA256 + "  line\\nbreak.mkv\n" +
+				helloSHA256 + "  show/a b.mkv\n" +
+				helloSHA256 + "  tab\tname.mkv\n",
+		},
+		{
+			FormatTSV,
+			"path\tsize\tsha256\n" +
+				"back\\\\slash.mkv\t5\t" + helloSHA256 + "\n" +
+				"line\\nbreak.mkv\t5\t" + helloSHA256 + "\n" +
+				"show/a b.mkv\t5\t" + helloSHA256 + "\n" +
+				"tab\\tname.mkv\t5\t" + helloSHA256 + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteFormat(&buf, sampleTree(t), tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	if err := WriteFormat(&bytes.Buffer{}, sampleTree(t), "xml"); err == nil {
+		t.Error("WriteFormat(xml) succeeded")
+	}
+}
+
+func TestWriteFormatKnownHash(t *testing.T) {
+	// A recorded hash is used instead of reading the file.
+	tree := &domain.DirectoryTree{Assets: map[string]domain.Asset{
+		"a.mkv": {Path: "a.mkv", Size: 5, Hash: helloSHA256, AbsPath: filepath.Join(t.TempDir(), "missing")},
+	}}
+	var buf bytes.Buffer
+	if err := WriteFormat(&buf, tree, FormatSHA256Sum); err != nil {
+		t.Fatal(err)
+	}
+	if want := helloSHA256 + "  a.mkv\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Package manifest serializes scanned directory trees so they can be compared
+later or on another machine.
+*/
+package manifest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"mddiff/pkg/checksum"
+	"mddiff/pkg/domain"
+)
+
+// Write encodes tree to w as indented JSON.
+func Write(w io.Writer, tree *domain.DirectoryTree) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tree)
+}
+
+// AddHashes fills in Asset.Hash for every file in tree that doesn't have one,
+// so that a manifest records content as well as sizes.
+func AddHashes(tree *domain.DirectoryTree) error {
+	for path, asset := range tree.Assets {
+		if asset.IsDir || asset.Hash != "" {
+			continue
+		}
+		sum, err := checksum.HashFile(asset.AbsPath)
+		if err != nil {
+			return fmt.Errorf("hashing %s: %w", path, err)
+		}
+		asset.Hash = sum
+		tree.Assets[path] = asset
+	}
+	return nil
+}
+
+// Read decodes a tree written by Write and checks that it is well formed, so
+// that a truncated or hand-edited manifest fails instead of producing a
+// misleading diff.
+func Read(r io.Reader) (*domain.DirectoryTree, error) {
+	tree := &domain.DirectoryTree{}
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(tree); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decoding manifest: unexpected data after the tree")
+	}
+	if tree.Assets == nil {
+		return nil, fmt.Errorf("decoding manifest: missing assets")
+	}
+	// Manifests written on Windows by older versions separate paths with
+	// backslashes.
+	assets := make(map[string]domain.Asset, len(tree.Assets))
+	for key, asset := range tree.Assets {
+		if err := validateAsset(key, asset); err != nil {
+			return nil, fmt.Errorf("invalid manifest: asset %q: %w", key, err)
+		}
+		asset.Path = filepath.ToSlash(asset.Path)
+		assets[asset.Path] = asset
+	}
+	tree.Assets = assets
+	return tree, nil
+}
+
+// validateAsset checks that asset, stored under key, is something Write could
+// have produced.
+func validateAsset(key string, asset domain.Asset) error {
+	switch {
+	case key != asset.Path:
+		return fmt.Errorf("path %q doesn't match its key", asset.Path)
+	case !filepath.IsLocal(key):
+		return errors.New("path must be relative and stay within the root")
+	case asset.Size < 0:
+		return fmt.Errorf("negative size %d", asset.Size)
+	case asset.IsDir && asset.Hash != "":
+		return errors.New("directory has a hash")
+	}
+	if asset.Hash != "" {
+		if b, err := hex.DecodeString(asset.Hash); err != nil || len(b) != 32 {
+			return fmt.Errorf("hash %q is not a hex SHA-256 digest", asset.Hash)
+		}
+	}
+	return nil
+}
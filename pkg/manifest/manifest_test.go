@@ -0,0 +1,44 @@
+package manifest
+
+import (
+	"bytes"
+	"maps"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestWriteRead(t *testing.T) {
+	tree := &domain.DirectoryTree{
+		RootPath: "/media",
+		Assets: map[string]domain.Asset{
+			"show":         {Path: "show", IsDir: true},
+			"show/ep1.mkv": {Path: "show/ep1.mkv", Ext: ".mkv", Size: 10},
+		},
+		FileCount: 1,
+		TotalSize: 10,
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, tree); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RootPath != tree.RootPath || got.FileCount != 1 || got.TotalSize != 10 {
+		t.Errorf("tree = %+v, want %+v", got, tree)
+	}
+	if !maps.Equal(got.Assets, tree.Assets) {
+		t.Errorf("assets = %+v, want %+v", got.Assets, tree.Assets)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for _, in := range []string{"", "{", `{"root_path": "/media"}`, "{\"assets\": {}}\n{}"} {
+		if _, err := Read(strings.NewReader(in)); err == nil {
+			t.Errorf("Read(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"mddiff/pkg/diff"
+	"mddiff/pkg/domain"
+	"mddiff/pkg/scanner"
+)
+
+// libraryFS holds a source and a target library for DiffDirectories.
+var libraryFS = fstest.MapFS{
+	"src/Movie.mkv":      {Data: []byte("12345")},
+	"src/show/ep1.mkv":   {Data: []byte("123")},
+	"src/show/ep2.mkv":   {Data: []byte("123")},
+	"src/notes.nfo":      {Data: []byte("1")},
+	"tgt/movie.mkv":      {Data: []byte("12345")},
+	"tgt/show/ep1.mkv":   {Data: []byte("1234")},
+	"tgt/show/ep3.mkv":   {Data: []byte("123")},
+	"tgt/show/.DS_Store": {Data: []byte("1")},
+}
+
+// typesAndPaths returns "TYPE path" for each item.
+func typesAndPaths(items []domain.DiffItem) []string {
+	var out []string
+	for _, item := range items {
+		out = append(out, string(item.Type)+" "+item.Path)
+	}
+	return out
+}
+
+func TestDiffDirectories(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{
+				"EXTRA movie.mkv", "EXTRA show/ep3.mkv",
+				"MISSING Movie.mkv", "MISSING notes.nfo", "MISSING show/ep2.mkv",
+				"MODIFIED show/ep1.mkv",
+			},
+		},
+		{
+			name: "ignore list and case",
+			opts: Options{Scan: scanner.ScanOptions{IgnoreExt: []string{"nfo"}}, IgnoreCase: true},
+			want: []string{"EXTRA show/ep3.mkv", "MISSING show/ep2.mkv", "MODIFIED Movie.mkv", "MODIFIED show/ep1.mkv"},
+		},
+		{
+			name: "comparator",
+			opts: Options{Comparator: diff.NopComparator{}, Scan: scanner.ScanOptions{IncludeExt: []string{"mkv"}}},
+			want: []string{"EXTRA movie.mkv", "EXTRA show/ep3.mkv", "MISSING Movie.mkv", "MISSING show/ep2.mkv"},
+		},
+		{
+			name: "renames sorted by path",
+			opts: Options{DetectRenames: true, Sort: diff.SortByPath, Scan: scanner.ScanOptions{IgnoreExt: []string{"nfo"}}},
+			want: []string{"RENAMED Movie.mkv", "MODIFIED show/ep1.mkv", "RENAMED show/ep2.mkv"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Scan.FS = libraryFS
+			r, err := DiffDirectories("src", "tgt", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := typesAndPaths(r.Items); !slices.Equal(got, tt.want) {
+				t.Errorf("items = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffDirectoriesErrors(t *testing.T) {
+	opts := Options{Scan: scanner.ScanOptions{FS: libraryFS}}
+	if _, err := DiffDirectories("nope", "tgt", opts); err == nil {
+		t.Error("DiffDirectories with a missing source succeeded")
+	}
+	if _, err := DiffDirectories("src", "nope", opts); err == nil {
+		t.Error("DiffDirectories with a missing target succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DiffDirectoriesContext(ctx, "src", "tgt", opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("DiffDirectoriesContext error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Package pipeline runs the scan, diff and report stages end to end, for
+programs that use mddiff as a library with their own scanners, comparators or
+reporters.
+*/
+package pipeline
+
+import (
+	"context"
+	"io"
+
+	"mddiff/pkg/diff"
+	"mddiff/pkg/domain"
+	"mddiff/pkg/report"
+	"mddiff/pkg/scanner"
+)
+
+// Tree is one side of a comparison: the Scanner that reads it and the root to
+// pass to Scan.
+type Tree struct {
+	Scanner domain.Scanner
+	Root    string
+}
+
+// Diff scans source and target and compares them with engine.
+func Diff(source, target Tree, engine *diff.Engine) (*domain.DiffReport, error) {
+	src, err := source.Scanner.Scan(source.Root)
+	if err != nil {
+		return nil, err
+	}
+	tgt, err := target.Scanner.Scan(target.Root)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Diff(src, tgt), nil
+}
+
+// Run scans source and target, compares them with comparator and writes the
+// result to w with reporter. The report is also returned so callers can act
+// on it, e.g. to choose an exit status.
+func Run(
+	source, target Tree,
+	comparator domain.AssetComparator,
+	reporter report.Reporter,
+	w io.Writer,
+) (*domain.DiffReport, error) {
+	r, err := Diff(source, target, diff.NewEngine(comparator))
+	if err != nil {
+		return nil, err
+	}
+	if err := reporter.Report(w, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Options configures DiffDirectories. The zero value compares by extension
+// and size, like the mddiff command without flags.
+type Options struct {
+	// Scan configures how both directories are read: ignored names and
+	// extensions, excludes, workers and so on. Its FS, if set, is read for
+	// both sides, with the source and target given as paths within it.
+	Scan scanner.ScanOptions
+	// Comparator decides whether matched files differ. Nil uses a
+	// diff.BasicComparator, which compares extensions and sizes.
+	Comparator domain.AssetComparator
+	// Verifier, when set, re-checks files the Comparator found unchanged,
+	// e.g. a diff.HashComparator to compare content.
+	Verifier domain.AssetComparator
+	// IgnoreCase matches files whose names differ only in case.
+	IgnoreCase bool
+	// NormalizeUnicode matches names that differ only in Unicode
+	// normalization, such as NFD names from macOS.
+	NormalizeUnicode bool
+	// DetectMoves reports a missing and an extra file with the same name
+	// and size in different directories as a single move.
+	DetectMoves bool
+	// DetectRenames reports a missing and an extra file with the same
+	// extension and size as a single rename.
+	DetectRenames bool
+	// SeparateExtChanges reports extension-only changes as EXT_CHANGED
+	// instead of MODIFIED.
+	SeparateExtChanges bool
+	// SeparateEmptyDirs reports empty directories found on only one side as
+	// EMPTY_DIR instead of MISSING or EXTRA.
+	SeparateEmptyDirs bool
+	// Sort orders the report's items; the empty key keeps the engine's
+	// order, by type and path. Reverse reverses whichever order is used.
+	Sort    diff.SortKey
+	Reverse bool
+}
+
+// NewEngine returns a diff engine configured by opts, ignoring opts.Scan.
+func NewEngine(opts Options) *diff.Engine {
+	comparator := opts.Comparator
+	if comparator == nil {
+		comparator = &diff.BasicComparator{}
+	}
+	engine := diff.NewEngine(comparator)
+	engine.Verifier = opts.Verifier
+	engine.IgnoreCase = opts.IgnoreCase
+	engine.NormalizeUnicode = opts.NormalizeUnicode
+	engine.DetectMoves = opts.DetectMoves
+	engine.DetectRenames = opts.DetectRenames
+	engine.SeparateExtChanges = opts.SeparateExtChanges
+	engine.SeparateEmptyDirs = opts.SeparateEmptyDirs
+	return engine
+}
+
+// DiffDirectories scans the source and target directories and compares them
+// as configured by opts.
+func DiffDirectories(source, target string, opts Options) (*domain.DiffReport, error) {
+	return DiffDirectoriesContext(context.Background(), source, target, opts)
+}
+
+// DiffDirectoriesContext is like DiffDirectories but stops when ctx is done,
+// returning ctx's error.
+func DiffDirectoriesContext(ctx context.Context, source, target string, opts Options) (*domain.DiffReport, error) {
+	s := scanner.NewLinearScannerWithOptions(opts.Scan)
+	src, err := s.ScanContext(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	tgt, err := s.ScanContext(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewEngine(opts).DiffContext(ctx, src, tgt)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Sort != "" || opts.Reverse {
+		diff.SortItems(r.Items, opts.Sort, opts.Reverse)
+	}
+	return r, nil
+}
@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"mddiff/pkg/diff"
+	"mddiff/pkg/domain"
+	"mddiff/pkg/report"
+)
+
+// memScanner is a domain.Scanner serving fixed trees by root, as a library
+// user reading from a database might.
+type memScanner map[string][]domain.Asset
+
+func (m memScanner) Scan(root string) (*domain.DirectoryTree, error) {
+	assets, ok := m[root]
+	if !ok {
+		return nil, errors.New("no such tree: " + root)
+	}
+	tree := &domain.DirectoryTree{RootPath: root, Assets: make(map[string]domain.Asset)}
+	for _, a := range assets {
+		tree.Assets[a.Path] = a
+	}
+	return tree, nil
+}
+
+var trees = memScanner{
+	"src": {
+		{Path: "a.mkv", Ext: ".mkv", Size: 1},
+		{Path: "b.mkv", Ext: ".mkv", Size: 1},
+	},
+	"tgt": {
+		{Path: "a.mkv", Ext: ".mkv", Size: 2},
+		{Path: "c.mkv", Ext: ".mkv", Size: 1},
+	},
+}
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := Run(Tree{trees, "src"}, Tree{trees, "tgt"}, &diff.BasicComparator{}, &report.CSVReporter{}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Summary.TotalMissing != 1 || r.Summary.TotalModified != 1 || r.Summary.TotalExtra != 1 {
+		t.Errorf("summary = %+v", r.Summary)
+	}
+	want := "type,path,reason,src_size,tgt_size\n" +
+		"EXTRA,c.mkv,,0,1\n" +
+		"MISSING,b.mkv,,1,0\n" +
+		"MODIFIED,a.mkv,Size changed: +1 bytes,1,2\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		source, target Tree
+	}{
+		{"source", Tree{trees, "nope"}, Tree{trees, "tgt"}},
+		{"target", Tree{trees, "src"}, Tree{trees, "nope"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		_, err := Run(tt.source, tt.target, &diff.BasicComparator{}, &report.CSVReporter{}, &buf)
+		if err == nil || err.Error() != "no such tree: nope" {
+			t.Errorf("%s: Run error = %v, want the scanner's", tt.name, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: Run wrote %q after failing", tt.name, buf.String())
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	engine := diff.NewEngine(diff.NopComparator{})
+	r, err := Diff(Tree{trees, "src"}, Tree{trees, "tgt"}, engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SourceDir != "src" || r.TargetDir != "tgt" || r.Summary.TotalModified != 0 || len(r.Items) != 2 {
+		t.Errorf("report = %+v", r)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Package remote scans directories on other machines by running mddiff there
+over SSH.
+*/
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"mddiff/pkg/domain"
+	"mddiff/pkg/manifest"
+)
+
+// commandNotFound is the exit status a POSIX shell uses when a command is
+// missing. ssh passes the remote shell's status through.
+const commandNotFound = 127
+
+// sshFailure is the exit status ssh itself uses for connection errors.
+const sshFailure = 255
+
+// Runner runs a command on host and returns its standard output.
+type Runner interface {
+	Run(ctx context.Context, host string, args ...string) ([]byte, error)
+}
+
+// ExitError is returned by a Runner when the remote command fails.
+type ExitError struct {
+	Code   int
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d: %s", e.Code, strings.TrimSpace(e.Stderr))
+}
+
+// SSHRunner runs commands with the local ssh client, so the user's ssh
+// config, keys and agent apply.
+type SSHRunner struct {
+	// Port, when set, is passed to ssh with -p.
+	Port string
+}
+
+// Run implements Runner. ssh joins its arguments into a single command line
+// for the remote shell, so each one is quoted to reach the command intact.
+func (r SSHRunner) Run(ctx context.Context, host string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "ssh", r.args(host, args)...) // #nosec G204 -- host and path come from the user
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, &ExitError{Code: exitErr.ExitCode(), Stderr: stderr.String()}
+	}
+	return out, err
+}
+
+// args returns the arguments for ssh to run args on host.
+func (r SSHRunner) args(host string, args []string) []string {
+	sshArgs := []string{"-o", "BatchMode=yes"}
+	if r.Port != "" {
+		sshArgs = append(sshArgs, "-p", r.Port)
+	}
+	sshArgs = append(sshArgs, "--", host)
+	for _, arg := range args {
+		sshArgs = append(sshArgs, shellQuote(arg))
+	}
+	return sshArgs
+}
+
+// shellQuote quotes s for a POSIX shell, so that spaces and metacharacters
+// in it are taken literally.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Target is a directory on a remote host.
+type Target struct {
+	// Host is passed to ssh as is, e.g. "user@nas".
+	Host string
+	// Port is the ssh port given in the URL, or empty for ssh's default.
+	Port string
+	Path string
+}
+
+// ParseTarget parses an ssh://[user@]host[:port]/path argument. ok is false when arg
+// isn't an ssh URL.
+func ParseTarget(arg string) (target Target, ok bool, err error) {
+	if !strings.HasPrefix(arg, "ssh://") {
+		return Target{}, false, nil
+	}
+	u, err := url.Parse(arg)
+	if err != nil {
+		return Target{}, true, fmt.Errorf("invalid ssh target %q: %w", arg, err)
+	}
+	if u.Host == "" || u.Path == "" {
+		return Target{}, true, fmt.Errorf("invalid ssh target %q: want ssh://[user@]host/path", arg)
+	}
+
+	host := u.Hostname()
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+	return Target{Host: host, Port: u.Port(), Path: u.Path}, true, nil
+}
+
+// Scanner implements domain.Scanner by running "mddiff manifest" on a remote
+// host and decoding the manifest it prints.
+type Scanner struct {
+	Host   string
+	Runner Runner
+	// Binary is the mddiff executable on the remote host.
+	Binary string
+}
+
+// NewScanner returns a Scanner for target's host that uses ssh and expects
+// mddiff to be on the remote PATH.
+func NewScanner(target Target) *Scanner {
+	return &Scanner{Host: target.Host, Runner: SSHRunner{Port: target.Port}, Binary: "mddiff"}
+}
+
+// Scan implements domain.Scanner. rootPath is a path on the remote host.
+func (s *Scanner) Scan(rootPath string) (*domain.DirectoryTree, error) {
+	return s.ScanContext(context.Background(), rootPath)
+}
+
+// ScanContext is like Scan but stops the remote scan when ctx is done. Like
+// scanner.LinearScanner.ScanContext, it then returns an error wrapping
+// ctx.Err() along with the assets found so far, which is none, since the
+// manifest is only read once the remote scan finishes.
+func (s *Scanner) ScanContext(ctx context.Context, rootPath string) (*domain.DirectoryTree, error) {
+	out, err := s.Runner.Run(ctx, s.Host, s.Binary, "manifest", rootPath)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		empty := &domain.DirectoryTree{RootPath: rootPath, Assets: make(map[string]domain.Asset)}
+		return empty, fmt.Errorf("scanning %s:%s: %w", s.Host, rootPath, ctxErr)
+	}
+	if err != nil {
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			switch exitErr.Code {
+			case commandNotFound:
+				return nil, fmt.Errorf("%s not found on %s; install mddiff there or put it on the PATH", s.Binary, s.Host)
+			case sshFailure:
+				return nil, fmt.Errorf("connecting to %s: %w", s.Host, err)
+			}
+		}
+		return nil, fmt.Errorf("scanning %s:%s: %w", s.Host, rootPath, err)
+	}
+
+	tree, err := manifest.Read(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest from %s: %w", s.Host, err)
+	}
+	return tree, nil
+}
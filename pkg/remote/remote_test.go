@@ -0,0 +1,175 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRunner returns canned output and records the command it was asked to
+// run.
+type fakeRunner struct {
+	out  string
+	err  error
+	host string
+	args []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, host string, args ...string) ([]byte, error) {
+	r.host, r.args = host, args
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return []byte(r.out), r.err
+}
+
+const cannedManifest = `{
+  "root_path": "/media",
+  "assets": {
+    "a.mkv": {"path": "a.mkv", "ext": ".mkv", "size": 3, "mod_time": "2025-01-02T03:04:05Z", "is_dir": false}
+  },
+  "file_count": 1,
+  "total_size": 3
+}
+`
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    Target
+		ok      bool
+		wantErr bool
+	}{
+		{arg: "/local/path"},
+		{arg: "ssh://nas/media", want: Target{Host: "nas", Path: "/media"}, ok: true},
+		{arg: "ssh://me@nas/media/tv", want: Target{Host: "me@nas", Path: "/media/tv"}, ok: true},
+		{arg: "ssh://me@nas:2222/media", want: Target{Host: "me@nas", Port: "2222", Path: "/media"}, ok: true},
+		{arg: "ssh://nas/srv/My%20Movies", want: Target{Host: "nas", Path: "/srv/My Movies"}, ok: true},
+		{arg: "ssh://nas", ok: true, wantErr: true},
+		{arg: "ssh:///media", ok: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := ParseTarget(tt.arg)
+		if got != tt.want || ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("ParseTarget(%q) = %+v, %v, %v, want %+v, %v, error %v",
+				tt.arg, got, ok, err, tt.want, tt.ok, tt.wantErr)
+		}
+	}
+}
+
+func TestSSHRunnerArgs(t *testing.T) {
+	tests := []struct {
+		runner SSHRunner
+		args   []string
+		want   []string
+	}{
+		{
+			runner: SSHRunner{},
+			args:   []string{"mddiff", "manifest", "/srv/My Movies"},
+			want:   []string{"-o", "BatchMode=yes", "--", "nas", "'mddiff'", "'manifest'", "'/srv/My Movies'"},
+		},
+		{
+			runner: SSHRunner{Port: "2222"},
+			args:   []string{"/srv/it's $(here);`now`"},
+			want:   []string{"-o", "BatchMode=yes", "-p", "2222", "--", "nas", `'/srv/it'\''s $(here);` + "`now`'"},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.runner.args("nas", tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("%+v.args(%q) = %q, want %q", tt.runner, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	runner := &fakeRunner{out: cannedManifest}
+	s := &Scanner{Host: "me@nas", Runner: runner, Binary: "/opt/mddiff"}
+	tree, err := s.Scan("/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runner.host != "me@nas" || !slices.Equal(runner.args, []string{"/opt/mddiff", "manifest", "/media"}) {
+		t.Errorf("ran %v on %s", runner.args, runner.host)
+	}
+	if a, ok := tree.Assets["a.mkv"]; !ok || a.Size != 3 || tree.FileCount != 1 {
+		t.Errorf("tree = %+v", tree)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		runner *fakeRunner
+		want   string
+	}{
+		{
+			name:   "missing binary",
+			runner: &fakeRunner{err: &ExitError{Code: commandNotFound, Stderr: "mddiff: not found"}},
+			want:   "mddiff not found on nas; install mddiff there or put it on the PATH",
+		},
+		{
+			name:   "connection",
+			runner: &fakeRunner{err: &ExitError{Code: sshFailure, Stderr: "Connection refused"}},
+			want:   "connecting to nas: exit status 255: Connection refused",
+		},
+		{
+			name:   "remote failure",
+			runner: &fakeRunner{err: &ExitError{Code: 1, Stderr: "no such directory"}},
+			want:   "scanning nas:/media: exit status 1: no such directory",
+		},
+		{
+			name:   "bad manifest",
+			runner: &fakeRunner{out: "not json"},
+			want:   "reading manifest from nas: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scanner{Host: "nas", Runner: tt.runner, Binary: "mddiff"}
+			_, err := s.Scan("/media")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Scan error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := &Scanner{Host: "nas", Runner: &fakeRunner{out: cannedManifest}, Binary: "mddiff"}
+	tree, err := s.ScanContext(ctx, "/media")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanContext error = %v, want %v", err, context.Canceled)
+	}
+	if tree == nil || len(tree.Assets) != 0 {
+		t.Errorf("tree = %+v, want an empty partial tree", tree)
+	}
+}
+
+// blockingRunner runs until ctx is done and then fails the way a killed ssh
+// does.
+type blockingRunner struct{}
+
+func (blockingRunner) Run(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, &ExitError{Code: -1, Stderr: "signal: killed"}
+}
+
+func TestScanContextStopsRemoteScan(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s := &Scanner{Host: "nas", Runner: blockingRunner{}, Binary: "mddiff"}
+
+	start := time.Now()
+	_, err := s.ScanContext(ctx, "/media")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ScanContext error = %v, want %v rather than the runner's", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ScanContext took %v after the deadline", elapsed)
+	}
+}
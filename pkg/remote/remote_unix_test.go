@@ -0,0 +1,49 @@
+//go:build unix
+
+package remote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSSH puts an ssh on the PATH that runs the remote command line with sh,
+// the way sshd hands it to the user's shell.
+func fakeSSH(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := `#!/bin/sh
+while [ "$1" != "--" ]; do shift; done
+shift 2
+exec sh -c "$*"
+`
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o700); err != nil { // #nosec G306 -- test script
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSSHRunnerQuoting(t *testing.T) {
+	fakeSSH(t)
+	marker := filepath.Join(t.TempDir(), "ran")
+	for _, arg := range []string{
+		"/srv/My Movies",
+		"/srv/it's here",
+		"/srv/a; touch " + marker,
+		"/srv/$(touch " + marker + ")",
+		"/srv/`touch " + marker + "`",
+	} {
+		out, err := SSHRunner{}.Run(context.Background(), "nas", "printf", "%s", arg)
+		if err != nil {
+			t.Fatalf("Run(%q): %v", arg, err)
+		}
+		if string(out) != arg {
+			t.Errorf("remote command got %q, want %q", out, arg)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("a metacharacter in an argument ran a command on the remote host")
+	}
+}
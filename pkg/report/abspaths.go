@@ -0,0 +1,41 @@
+package report
+
+import (
+	"path/filepath"
+
+	"mddiff/pkg/domain"
+)
+
+// AbsolutePaths returns a copy of r with every path joined to the root it is
+// relative to, so items from several reports can't be confused. Extra items,
+// empty directories only in the target, and the new path of a rename or move
+// are under the target; all other paths are under the source.
+func AbsolutePaths(r *domain.DiffReport) *domain.DiffReport {
+	out := *r
+	out.Items = make([]domain.DiffItem, len(r.Items))
+	for i, item := range r.Items {
+		root := r.SourceDir
+		if item.Type == domain.Extra || item.Reason == domain.EmptyDirInTarget {
+			root = r.TargetDir
+		}
+		item.Path = filepath.Join(root, item.Path)
+		if item.NewPath != "" {
+			item.NewPath = filepath.Join(r.TargetDir, item.NewPath)
+		}
+		out.Items[i] = item
+	}
+
+	if r.Duplicates != nil {
+		out.Duplicates = make([]domain.Duplicate, len(r.Duplicates))
+		for i, d := range r.Duplicates {
+			root := r.SourceDir
+			if d.Side == "target" {
+				root = r.TargetDir
+			}
+			d.Path = filepath.Join(root, d.Path)
+			d.Original = filepath.Join(root, d.Original)
+			out.Duplicates[i] = d
+		}
+	}
+	return &out
+}
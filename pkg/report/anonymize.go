@@ -0,0 +1,82 @@
+package report
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+// Anonymizer replaces the names in a report with aliases so it can be shared
+// without revealing the directory structure's names. Each path component maps
+// to the same alias for the lifetime of the Anonymizer, so items in the same
+// directory still share a parent, and extensions are kept. Aliases are keyed
+// with a random secret, so they can't be reversed by hashing guessed names
+// and differ between runs.
+type Anonymizer struct {
+	key []byte
+}
+
+// NewAnonymizer returns an Anonymizer with a fresh random key.
+func NewAnonymizer() (*Anonymizer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Anonymizer{key: key}, nil
+}
+
+// Path returns the alias for a relative path.
+func (a *Anonymizer) Path(path string) string {
+	if path == "" {
+		return ""
+	}
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		parts[i] = a.component(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (a *Anonymizer) component(name string) string {
+	if name == "." || name == ".." {
+		return name
+	}
+	ext := path.Ext(name)
+	mac := hmac.New(sha256.New, a.key)
+	_, _ = mac.Write([]byte(strings.TrimSuffix(name, ext)))
+	return hex.EncodeToString(mac.Sum(nil))[:12] + ext
+}
+
+// Report returns a copy of r with every path replaced by its alias and the
+// roots replaced by "source" and "target". Fields that could reveal names or
+// content, such as editor links and text previews, are dropped. Counts are
+// left as they are.
+func (a *Anonymizer) Report(r *domain.DiffReport) *domain.DiffReport {
+	out := *r
+	out.SourceDir = "source"
+	out.TargetDir = "target"
+
+	out.Items = make([]domain.DiffItem, len(r.Items))
+	for i, item := range r.Items {
+		item.Path = a.Path(item.Path)
+		item.NewPath = a.Path(item.NewPath)
+		item.Link = ""
+		item.Preview = ""
+		out.Items[i] = item
+	}
+
+	if r.Duplicates != nil {
+		out.Duplicates = make([]domain.Duplicate, len(r.Duplicates))
+		for i, d := range r.Duplicates {
+			d.Path = a.Path(d.Path)
+			d.Original = a.Path(d.Original)
+			out.Duplicates[i] = d
+		}
+	}
+	return &out
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestAnonymizerPath(t *testing.T) {
+	a, err := NewAnonymizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep1, ep2 := a.Path("Show/Season 1/ep1.mkv"), a.Path("Show/Season 1/ep2.mkv")
+	if ep1 != a.Path("Show/Season 1/ep1.mkv") {
+		t.Errorf("aliases for the same path differ: %s, %s", ep1, a.Path("Show/Season 1/ep1.mkv"))
+	}
+	dir1, dir2 := ep1[:strings.LastIndex(ep1, "/")], ep2[:strings.LastIndex(ep2, "/")]
+	if dir1 != dir2 || ep1 == ep2 {
+		t.Errorf("aliases %s and %s don't keep the shared directory", ep1, ep2)
+	}
+	if strings.Contains(ep1, "Show") || strings.Contains(ep1, "Season") || strings.Contains(ep1, "ep1") {
+		t.Errorf("alias %s reveals a name", ep1)
+	}
+	if !strings.HasSuffix(ep1, ".mkv") || strings.Count(ep1, "/") != 2 {
+		t.Errorf("alias %s doesn't keep the extension and depth", ep1)
+	}
+	if got := a.Path(""); got != "" {
+		t.Errorf("Path(\"\") = %q", got)
+	}
+	if got := a.Path("../a.mkv"); !strings.HasPrefix(got, "../") {
+		t.Errorf("Path(../a.mkv) = %q, want .. kept", got)
+	}
+
+	// Another Anonymizer uses another key.
+	b, err := NewAnonymizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Path("Show/Season 1/ep1.mkv") == ep1 {
+		t.Error("two Anonymizers give the same alias")
+	}
+}
+
+func TestAnonymizerReport(t *testing.T) {
+	a, err := NewAnonymizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := sampleReport()
+	r.Items = append(r.Items, domain.DiffItem{
+		Type: domain.Renamed, Path: "show/ep2.mkv", NewPath: "show/ep02.mkv",
+		Link: "vscode://file/src/show/ep2.mkv", Preview: "-secret\n",
+	})
+	r.Duplicates = []domain.Duplicate{{Side: "source", Path: "show/ep1 (1).mkv", Original: "show/ep1.mkv"}}
+
+	out := a.Report(r)
+	if out.SourceDir != "source" || out.TargetDir != "target" {
+		t.Errorf("roots = %s, %s", out.SourceDir, out.TargetDir)
+	}
+	if out.Summary != r.Summary || len(out.Items) != len(r.Items) {
+		t.Errorf("summary or item count changed: %+v", out)
+	}
+	for i, item := range out.Items {
+		if item.Path != a.Path(r.Items[i].Path) || item.NewPath != a.Path(r.Items[i].NewPath) {
+			t.Errorf("item %d = %+v, want its paths aliased", i, item)
+		}
+		if item.Type != r.Items[i].Type || item.SrcSize != r.Items[i].SrcSize {
+			t.Errorf("item %d = %+v, want only paths changed", i, item)
+		}
+	}
+	if renamed := out.Items[3]; renamed.Link != "" || renamed.Preview != "" {
+		t.Errorf("renamed item = %+v, want no link or preview", renamed)
+	}
+	if d := out.Duplicates[0]; d.Original != out.Items[2].Path || d.Path == r.Duplicates[0].Path {
+		t.Errorf("duplicate = %+v, want aliased paths matching the items", d)
+	}
+	// The original report is untouched.
+	if r.Items[0].Path != "new.mkv" || r.Items[3].Link == "" || r.Duplicates[0].Path != "show/ep1 (1).mkv" {
+		t.Errorf("Report modified its input: %+v", r)
+	}
+}
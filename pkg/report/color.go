@@ -0,0 +1,68 @@
+package report
+
+import (
+	"io"
+	"os"
+
+	"mddiff/pkg/domain"
+)
+
+// ColorMode controls whether reporters emit ANSI color codes.
+type ColorMode string
+
+// Supported color modes.
+const (
+	// ColorAuto colors output only when it is written to a terminal.
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+const ansiReset = "\x1b[0m"
+
+var typeColors = map[domain.DiffType]string{
+	domain.Missing:  "\x1b[31m", // red
+	domain.Modified: "\x1b[33m", // yellow
+	domain.Extra:    "\x1b[32m", // green
+	domain.Renamed:  "\x1b[36m", // cyan
+	domain.Moved:    "\x1b[36m", // cyan
+	// Extension changes are modifications too.
+	domain.ExtChanged: "\x1b[33m",   // yellow
+	domain.Matched:    "\x1b[2;32m", // dim green
+	domain.EmptyDir:   "\x1b[35m",   // magenta
+}
+
+// enabled reports whether output written to w should be colored. Files,
+// pipes and buffers are never colored unless the mode is ColorAlways.
+func (m ColorMode) enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		return isTerminal(w)
+	}
+}
+
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in the color for t.
+func colorize(t domain.DiffType, s string) string {
+	c, ok := typeColors[t]
+	if !ok {
+		return s
+	}
+	return c + s + ansiReset
+}
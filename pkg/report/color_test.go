@@ -0,0 +1,58 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestColorModeEnabled(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	tests := []struct {
+		name string
+		mode ColorMode
+		w    io.Writer
+		want bool
+	}{
+		{"auto to a buffer", ColorAuto, &bytes.Buffer{}, false},
+		{"auto to a file", ColorAuto, f, false},
+		{"always to a file", ColorAlways, f, true},
+		{"never to a buffer", ColorNever, &bytes.Buffer{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.enabled(tt.w); got != tt.want {
+			t.Errorf("%s: enabled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTableReporterColor(t *testing.T) {
+	plain := render(t, &TableReporter{Color: ColorAuto}, sampleReport())
+	if strings.Contains(plain, "\x1b[") {
+		t.Errorf("ColorAuto colored output to a buffer:\n%q", plain)
+	}
+
+	colored := render(t, &TableReporter{Color: ColorAlways}, sampleReport())
+	if !strings.Contains(colored, typeColors[domain.Missing]+"MISSING ") {
+		t.Errorf("ColorAlways didn't color the missing row:\n%q", colored)
+	}
+	// Colors are added after alignment, so stripping them gives the plain
+	// table back.
+	stripped := colored
+	for _, c := range typeColors {
+		stripped = strings.ReplaceAll(stripped, c, "")
+	}
+	if stripped = strings.ReplaceAll(stripped, ansiReset, ""); stripped != plain {
+		t.Errorf("colored table without escapes =\n%s\nwant\n%s", stripped, plain)
+	}
+}
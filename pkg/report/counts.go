@@ -0,0 +1,43 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"mddiff/pkg/domain"
+)
+
+// CountsReporter writes a single "MISSING=3 MODIFIED=1 EXTRA=5 RENAMED=0
+// TOTAL=9" line, for scripts that only need the number of each diff type.
+// Every type is always present, even when its count is zero, except MOVED,
+// EXT_CHANGED and EMPTY_DIR, which only appear when there are any, since
+// they are only reported on request. The counts come from the report's
+// summary, so they cover items hidden by --only and the path filters, and
+// TOTAL is their sum, counting each rename once.
+type CountsReporter struct {
+	// RenamesAsModified is set when the summary's TotalModified already
+	// includes the renames, so they aren't added to TOTAL again.
+	RenamesAsModified bool
+}
+
+// Report implements Reporter.
+func (r *CountsReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	s := report.Summary
+	total := s.TotalMissing + s.TotalModified + s.TotalExtra
+	if !r.RenamesAsModified {
+		total += s.TotalRenamed
+	}
+	line := fmt.Sprintf("MISSING=%d MODIFIED=%d EXTRA=%d RENAMED=%d",
+		s.TotalMissing, s.TotalModified, s.TotalExtra, s.TotalRenamed)
+	for _, c := range []struct {
+		name string
+		n    int
+	}{{"MOVED", s.TotalMoved}, {"EXT_CHANGED", s.TotalExtChanged}, {"EMPTY_DIR", s.TotalEmptyDirs}} {
+		if c.n > 0 {
+			line += fmt.Sprintf(" %s=%d", c.name, c.n)
+			total += c.n
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s TOTAL=%d\n", line, total)
+	return err
+}
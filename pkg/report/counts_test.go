@@ -0,0 +1,50 @@
+package report
+
+import (
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestCountsReporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary domain.Summary
+		want    string
+	}{
+		{"no differences", domain.Summary{TotalPaired: 5}, "MISSING=0 MODIFIED=0 EXTRA=0 RENAMED=0 TOTAL=0\n"},
+		{
+			"common types",
+			domain.Summary{TotalMissing: 3, TotalModified: 1, TotalExtra: 5},
+			"MISSING=3 MODIFIED=1 EXTRA=5 RENAMED=0 TOTAL=9\n",
+		},
+		{
+			"optional types",
+			domain.Summary{TotalRenamed: 2, TotalMoved: 1, TotalEmptyDirs: 4},
+			"MISSING=0 MODIFIED=0 EXTRA=0 RENAMED=2 MOVED=1 EMPTY_DIR=4 TOTAL=7\n",
+		},
+		{
+			"extension changes",
+			domain.Summary{TotalModified: 1, TotalExtChanged: 2},
+			"MISSING=0 MODIFIED=1 EXTRA=0 RENAMED=0 EXT_CHANGED=2 TOTAL=3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Items hidden from the report still count.
+			report := &domain.DiffReport{Summary: tt.summary}
+			if got := render(t, &CountsReporter{}, report); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountsReporterRenamesAsModified(t *testing.T) {
+	// A rename counted as a modification is already in TotalModified.
+	report := &domain.DiffReport{Summary: domain.Summary{TotalModified: 1, TotalRenamed: 1}}
+	want := "MISSING=0 MODIFIED=1 EXTRA=0 RENAMED=1 TOTAL=1\n"
+	if got := render(t, &CountsReporter{RenamesAsModified: true}, report); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"mddiff/pkg/domain"
+)
+
+// DirSummary counts the differences found directly inside one directory.
+type DirSummary struct {
+	Dir      string `json:"dir"`
+	Missing  int    `json:"missing"`
+	Modified int    `json:"modified"`
+	Extra    int    `json:"extra"`
+	Renamed  int    `json:"renamed"`
+	Total    int    `json:"total"`
+}
+
+// SummarizeDirs groups items by their parent directory. Only directories with
+// at least one difference are returned, ordered by total differences, most
+// first, then by path.
+func SummarizeDirs(items []domain.DiffItem) []DirSummary {
+	byDir := make(map[string]*DirSummary)
+	for _, item := range items {
+		dir := path.Dir(item.Path)
+		s, ok := byDir[dir]
+		if !ok {
+			s = &DirSummary{Dir: dir}
+			byDir[dir] = s
+		}
+		switch item.Type {
+		case domain.Missing:
+			s.Missing++
+		case domain.EmptyDir:
+			if item.Reason == domain.EmptyDirInTarget {
+				s.Extra++
+			} else {
+				s.Missing++
+			}
+		case domain.Modified, domain.ExtChanged:
+			s.Modified++
+		case domain.Extra:
+			s.Extra++
+		case domain.Renamed, domain.Moved:
+			s.Renamed++
+		default:
+			continue
+		}
+		s.Total++
+	}
+
+	dirs := make([]DirSummary, 0, len(byDir))
+	for _, s := range byDir {
+		if s.Total > 0 {
+			dirs = append(dirs, *s)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Total != dirs[j].Total {
+			return dirs[i].Total > dirs[j].Total
+		}
+		return dirs[i].Dir < dirs[j].Dir
+	})
+	return dirs
+}
+
+// ChangedDirsReporter lists only the directories that contain differences,
+// with per-directory counts, instead of every item.
+type ChangedDirsReporter struct {
+	// JSON writes the list as a JSON array instead of a table.
+	JSON bool
+}
+
+// Report implements Reporter.
+func (r *ChangedDirsReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	dirs := SummarizeDirs(report.Items)
+	if r.JSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(dirs)
+	}
+
+	if len(dirs) == 0 {
+		fmt.Fprintln(w, noItemsMessage(report))
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "TOTAL\tMISSING\tMODIFIED\tEXTRA\tRENAMED\t\tDIRECTORY")
+	for _, d := range dirs {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t\t%s\n", d.Total, d.Missing, d.Modified, d.Extra, d.Renamed, d.Dir)
+	}
+	return tw.Flush()
+}
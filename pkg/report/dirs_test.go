@@ -0,0 +1,70 @@
+package report
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestSummarizeDirs(t *testing.T) {
+	items := []domain.DiffItem{
+		{Type: domain.Missing, Path: "a/one.mkv"},
+		{Type: domain.Modified, Path: "a/two.mkv"},
+		{Type: domain.Extra, Path: "b/three.mkv"},
+		{Type: domain.Renamed, Path: "b/four.mkv", NewPath: "b/five.mkv"},
+		{Type: domain.ExtChanged, Path: "b/six.avi"},
+		{Type: domain.Extra, Path: "top.mkv"},
+		{Type: domain.EmptyDir, Path: "c/empty", Reason: domain.EmptyDirInTarget},
+		{Type: domain.Matched, Path: "d/same.mkv"},
+	}
+	want := []DirSummary{
+		{Dir: "b", Modified: 1, Extra: 1, Renamed: 1, Total: 3},
+		{Dir: "a", Missing: 1, Modified: 1, Total: 2},
+		{Dir: ".", Extra: 1, Total: 1},
+		{Dir: "c", Extra: 1, Total: 1},
+	}
+	if got := SummarizeDirs(items); !slices.Equal(got, want) {
+		t.Errorf("SummarizeDirs = %+v, want %+v", got, want)
+	}
+}
+
+func TestChangedDirsReporter(t *testing.T) {
+	out := render(t, &ChangedDirsReporter{}, sampleReport())
+	for _, want := range []string{"TOTAL  MISSING  MODIFIED  EXTRA  RENAMED", "  gone\n", "  show\n", "  .\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	var dirs []DirSummary
+	if err := json.Unmarshal([]byte(render(t, &ChangedDirsReporter{JSON: true}, sampleReport())), &dirs); err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 3 {
+		t.Errorf("JSON lists %+v, want 3 directories", dirs)
+	}
+
+	empty := &domain.DiffReport{}
+	if out := render(t, &ChangedDirsReporter{}, empty); out != "No differences found.\n" {
+		t.Errorf("output without items = %q", out)
+	}
+	if out := render(t, &ChangedDirsReporter{JSON: true}, empty); out != "[]\n" {
+		t.Errorf("JSON without items = %q, want an empty array", out)
+	}
+}
+
+func TestNewReporterChangedDirs(t *testing.T) {
+	for _, format := range []string{"human", "table", "json"} {
+		if _, err := NewReporter(format, Options{ChangedDirs: true}); err != nil {
+			t.Errorf("NewReporter(%s) with ChangedDirs: %v", format, err)
+		}
+	}
+	for _, format := range []string{"csv", "html", "junit", "sarif", "tree", "counts", "ndjson"} {
+		if _, err := NewReporter(format, Options{ChangedDirs: true}); err == nil {
+			t.Errorf("NewReporter(%s) with ChangedDirs succeeded", format)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"mddiff/pkg/domain"
+)
+
+// WriteDuplicateGroups writes the groups found in root as text, one group per
+// block with a closing summary, or as JSON when asJSON is set.
+func WriteDuplicateGroups(w io.Writer, root string, groups []domain.DuplicateGroup, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Root   string                  `json:"root"`
+			Groups []domain.DuplicateGroup `json:"groups"`
+		}{root, groups})
+	}
+
+	fmt.Fprintf(w, "Root: %s\n\n", root)
+	if len(groups) == 0 {
+		fmt.Fprintln(w, "No duplicates found.")
+		return nil
+	}
+	redundant, wasted := 0, int64(0)
+	for _, g := range groups {
+		label := "same size"
+		if g.Hash != "" {
+			label = "sha256 " + g.Hash[:12]
+		}
+		fmt.Fprintf(w, "%s, %d files (%s):\n", formatBytes(g.Size), len(g.Paths), label)
+		for _, p := range g.Paths {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+		fmt.Fprintln(w)
+		redundant += len(g.Paths) - 1
+		wasted += int64(len(g.Paths)-1) * g.Size
+	}
+	_, err := fmt.Fprintf(w, "Groups: %d  Redundant copies: %d (%s)\n", len(groups), redundant, formatBytes(wasted))
+	return err
+}
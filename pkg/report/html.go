@@ -0,0 +1,153 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"sort"
+
+	"mddiff/pkg/domain"
+)
+
+// HTMLReporter writes the report as a self-contained HTML page, with items
+// grouped into collapsible sections by diff type. It needs no external
+// stylesheets or scripts, so it can be attached to an email.
+type HTMLReporter struct {
+	// HumanReadable shows sizes in binary units instead of bytes.
+	HumanReadable bool
+	// SummaryOnly leaves out the item sections.
+	SummaryOnly bool
+	// KeepOrder lists the items of each section in the order of
+	// report.Items instead of sorting them by path.
+	KeepOrder bool
+}
+
+// htmlSection is one group of items in an HTML report.
+type htmlSection struct {
+	Title string
+	Class string
+	Items []domain.DiffItem
+}
+
+// htmlSectionOrder lists the sections in the order they are shown.
+var htmlSectionOrder = []struct {
+	typ   domain.DiffType
+	title string
+}{
+	{domain.Missing, "Missing"},
+	{domain.Modified, "Modified"},
+	{domain.ExtChanged, "Extension changed"},
+	{domain.Renamed, "Renamed"},
+	{domain.Moved, "Moved"},
+	{domain.EmptyDir, "Empty directories"},
+	{domain.Extra, "Extra"},
+	{domain.Matched, "Matched"},
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"details": details,
+	"bytes":   func(n int64) string { return bytesNote(n, formatBytes) },
+	"delta":   func(n int64) string { return bytesNote(n, formatDelta) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>mddiff: {{.Report.SourceDir}} vs {{.Report.TargetDir}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #ddd; }
+td.path { font-family: monospace; word-break: break-all; }
+summary { font-size: 1.2em; font-weight: bold; margin: 1em 0 0.5em; cursor: pointer; }
+.counts span { display: inline-block; margin-right: 1.5em; }
+.missing { color: #c0392b; }
+.modified { color: #b7950b; }
+.renamed { color: #2874a6; }
+.extra { color: #1e8449; }
+.matched { color: #7d8c7d; }
+.emptydir { color: #8e44ad; }
+.note { color: #666; font-style: italic; }
+pre { background: #f6f6f6; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>mddiff report</h1>
+<p>Source: <code>{{.Report.SourceDir}}</code><br>Target: <code>{{.Report.TargetDir}}</code></p>
+{{with .Report.Summary -}}
+<p class="counts">
+<span class="missing">Missing: {{.TotalMissing}}{{bytes .MissingBytes}}</span>
+<span class="modified">Modified: {{.TotalModified}}{{delta .ModifiedBytes}}</span>
+<span class="extra">Extra: {{.TotalExtra}}{{bytes .ExtraBytes}}</span>
+<span class="renamed">Renamed: {{.TotalRenamed}}</span>
+{{if .TotalMoved}}<span class="renamed">Moved: {{.TotalMoved}}</span>
+{{end}}{{if .TotalExtChanged}}<span class="modified">Extension changed: {{.TotalExtChanged}}</span>
+{{end}}{{if .TotalEmptyDirs}}<span class="emptydir">Empty directories: {{.TotalEmptyDirs}}</span>
+{{end}}<span>Paired: {{.TotalPaired}}</span>
+{{if .TotalSkipped}}<span class="missing">Skipped (unreadable): {{.TotalSkipped}}</span>
+{{end}}
+</p>
+{{- end}}
+{{if .Report.Truncated}}<p class="note">Incomplete: the run stopped before every file was compared.</p>
+{{end}}
+{{- if not (or .Sections .Brief .Report.Truncated)}}<p>No differences found.</p>
+{{end}}
+{{- range .Sections}}
+<details open>
+<summary class="{{.Class}}">{{.Title}} ({{len .Items}})</summary>
+<table>
+<tr><th>Path</th><th>Details</th></tr>
+{{- range .Items}}
+<tr><td class="path">{{.Path}}</td><td>{{details . $.Human}}{{if .Preview}}<pre>{{.Preview}}</pre>{{end}}</td></tr>
+{{- end}}
+</table>
+</details>
+{{- end}}
+</body>
+</html>
+`))
+
+// Report implements Reporter.
+func (r *HTMLReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	byType := make(map[domain.DiffType][]domain.DiffItem)
+	for _, item := range report.Items {
+		byType[item.Type] = append(byType[item.Type], item)
+	}
+
+	// Sort each section by path, so the page doesn't depend on the order of
+	// report.Items, which callers building a report by hand may not keep
+	// stable.
+	var sections []htmlSection
+	for _, s := range htmlSectionOrder {
+		if items := byType[s.typ]; len(items) > 0 && !r.SummaryOnly {
+			if !r.KeepOrder {
+				sort.SliceStable(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+			}
+			sections = append(sections, htmlSection{Title: s.title, Class: classFor(s.typ), Items: items})
+		}
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		Report   *domain.DiffReport
+		Sections []htmlSection
+		Human    bool
+		// Brief suppresses "No differences found" when sections were left out.
+		Brief bool
+	}{report, sections, r.HumanReadable, r.SummaryOnly})
+}
+
+// classFor returns the CSS class that colors items of type t.
+func classFor(t domain.DiffType) string {
+	switch t {
+	case domain.Missing:
+		return "missing"
+	case domain.Extra:
+		return "extra"
+	case domain.Renamed, domain.Moved:
+		return "renamed"
+	case domain.Matched:
+		return "matched"
+	case domain.EmptyDir:
+		return "emptydir"
+	default:
+		return "modified"
+	}
+}
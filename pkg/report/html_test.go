@@ -0,0 +1,89 @@
+package report
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// htmlPath matches a path cell of the HTML report.
+var htmlPath = regexp.MustCompile(`<td class="path">([^<]*)</td>`)
+
+func TestHTMLReporter(t *testing.T) {
+	out := render(t, &HTMLReporter{}, sampleReport())
+	for _, want := range []string{
+		"<title>mddiff: /src vs /tgt</title>",
+		`<span class="missing">Missing: 1 (100 B)</span>`,
+		`<summary class="modified">Modified (1)</summary>`,
+		`<tr><td class="path">show/ep1.mkv</td><td>Size changed: &#43;50 bytes (200 -&gt; 250 bytes)</td></tr>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	out = render(t, &HTMLReporter{HumanReadable: true}, sampleReport())
+	if want := `<td class="path">new.mkv</td><td>Size: 300 B</td>`; !strings.Contains(out, want) {
+		t.Errorf("human-readable output is missing %q:\n%s", want, out)
+	}
+
+	out = render(t, &HTMLReporter{SummaryOnly: true}, sampleReport())
+	if strings.Contains(out, "<details") || strings.Contains(out, "No differences found") {
+		t.Errorf("SummaryOnly output has sections or claims no differences:\n%s", out)
+	}
+}
+
+func TestHTMLReporterOrder(t *testing.T) {
+	report := &domain.DiffReport{Items: []domain.DiffItem{
+		{Type: domain.Extra, Path: "z.mkv"},
+		{Type: domain.Missing, Path: "m.mkv"},
+		{Type: domain.Extra, Path: "a.mkv"},
+		{Type: domain.Modified, Path: "b.mkv"},
+		{Type: domain.Missing, Path: "c.mkv"},
+	}}
+	out := render(t, &HTMLReporter{}, report)
+
+	// Sections come in a fixed order and are each sorted by path.
+	want := []string{"c.mkv", "m.mkv", "b.mkv", "a.mkv", "z.mkv"}
+	if got := htmlPaths(out); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if again := render(t, &HTMLReporter{}, report); again != out {
+		t.Error("rendering the same report twice gave different output")
+	}
+
+	// KeepOrder leaves the order chosen with --sort or --reverse.
+	want = []string{"m.mkv", "c.mkv", "b.mkv", "z.mkv", "a.mkv"}
+	if got := htmlPaths(render(t, &HTMLReporter{KeepOrder: true}, report)); !slices.Equal(got, want) {
+		t.Errorf("with KeepOrder, paths = %v, want %v", got, want)
+	}
+}
+
+// htmlPaths returns the item paths in an HTML report, in page order.
+func htmlPaths(out string) []string {
+	var paths []string
+	for _, m := range htmlPath.FindAllStringSubmatch(out, -1) {
+		paths = append(paths, m[1])
+	}
+	return paths
+}
+
+func TestHTMLReporterEmptyDirs(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items,
+		domain.DiffItem{Type: domain.EmptyDir, Path: "extras", Reason: domain.EmptyDirInTarget},
+	)
+	report.Summary.TotalEmptyDirs = 1
+	out := render(t, &HTMLReporter{}, report)
+	for _, want := range []string{
+		`<span class="emptydir">Empty directories: 1</span>`,
+		`<summary class="emptydir">Empty directories (1)</summary>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
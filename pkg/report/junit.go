@@ -0,0 +1,98 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+// JUnitReporter writes the report as JUnit XML, so CI systems can show each
+// difference as a failed test case. Missing, modified, extension-changed,
+// renamed and moved items fail, as do empty directories missing from the
+// target; extra items, empty directories only in the target, and matched items
+// are skipped, or pass when Passing is set.
+type JUnitReporter struct {
+	// Passing reports extra and matched items as passing test cases instead of
+	// skipped ones.
+	Passing bool
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// Report implements Reporter.
+func (r *JUnitReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	suite := junitSuite{
+		Name:  report.SourceDir + " -> " + report.TargetDir,
+		Cases: make([]junitCase, 0, len(report.Items)),
+	}
+	for _, item := range report.Items {
+		c := junitCase{Name: item.Path, ClassName: strings.ToLower(string(item.Type))}
+		switch {
+		case item.Type == domain.Extra, item.Type == domain.Matched, item.Reason == domain.EmptyDirInTarget:
+			if !r.Passing {
+				c.Skipped = &junitSkipped{Message: string(item.Type)}
+				suite.Skipped++
+			}
+		default:
+			message := item.Reason
+			if message == "" {
+				message = string(item.Type)
+			}
+			c.Failure = &junitFailure{Message: message, Type: string(item.Type), Text: details(item, false)}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{
+		Name:     "mddiff",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Suites:   []junitSuite{suite},
+	}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
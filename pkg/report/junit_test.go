@@ -0,0 +1,72 @@
+package report
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// parseJUnit decodes out, checking that it is a well-formed JUnit document.
+func parseJUnit(t *testing.T, out string) junitSuites {
+	t.Helper()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("output doesn't start with the XML header:\n%s", out)
+	}
+	var got junitSuites
+	if err := xml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output doesn't parse: %v\n%s", err, out)
+	}
+	return got
+}
+
+func TestJUnitReporter(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items,
+		domain.DiffItem{Type: domain.Renamed, Path: "a & b.mkv", NewPath: "c.mkv"},
+		domain.DiffItem{Type: domain.EmptyDir, Path: "empty", Reason: domain.EmptyDirInTarget},
+	)
+	got := parseJUnit(t, render(t, &JUnitReporter{}, report))
+
+	if got.Name != "mddiff" || got.Tests != 5 || got.Failures != 3 || got.Skipped != 2 || len(got.Suites) != 1 {
+		t.Fatalf("testsuites = %+v", got)
+	}
+	suite := got.Suites[0]
+	if suite.Name != "/src -> /tgt" || suite.Tests != 5 || suite.Failures != 3 || suite.Skipped != 2 {
+		t.Errorf("testsuite = %+v", suite)
+	}
+	want := []struct {
+		name, class string
+		failure     string
+		skipped     bool
+	}{
+		{"new.mkv", "extra", "", true},
+		{"gone/old.mkv", "missing", "MISSING", false},
+		{"show/ep1.mkv", "modified", "Size changed: +50 bytes", false},
+		{"a & b.mkv", "renamed", "RENAMED", false},
+		{"empty", "empty_dir", "", true},
+	}
+	for i, w := range want {
+		c := suite.Cases[i]
+		if c.Name != w.name || c.ClassName != w.class {
+			t.Errorf("case %d = %s (%s), want %s (%s)", i, c.Name, c.ClassName, w.name, w.class)
+		}
+		if (c.Failure != nil) != (w.failure != "") || (c.Failure != nil && c.Failure.Message != w.failure) {
+			t.Errorf("case %s: failure = %+v, want message %q", c.Name, c.Failure, w.failure)
+		}
+		if (c.Skipped != nil) != w.skipped {
+			t.Errorf("case %s: skipped = %v, want %v", c.Name, c.Skipped != nil, w.skipped)
+		}
+	}
+}
+
+func TestJUnitReporterPassing(t *testing.T) {
+	got := parseJUnit(t, render(t, &JUnitReporter{Passing: true}, sampleReport()))
+	if got.Tests != 3 || got.Failures != 2 || got.Skipped != 0 {
+		t.Errorf("testsuites = %+v, want 3 tests, 2 failures and none skipped", got)
+	}
+	if c := got.Suites[0].Cases[0]; c.Name != "new.mkv" || c.Failure != nil || c.Skipped != nil {
+		t.Errorf("extra case = %+v, want it passing", c)
+	}
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// editorLinkers build a URI that opens an absolute, slash-separated path in an
+// editor, keyed by the name accepted by EditorLink.
+var editorLinkers = map[string]func(path string) string{
+	"vscode": func(path string) string {
+		return (&url.URL{Scheme: "vscode", Host: "file", Path: path}).String()
+	},
+	"vscodium": func(path string) string {
+		return (&url.URL{Scheme: "vscodium", Host: "file", Path: path}).String()
+	},
+	"idea": func(path string) string {
+		return "idea://open?file=" + url.QueryEscape(path)
+	},
+	"sublime": func(path string) string {
+		return "subl://open?url=" + url.QueryEscape("file://"+path)
+	},
+}
+
+// Editors returns the editor names accepted by EditorLink, sorted.
+func Editors() []string {
+	names := make([]string, 0, len(editorLinkers))
+	for name := range editorLinkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EditorLink returns a URI that opens the file at absPath in editor, e.g.
+// vscode://file/media/movies/a.mkv.
+func EditorLink(editor, absPath string) (string, error) {
+	link, ok := editorLinkers[editor]
+	if !ok {
+		return "", fmt.Errorf("unknown editor: %s (want %s)", editor, strings.Join(Editors(), "|"))
+	}
+	path := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(path, "/") {
+		// Windows drive paths, e.g. C:/media.
+		path = "/" + path
+	}
+	return link(path), nil
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditorLink(t *testing.T) {
+	tests := []struct {
+		editor, path, want string
+	}{
+		{"vscode", "/media/movies/a.mkv", "vscode://file/media/movies/a.mkv"},
+		{"vscodium", "/media/a b.mkv", "vscodium://file/media/a%20b.mkv"},
+		{"idea", "/media/a b.mkv", "idea://open?file=%2Fmedia%2Fa+b.mkv"},
+		{"sublime", "/media/a.mkv", "subl://open?url=file%3A%2F%2F%2Fmedia%2Fa.mkv"},
+	}
+	for _, tt := range tests {
+		got, err := EditorLink(tt.editor, tt.path)
+		if err != nil {
+			t.Errorf("EditorLink(%s, %s): %v", tt.editor, tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("EditorLink(%s, %s) = %s, want %s", tt.editor, tt.path, got, tt.want)
+		}
+	}
+
+	_, err := EditorLink("emacs", "/media/a.mkv")
+	if err == nil || !strings.Contains(err.Error(), "(want idea|sublime|vscode|vscodium)") {
+		t.Errorf("EditorLink(emacs) error = %v, want the known editors listed", err)
+	}
+}
+
+func TestTableReporterLinks(t *testing.T) {
+	report := sampleReport()
+	report.Items[1].Link = "vscode://file/src/gone/old.mkv"
+	out := render(t, &TableReporter{}, report)
+	for _, want := range []string{"  LINK\n", "  vscode://file/src/gone/old.mkv\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if out := render(t, &TableReporter{}, sampleReport()); strings.Contains(out, "LINK") {
+		t.Errorf("output without links has a LINK column:\n%s", out)
+	}
+}
@@ -0,0 +1,56 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+// MarkdownReporter writes the report as GitHub-flavored Markdown: a table of
+// the items followed by the summary, e.g. for a CI job summary or a pull
+// request comment.
+type MarkdownReporter struct {
+	// HumanReadable shows sizes in binary units instead of bytes.
+	HumanReadable bool
+	// SummaryOnly leaves out the item table.
+	SummaryOnly bool
+}
+
+// markdownEscaper escapes the characters that would end a table cell or be
+// read as formatting in a path or reason.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`,
+)
+
+// Report implements Reporter.
+func (r *MarkdownReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	fmt.Fprintf(w, "## mddiff report\n\nSource: %s  \nTarget: %s\n\n",
+		markdownEscaper.Replace(report.SourceDir), markdownEscaper.Replace(report.TargetDir))
+
+	if !r.SummaryOnly {
+		if len(report.Items) == 0 {
+			fmt.Fprintf(w, "%s\n\n", noItemsMessage(report))
+		} else {
+			fmt.Fprintln(w, "| Type | Path | Details |")
+			fmt.Fprintln(w, "| --- | --- | --- |")
+			for _, item := range report.Items {
+				fmt.Fprintf(w, "| %s | %s | %s |\n", item.Type,
+					markdownEscaper.Replace(item.Path), markdownEscaper.Replace(details(item, r.HumanReadable)))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	fmt.Fprintf(w, "**Summary:** %s\n", summaryLine(report.Summary))
+	if report.Truncated {
+		fmt.Fprintln(w, "\n**Incomplete:** the run stopped before every file was compared")
+	}
+	if s := report.Sampling; s != nil {
+		_, err := fmt.Fprintf(w, "\nSampled verification: hashed %d of %d matched files (%.1f%% coverage, seed %d)\n",
+			s.Sampled, s.Eligible, s.Coverage(), s.Seed)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestMarkdownReporter(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items, domain.DiffItem{Type: domain.Extra, Path: "a|b_*c*.mkv", TgtSize: 1})
+	out := render(t, &MarkdownReporter{}, report)
+	for _, want := range []string{
+		"Source: /src  \nTarget: /tgt\n",
+		"| Type | Path | Details |\n| --- | --- | --- |\n",
+		"| EXTRA | new.mkv | Size: 300 bytes |\n",
+		"| MISSING | gone/old.mkv | Size: 100 bytes |\n",
+		"| MODIFIED | show/ep1.mkv | Size changed: +50 bytes (200 -> 250 bytes) |\n",
+		`| EXTRA | a\|b\_\*c\*.mkv | Size: 1 bytes |` + "\n",
+		"**Summary:** Missing: 1 (100 B)  Modified: 1 (+50 B)  Extra: 1 (300 B)  Renamed: 0  Paired: 4\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	human := render(t, &MarkdownReporter{HumanReadable: true}, report)
+	if !strings.Contains(human, "| EXTRA | new.mkv | Size: 300 B |\n") {
+		t.Errorf("human-readable output has no formatted size:\n%s", human)
+	}
+}
+
+func TestMarkdownReporterSummaryOnly(t *testing.T) {
+	out := render(t, &MarkdownReporter{SummaryOnly: true}, sampleReport())
+	if strings.Contains(out, "| Type |") || !strings.Contains(out, "**Summary:** Missing: 1") {
+		t.Errorf("SummaryOnly output isn't just the summary:\n%s", out)
+	}
+
+	report := &domain.DiffReport{SourceDir: "/src", TargetDir: "/tgt", Truncated: true}
+	out = render(t, &MarkdownReporter{}, report)
+	for _, want := range []string{
+		"No differences found before the run stopped.\n",
+		"**Incomplete:** the run stopped before every file was compared\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Package report renders a DiffReport in the supported output formats.
+*/
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"mddiff/pkg/domain"
+)
+
+// Reporter writes a DiffReport to w.
+type Reporter interface {
+	Report(w io.Writer, report *domain.DiffReport) error
+}
+
+// Options configures the reporters returned by NewReporter.
+type Options struct {
+	Color ColorMode
+	// ChangedDirs lists only the directories containing differences instead
+	// of the full report, as a table or, for the json format, a JSON array.
+	// Other formats don't support it.
+	ChangedDirs bool
+	// HumanReadable shows sizes in binary units, e.g. "4.5 GiB", instead of
+	// bytes. JSON keeps the byte counts and adds formatted sizes alongside.
+	HumanReadable bool
+	// JUnitPassing makes the junit format report extra and matched items as
+	// passing test cases instead of skipped ones.
+	JUnitPassing bool
+	// SummaryOnly leaves out the items and writes just the summary. The csv,
+	// junit and sarif formats only list items and ignore it.
+	SummaryOnly bool
+	// KeepOrder keeps the order of report.Items in formats that would
+	// otherwise sort them, e.g. when the caller chose an order itself.
+	KeepOrder bool
+	// RenamesAsModified tells the counts format that renames are already
+	// included in Summary.TotalModified.
+	RenamesAsModified bool
+}
+
+// NewReporter returns the Reporter for the named format.
+func NewReporter(format string, opts Options) (Reporter, error) {
+	if opts.ChangedDirs {
+		switch format {
+		case "human", "table", "json":
+			return &ChangedDirsReporter{JSON: format == "json"}, nil
+		default:
+			return nil, fmt.Errorf("format %s doesn't support listing changed directories", format)
+		}
+	}
+
+	switch format {
+	case "human", "table":
+		return &TableReporter{Color: opts.Color, HumanReadable: opts.HumanReadable, SummaryOnly: opts.SummaryOnly}, nil
+	case "json":
+		return &JSONReporter{HumanReadable: opts.HumanReadable, SummaryOnly: opts.SummaryOnly}, nil
+	case "tree":
+		return &TreeReporter{Color: opts.Color, SummaryOnly: opts.SummaryOnly}, nil
+	case "counts":
+		return &CountsReporter{RenamesAsModified: opts.RenamesAsModified}, nil
+	case "csv":
+		return &CSVReporter{}, nil
+	case "html":
+		return &HTMLReporter{HumanReadable: opts.HumanReadable, SummaryOnly: opts.SummaryOnly, KeepOrder: opts.KeepOrder}, nil
+	case "markdown":
+		return &MarkdownReporter{HumanReadable: opts.HumanReadable, SummaryOnly: opts.SummaryOnly}, nil
+	case "ndjson":
+		return &NDJSONReporter{SummaryOnly: opts.SummaryOnly}, nil
+	case "junit":
+		return &JUnitReporter{Passing: opts.JUnitPassing}, nil
+	case "sarif":
+		return &SARIFReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// JSONReporter writes the report as indented JSON.
+type JSONReporter struct {
+	// HumanReadable adds src_size_human and tgt_size_human to each item, and
+	// formatted byte totals to the summary.
+	HumanReadable bool
+	// SummaryOnly writes the report without its items, duplicates and
+	// previews: the schema version, time, directories, summary and any
+	// sampling or truncation.
+	SummaryOnly bool
+}
+
+// humanItem is a DiffItem with its sizes also formatted for people. Like the
+// byte counts, they are left out when zero.
+type humanItem struct {
+	domain.DiffItem
+	SrcSizeHuman string `json:"src_size_human,omitempty"`
+	TgtSizeHuman string `json:"tgt_size_human,omitempty"`
+}
+
+// humanSummary is a Summary with its byte totals also formatted for people.
+type humanSummary struct {
+	domain.Summary
+	MissingBytesHuman  string `json:"missing_bytes_human"`
+	ExtraBytesHuman    string `json:"extra_bytes_human"`
+	ModifiedBytesHuman string `json:"modified_bytes_human"`
+}
+
+// reportSummary is what the json and ndjson formats write of a report when
+// only its summary is wanted. Summary is a domain.Summary or a humanSummary.
+type reportSummary struct {
+	SchemaVersion string           `json:"schema_version"`
+	GeneratedAt   time.Time        `json:"generated_at"`
+	SourceDir     string           `json:"source_dir"`
+	TargetDir     string           `json:"target_dir"`
+	Summary       any              `json:"summary"`
+	Sampling      *domain.Sampling `json:"sampling,omitempty"`
+	Truncated     bool             `json:"truncated,omitempty"`
+}
+
+func newReportSummary(report *domain.DiffReport, summary any) reportSummary {
+	return reportSummary{
+		report.SchemaVersion, report.GeneratedAt, report.SourceDir, report.TargetDir,
+		summary, report.Sampling, report.Truncated,
+	}
+}
+
+// Report implements Reporter.
+func (r *JSONReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	var summary any = report.Summary
+	if r.HumanReadable {
+		s := report.Summary
+		summary = humanSummary{
+			Summary:            s,
+			MissingBytesHuman:  formatBytes(s.MissingBytes),
+			ExtraBytesHuman:    formatBytes(s.ExtraBytes),
+			ModifiedBytesHuman: formatDelta(s.ModifiedBytes),
+		}
+	}
+	if r.SummaryOnly {
+		return enc.Encode(newReportSummary(report, summary))
+	}
+	if !r.HumanReadable {
+		return enc.Encode(report)
+	}
+	items := make([]humanItem, len(report.Items))
+	for i, item := range report.Items {
+		items[i] = humanItem{DiffItem: item}
+		if item.SrcSize != 0 {
+			items[i].SrcSizeHuman = formatBytes(item.SrcSize)
+		}
+		if item.TgtSize != 0 {
+			items[i].TgtSizeHuman = formatBytes(item.TgtSize)
+		}
+	}
+	// The outer Items and Summary hide the embedded report's.
+	return enc.Encode(struct {
+		*domain.DiffReport
+		Items   []humanItem `json:"items"`
+		Summary any         `json:"summary"`
+	}{report, items, summary})
+}
+
+// NDJSONReporter writes one JSON object per line: one per item, then a final
+// line holding the schema version, time, directories, summary and any
+// sampling or truncation. Each
+// line is written as soon as it is encoded, so consumers can process a large
+// report as a stream.
+type NDJSONReporter struct {
+	// SummaryOnly writes only the final line.
+	SummaryOnly bool
+}
+
+// Report implements Reporter.
+func (r *NDJSONReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	enc := json.NewEncoder(w)
+	for _, item := range report.Items {
+		if r.SummaryOnly {
+			break
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(newReportSummary(report, report.Summary))
+}
+
+// CSVReporter writes one row per item with a header row, for spreadsheets.
+type CSVReporter struct{}
+
+// Report implements Reporter.
+func (r *CSVReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"type", "path", "reason", "src_size", "tgt_size"}); err != nil {
+		return err
+	}
+	for _, item := range report.Items {
+		reason := item.Reason
+		if item.Type == domain.Renamed || item.Type == domain.Moved {
+			reason = details(item, false)
+		}
+		row := []string{
+			string(item.Type),
+			item.Path,
+			reason,
+			strconv.FormatInt(item.SrcSize, 10),
+			strconv.FormatInt(item.TgtSize, 10),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteCombinedJSON writes the reports from a multi-pair run as a single
+// indented JSON array.
+func WriteCombinedJSON(w io.Writer, reports []domain.PairReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+// TableReporter writes the report as an aligned, human-readable table.
+type TableReporter struct {
+	// Color decides whether rows are colored by diff type. In ColorAuto mode
+	// rows are only colored when w is a terminal.
+	Color ColorMode
+	// HumanReadable shows sizes in binary units instead of bytes.
+	HumanReadable bool
+	// SummaryOnly leaves out the items, previews and duplicates.
+	SummaryOnly bool
+}
+
+// Report implements Reporter.
+func (r *TableReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	fmt.Fprintf(w, "Source: %s\nTarget: %s\n\n", report.SourceDir, report.TargetDir)
+
+	if !r.SummaryOnly {
+		if len(report.Items) == 0 {
+			fmt.Fprintln(w, noItemsMessage(report))
+		} else if err := r.writeItems(w, report.Items); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, summaryLine(report.Summary))
+	if report.Truncated {
+		fmt.Fprintln(w, "Incomplete: the run stopped before every file was compared")
+	}
+	if s := report.Sampling; s != nil {
+		fmt.Fprintf(w, "Sampled verification: hashed %d of %d matched files (%.1f%% coverage, seed %d)\n",
+			s.Sampled, s.Eligible, s.Coverage(), s.Seed)
+	}
+	if r.SummaryOnly {
+		return nil
+	}
+
+	for _, item := range report.Items {
+		if item.Preview == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", item.Path)
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(item.Preview, "\n"), "\n") {
+			fmt.Fprint(w, "  "+line)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Duplicates) > 0 {
+		fmt.Fprintln(w, "\nPotential duplicates:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, d := range report.Duplicates {
+			fmt.Fprintf(tw, "  %s\t%s\tcopy of %s\n", d.Side, d.Path, d.Original)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeItems writes the item table. Rows are aligned before coloring so the
+// escape codes don't throw off the column widths.
+func (r *TableReporter) writeItems(w io.Writer, items []domain.DiffItem) error {
+	withLinks := false
+	for _, item := range items {
+		if item.Link != "" {
+			withLinks = true
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if withLinks {
+		fmt.Fprintln(tw, "TYPE\tPATH\tDETAILS\tLINK")
+	} else {
+		fmt.Fprintln(tw, "TYPE\tPATH\tDETAILS")
+	}
+	for _, item := range items {
+		if withLinks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.Type, item.Path, details(item, r.HumanReadable), item.Link)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", item.Type, item.Path, details(item, r.HumanReadable))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	color := r.Color.enabled(w)
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, line := range lines {
+		if color && i > 0 && i <= len(items) {
+			line = colorize(items[i-1].Type, strings.TrimSuffix(line, "\n")) + "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summaryLine formats the summary counts. Extension changes and empty
+// directories are only shown when there are any, since they are only counted
+// separately on request, and so are skipped paths.
+func summaryLine(s domain.Summary) string {
+	line := fmt.Sprintf("Missing: %d%s  Modified: %d%s  Extra: %d%s  Renamed: %d  Paired: %d",
+		s.TotalMissing, bytesNote(s.MissingBytes, formatBytes),
+		s.TotalModified, bytesNote(s.ModifiedBytes, formatDelta),
+		s.TotalExtra, bytesNote(s.ExtraBytes, formatBytes),
+		s.TotalRenamed, s.TotalPaired)
+	if s.TotalMoved > 0 {
+		line += fmt.Sprintf("  Moved: %d", s.TotalMoved)
+	}
+	if s.TotalExtChanged > 0 {
+		line += fmt.Sprintf("  Extension changed: %d", s.TotalExtChanged)
+	}
+	if s.TotalEmptyDirs > 0 {
+		line += fmt.Sprintf("  Empty directories: %d", s.TotalEmptyDirs)
+	}
+	if s.TotalSkipped > 0 {
+		line += fmt.Sprintf("  Skipped: %d", s.TotalSkipped)
+	}
+	return line
+}
+
+// details describes item for the DETAILS column, with sizes in binary units
+// when human is set.
+func details(item domain.DiffItem, human bool) string {
+	size := func(n int64) string { return fmt.Sprintf("%d bytes", n) }
+	if human {
+		size = formatBytes
+	}
+	switch item.Type {
+	case domain.Matched:
+		if item.Reason != "" {
+			return fmt.Sprintf("Size: %s (%s)", size(item.SrcSize), item.Reason)
+		}
+		return "Size: " + size(item.SrcSize)
+	case domain.Missing:
+		return "Size: " + size(item.SrcSize)
+	case domain.Extra:
+		return "Size: " + size(item.TgtSize)
+	case domain.Renamed:
+		return "Renamed to " + item.NewPath
+	case domain.Moved:
+		return "Moved to " + item.NewPath
+	case domain.EmptyDir:
+		return item.Reason
+	default:
+		sizes := fmt.Sprintf("%d -> %d bytes", item.SrcSize, item.TgtSize)
+		if human {
+			sizes = size(item.SrcSize) + " -> " + size(item.TgtSize)
+		}
+		// A size change's reason already gives the delta.
+		if item.SizeDelta == 0 || strings.HasPrefix(item.Reason, domain.SizeChangedReason) {
+			return fmt.Sprintf("%s (%s)", item.Reason, sizes)
+		}
+		return fmt.Sprintf("%s (%s, %s)", item.Reason, sizes, formatDelta(item.SizeDelta))
+	}
+}
+
+// noItemsMessage is written in place of the items of a report that has none.
+// A truncated report may have stopped before finding any.
+func noItemsMessage(report *domain.DiffReport) string {
+	if report.Truncated {
+		return "No differences found before the run stopped."
+	}
+	return "No differences found."
+}
+
+// formatDelta formats a signed size difference with a binary unit, such as
+// "+1.5 MiB" or "-200 B".
+func formatDelta(n int64) string {
+	if n < 0 {
+		return "-" + formatBytes(-n)
+	}
+	return "+" + formatBytes(n)
+}
+
+// formatBytes formats a size in binary units, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, exp := float64(n)/unit, 0
+	for v >= unit && exp < 4 {
+		v /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", v, "KMGTP"[exp])
+}
+
+// bytesNote formats n with format as a parenthesized suffix for a summary
+// count, or returns "" when n is zero.
+func bytesNote(n int64, format func(int64) string) string {
+	if n == 0 {
+		return ""
+	}
+	return " (" + format(n) + ")"
+}
@@ -0,0 +1,323 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"mddiff/pkg/domain"
+)
+
+// sampleReport returns a report with one item of each common type.
+func sampleReport() *domain.DiffReport {
+	return &domain.DiffReport{
+		SchemaVersion: domain.ReportSchemaVersion,
+		GeneratedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		SourceDir:     "/src",
+		TargetDir:     "/tgt",
+		Items: []domain.DiffItem{
+			{Type: domain.Extra, Path: "new.mkv", TgtSize: 300},
+			{Type: domain.Missing, Path: "gone/old.mkv", SrcSize: 100},
+			{
+				Type: domain.Modified, Path: "show/ep1.mkv", Reason: "Size changed: +50 bytes",
+				SrcSize: 200, TgtSize: 250, SizeDelta: 50,
+			},
+		},
+		Summary: domain.Summary{
+			TotalMissing: 1, TotalModified: 1, TotalExtra: 1, TotalPaired: 4,
+			MissingBytes: 100, ExtraBytes: 300, ModifiedBytes: 50,
+		},
+	}
+}
+
+// render writes report with r and returns the output.
+func render(t *testing.T, r Reporter, report *domain.DiffReport) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.Report(&buf, report); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	return buf.String()
+}
+
+func TestNewReporter(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   Options
+		want   Reporter
+	}{
+		{"human", Options{}, &TableReporter{}},
+		{"table", Options{HumanReadable: true}, &TableReporter{HumanReadable: true}},
+		{"json", Options{}, &JSONReporter{}},
+		{"json", Options{SummaryOnly: true, HumanReadable: true}, &JSONReporter{SummaryOnly: true, HumanReadable: true}},
+		{"ndjson", Options{}, &NDJSONReporter{}},
+		{"csv", Options{}, &CSVReporter{}},
+		{"tree", Options{}, &TreeReporter{}},
+		{"counts", Options{}, &CountsReporter{}},
+		{"counts", Options{RenamesAsModified: true}, &CountsReporter{RenamesAsModified: true}},
+		{"html", Options{}, &HTMLReporter{}},
+		{"html", Options{KeepOrder: true}, &HTMLReporter{KeepOrder: true}},
+		{"markdown", Options{SummaryOnly: true}, &MarkdownReporter{SummaryOnly: true}},
+		{"junit", Options{JUnitPassing: true}, &JUnitReporter{Passing: true}},
+		{"sarif", Options{}, &SARIFReporter{}},
+	}
+	for _, tt := range tests {
+		got, err := NewReporter(tt.format, tt.opts)
+		if err != nil {
+			t.Errorf("NewReporter(%q): %v", tt.format, err)
+			continue
+		}
+		if fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", tt.want) {
+			t.Errorf("NewReporter(%q, %+v) = %#v, want %#v", tt.format, tt.opts, got, tt.want)
+		}
+	}
+
+	if _, err := NewReporter("xml", Options{}); err == nil {
+		t.Error("NewReporter(xml) succeeded")
+	}
+}
+
+func TestTableReporter(t *testing.T) {
+	out := render(t, &TableReporter{}, sampleReport())
+	for _, want := range []string{
+		"Source: /src\nTarget: /tgt\n",
+		"TYPE      PATH          DETAILS\n",
+		"EXTRA     new.mkv       Size: 300 bytes\n",
+		"MISSING   gone/old.mkv  Size: 100 bytes\n",
+		"MODIFIED  show/ep1.mkv  Size changed: +50 bytes (200 -> 250 bytes)\n",
+		"Missing: 1 (100 B)  Modified: 1 (+50 B)  Extra: 1 (300 B)  Renamed: 0  Paired: 4\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTableReporterHumanReadable(t *testing.T) {
+	report := sampleReport()
+	report.Items[0].TgtSize = 5 << 30
+	report.Items[2].SrcSize, report.Items[2].TgtSize = 1023, 1536
+	out := render(t, &TableReporter{HumanReadable: true}, report)
+	for _, want := range []string{
+		"EXTRA     new.mkv       Size: 5.0 GiB\n",
+		"MISSING   gone/old.mkv  Size: 100 B\n",
+		"MODIFIED  show/ep1.mkv  Size changed: +50 bytes (1023 B -> 1.5 KiB)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTableReporterMatched(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items,
+		domain.DiffItem{Type: domain.Matched, Path: "a.mkv", SrcSize: 7, TgtSize: 7},
+		domain.DiffItem{Type: domain.Matched, Path: "b.mkv", SrcSize: 8, TgtSize: 8, Reason: "Not verified"},
+	)
+	out := render(t, &TableReporter{}, report)
+	for _, want := range []string{
+		"MATCHED   a.mkv         Size: 7 bytes\n",
+		"MATCHED   b.mkv         Size: 8 bytes (Not verified)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTableReporterExtChanged(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items, domain.DiffItem{
+		Type: domain.ExtChanged, Path: "remux.avi", Reason: "Extension changed: .avi -> .mkv", SrcSize: 5, TgtSize: 5,
+	})
+	report.Summary.TotalExtChanged = 1
+	out := render(t, &TableReporter{}, report)
+	for _, want := range []string{
+		"EXT_CHANGED  remux.avi     Extension changed: .avi -> .mkv",
+		"  Extension changed: 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTableReporterNoItems(t *testing.T) {
+	report := &domain.DiffReport{SourceDir: "/src", TargetDir: "/tgt"}
+	out := render(t, &TableReporter{}, report)
+	if !strings.Contains(out, "No differences found.\n") {
+		t.Errorf("output doesn't say there are no differences:\n%s", out)
+	}
+}
+
+func TestTableReporterTruncated(t *testing.T) {
+	report := &domain.DiffReport{SourceDir: "/src", TargetDir: "/tgt", Truncated: true}
+	out := render(t, &TableReporter{}, report)
+	for _, want := range []string{
+		"No differences found before the run stopped.\n",
+		"Incomplete: the run stopped before every file was compared\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "No differences found.") {
+		t.Errorf("truncated report claims there are no differences:\n%s", out)
+	}
+}
+
+func TestTableReporterSampling(t *testing.T) {
+	report := sampleReport()
+	report.Sampling = &domain.Sampling{Percent: 10, Seed: 7, Eligible: 40, Sampled: 4}
+	out := render(t, &TableReporter{}, report)
+	want := "Sampled verification: hashed 4 of 40 matched files (10.0% coverage, seed 7)\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("output is missing %q:\n%s", want, out)
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	report := sampleReport()
+	var got domain.DiffReport
+	if err := json.Unmarshal([]byte(render(t, &JSONReporter{}, report)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != 3 || got.Items[2].SizeDelta != 50 || got.Summary != report.Summary {
+		t.Errorf("decoded report = %+v, want %+v", got, *report)
+	}
+
+	plain := render(t, &JSONReporter{}, report)
+	if !strings.Contains(plain, `"total_paired": 4`) || strings.Contains(plain, "total_renamed") {
+		t.Errorf("summary should have total_paired and leave out total_renamed when zero:\n%s", plain)
+	}
+
+	out := render(t, &JSONReporter{HumanReadable: true}, report)
+	for _, want := range []string{`"tgt_size_human": "300 B"`, `"extra_bytes_human": "300 B"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("human-readable JSON is missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestJSONReporterSummaryOnly(t *testing.T) {
+	out := render(t, &JSONReporter{SummaryOnly: true}, sampleReport())
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["items"]; ok {
+		t.Errorf("SummaryOnly output has items:\n%s", out)
+	}
+	var summary domain.Summary
+	if err := json.Unmarshal(got["summary"], &summary); err != nil || summary != sampleReport().Summary {
+		t.Errorf("summary = %s, want %+v", got["summary"], sampleReport().Summary)
+	}
+	if !strings.HasPrefix(out, "{\n  \"schema_version\": ") || !strings.Contains(out, "\n  \"summary\": {\n    ") {
+		t.Errorf("SummaryOnly output isn't indented like the full report:\n%s", out)
+	}
+
+	human := render(t, &JSONReporter{SummaryOnly: true, HumanReadable: true}, sampleReport())
+	for _, want := range []string{`"missing_bytes_human": "100 B"`, `"modified_bytes_human": "+50 B"`} {
+		if !strings.Contains(human, want) {
+			t.Errorf("human-readable summary is missing %s:\n%s", want, human)
+		}
+	}
+}
+
+func TestNDJSONReporter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(render(t, &NDJSONReporter{}, sampleReport())), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 3 items and a summary:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	var item domain.DiffItem
+	if err := json.Unmarshal([]byte(lines[1]), &item); err != nil || item.Path != "gone/old.mkv" {
+		t.Errorf("second line = %s, want the missing item", lines[1])
+	}
+	var last struct {
+		SchemaVersion string         `json:"schema_version"`
+		SourceDir     string         `json:"source_dir"`
+		Summary       domain.Summary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.SchemaVersion != domain.ReportSchemaVersion || last.SourceDir != "/src" || last.Summary.TotalExtra != 1 {
+		t.Errorf("summary line = %s", lines[3])
+	}
+
+	summary := render(t, &NDJSONReporter{SummaryOnly: true}, sampleReport())
+	if strings.Count(summary, "\n") != 1 {
+		t.Errorf("SummaryOnly wrote more than the summary line:\n%s", summary)
+	}
+}
+
+func TestCSVReporter(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(render(t, &CSVReporter{}, sampleReport()))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"type", "path", "reason", "src_size", "tgt_size"},
+		{"EXTRA", "new.mkv", "", "0", "300"},
+		{"MISSING", "gone/old.mkv", "", "100", "0"},
+		{"MODIFIED", "show/ep1.mkv", "Size changed: +50 bytes", "200", "250"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %q, want %q", rows, want)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1, "1 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1 << 20, "1.0 MiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+	if got := formatDelta(-200); got != "-200 B" {
+		t.Errorf("formatDelta(-200) = %q, want -200 B", got)
+	}
+}
+
+func TestTableReporterEmptyDirs(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items,
+		domain.DiffItem{Type: domain.EmptyDir, Path: "extras", Reason: domain.EmptyDirInSource},
+	)
+	report.Summary.TotalEmptyDirs = 1
+	out := render(t, &TableReporter{}, report)
+	for _, want := range []string{
+		"EMPTY_DIR  extras        " + domain.EmptyDirInSource + "\n",
+		"  Empty directories: 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	colored := render(t, &TableReporter{Color: ColorAlways}, report)
+	if !strings.Contains(colored, typeColors[domain.EmptyDir]+"EMPTY_DIR ") {
+		t.Errorf("the empty directory row isn't colored:\n%q", colored)
+	}
+}
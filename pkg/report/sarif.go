@@ -0,0 +1,159 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+// SARIFReporter writes the report as a SARIF 2.1.0 log, so tools that ingest
+// static analysis results can show differences as findings. Each item is a
+// result whose rule is its diff type, located at its path under the source
+// or, for extra items, the target directory.
+type SARIFReporter struct{}
+
+// sarifRules describes each diff type, in the order rules are listed.
+var sarifRules = []struct {
+	typ   domain.DiffType
+	text  string
+	level string
+}{
+	{domain.Missing, "File is missing from the target", "error"},
+	{domain.Modified, "File differs between source and target", "error"},
+	{domain.ExtChanged, "File extension changed", "warning"},
+	{domain.Renamed, "File was renamed in the target", "warning"},
+	{domain.Moved, "File was moved to another directory in the target", "warning"},
+	{domain.EmptyDir, "Empty directory exists on only one side", "warning"},
+	{domain.Extra, "File exists only in the target", "note"},
+	{domain.Matched, "File is unchanged", "none"},
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool              sarifTool                   `json:"tool"`
+	OriginalURIBaseID map[string]sarifArtifactLoc `json:"originalUriBaseIds"`
+	Results           []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Report implements Reporter.
+func (r *SARIFReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: "mddiff"}},
+		OriginalURIBaseID: map[string]sarifArtifactLoc{
+			"SOURCE": {URI: dirURI(report.SourceDir)},
+			"TARGET": {URI: dirURI(report.TargetDir)},
+		},
+		Results: make([]sarifResult, 0, len(report.Items)),
+	}
+	levels := make(map[domain.DiffType]string, len(sarifRules))
+	for _, rule := range sarifRules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               string(rule.typ),
+			ShortDescription: sarifMessage{Text: rule.text},
+		})
+		levels[rule.typ] = rule.level
+	}
+
+	for _, item := range report.Items {
+		base := "SOURCE"
+		if item.Type == domain.Extra || item.Reason == domain.EmptyDirInTarget {
+			base = "TARGET"
+		}
+		message := item.Reason
+		if message == "" {
+			message = details(item, false)
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  string(item.Type),
+			Level:   levels[item.Type],
+			Message: sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLoc{URI: pathURI(item.Path), URIBaseID: base},
+			}}},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// pathURI escapes a relative OS path as a relative URI reference.
+func pathURI(p string) string {
+	u := (&url.URL{Path: filepath.ToSlash(p)}).String()
+	// A colon in the first segment would otherwise read as a scheme.
+	if first, _, _ := strings.Cut(u, "/"); strings.Contains(first, ":") {
+		u = "./" + u
+	}
+	return u
+}
+
+// dirURI returns a file URI for dir ending in a slash, as SARIF requires for
+// base URIs. Remote or baseline roots that aren't absolute paths are escaped
+// as relative references.
+func dirURI(dir string) string {
+	p := filepath.ToSlash(dir)
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	if !strings.HasPrefix(p, "/") {
+		if filepath.IsAbs(dir) {
+			// A Windows path such as C:/media/.
+			p = "/" + p
+		} else {
+			return pathURI(p)
+		}
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+
+	"mddiff/pkg/domain"
+)
+
+// SyslogTag identifies mddiff's messages in the system log.
+const SyslogTag = "mddiff"
+
+// ErrSyslogUnsupported is returned by DialSyslog on platforms without syslog.
+var ErrSyslogUnsupported = errors.New("syslog is not supported on this platform")
+
+// SyslogWriter is the subset of *log/syslog.Writer used to send reports.
+type SyslogWriter interface {
+	Notice(m string) error
+	Warning(m string) error
+	Info(m string) error
+	Close() error
+}
+
+// SendToSyslog writes a one-line summary of r at notice level. When withItems
+// is set, each missing or modified item is also sent at warning level and any
+// other item at info level.
+func SendToSyslog(w SyslogWriter, r *domain.DiffReport, withItems bool) error {
+	if withItems {
+		for _, item := range r.Items {
+			msg := fmt.Sprintf("%s %s", item.Type, item.Path)
+			if item.Reason != "" {
+				msg += ": " + item.Reason
+			}
+
+			var err error
+			switch item.Type {
+			case domain.Missing, domain.Modified, domain.ExtChanged:
+				err = w.Warning(msg)
+			case domain.EmptyDir:
+				if item.Reason == domain.EmptyDirInSource {
+					err = w.Warning(msg)
+				} else {
+					err = w.Info(msg)
+				}
+			default:
+				err = w.Info(msg)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.Notice(fmt.Sprintf("%s -> %s: missing=%d modified=%d extra=%d renamed=%d items=%d",
+		r.SourceDir, r.TargetDir, r.Summary.TotalMissing, r.Summary.TotalModified, r.Summary.TotalExtra,
+		r.Summary.TotalRenamed, r.Differences()))
+}
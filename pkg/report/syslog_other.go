@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package report
+
+// DialSyslog always fails with ErrSyslogUnsupported on this platform.
+func DialSyslog() (SyslogWriter, error) {
+	return nil, ErrSyslogUnsupported
+}
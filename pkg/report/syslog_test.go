@@ -0,0 +1,83 @@
+package report
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+// fakeSyslog records the messages sent to it, prefixed by their level.
+type fakeSyslog struct {
+	messages []string
+	err      error
+}
+
+func (f *fakeSyslog) send(level, m string) error {
+	f.messages = append(f.messages, level+" "+m)
+	return f.err
+}
+
+func (f *fakeSyslog) Notice(m string) error  { return f.send("notice", m) }
+func (f *fakeSyslog) Warning(m string) error { return f.send("warning", m) }
+func (f *fakeSyslog) Info(m string) error    { return f.send("info", m) }
+func (f *fakeSyslog) Close() error           { return nil }
+
+func TestSendToSyslog(t *testing.T) {
+	summary := "notice /src -> /tgt: missing=1 modified=1 extra=1 renamed=0 items=3"
+	tests := []struct {
+		name      string
+		withItems bool
+		want      []string
+	}{
+		{"summary only", false, []string{summary}},
+		{
+			"with items", true,
+			[]string{
+				"info EXTRA new.mkv",
+				"warning MISSING gone/old.mkv",
+				"warning MODIFIED show/ep1.mkv: Size changed: +50 bytes",
+				summary,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &fakeSyslog{}
+			if err := SendToSyslog(w, sampleReport(), tt.withItems); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(w.messages, tt.want) {
+				t.Errorf("messages = %q, want %q", w.messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendToSyslogEmptyDirs(t *testing.T) {
+	r := &domain.DiffReport{Items: []domain.DiffItem{
+		{Type: domain.EmptyDir, Path: "a", Reason: domain.EmptyDirInSource},
+		{Type: domain.EmptyDir, Path: "b", Reason: domain.EmptyDirInTarget},
+	}}
+	w := &fakeSyslog{}
+	if err := SendToSyslog(w, r, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.messages) != 3 || !strings.HasPrefix(w.messages[0], "warning EMPTY_DIR a:") ||
+		!strings.HasPrefix(w.messages[1], "info EMPTY_DIR b:") {
+		t.Errorf("messages = %q, want the source-only directory at warning level", w.messages)
+	}
+}
+
+func TestSendToSyslogError(t *testing.T) {
+	errDown := errors.New("down")
+	w := &fakeSyslog{err: errDown}
+	if err := SendToSyslog(w, sampleReport(), true); !errors.Is(err, errDown) {
+		t.Errorf("SendToSyslog = %v, want %v", err, errDown)
+	}
+	if len(w.messages) != 1 {
+		t.Errorf("sent %q after the first error", w.messages)
+	}
+}
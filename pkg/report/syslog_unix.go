@@ -0,0 +1,10 @@
+//go:build !windows && !plan9
+
+package report
+
+import "log/syslog"
+
+// DialSyslog connects to the local syslog daemon using the user facility.
+func DialSyslog() (SyslogWriter, error) {
+	return syslog.New(syslog.LOG_USER|syslog.LOG_NOTICE, SyslogTag)
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+var treeMarkers = map[domain.DiffType]string{
+	domain.Missing:  "-",
+	domain.Extra:    "+",
+	domain.Modified: "~",
+	domain.Renamed:  ">",
+	domain.Moved:    ">",
+	// Extension changes are modifications too.
+	domain.ExtChanged: "~",
+	domain.Matched:    "=",
+}
+
+// emptyDirMarkers mark an EMPTY_DIR item like the missing or extra file it
+// would otherwise be, by its side.
+var emptyDirMarkers = map[string]string{
+	domain.EmptyDirInSource: treeMarkers[domain.Missing],
+	domain.EmptyDirInTarget: treeMarkers[domain.Extra],
+}
+
+// TreeReporter draws the differences as an indented directory tree, like
+// tree(1). Each differing path is prefixed with a marker for its type; the
+// directories leading to it are shown unmarked for context.
+type TreeReporter struct {
+	// Color decides whether marked nodes are colored by diff type. In
+	// ColorAuto mode nodes are only colored when w is a terminal.
+	Color ColorMode
+	// SummaryOnly writes just the summary line instead of the tree.
+	SummaryOnly bool
+}
+
+type treeNode struct {
+	name     string
+	item     *domain.DiffItem
+	children map[string]*treeNode
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	c, ok := n.children[name]
+	if !ok {
+		c = &treeNode{name: name, children: make(map[string]*treeNode)}
+		n.children[name] = c
+	}
+	return c
+}
+
+// buildTree arranges items by their path components.
+func buildTree(items []domain.DiffItem) *treeNode {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for i := range items {
+		node := root
+		for _, part := range strings.Split(items[i].Path, "/") {
+			node = node.child(part)
+		}
+		node.item = &items[i]
+	}
+	return root
+}
+
+// Report implements Reporter.
+func (r *TreeReporter) Report(w io.Writer, report *domain.DiffReport) error {
+	fmt.Fprintf(w, "Source: %s\nTarget: %s\n\n", report.SourceDir, report.TargetDir)
+	if r.SummaryOnly {
+		fmt.Fprintln(w, summaryLine(report.Summary))
+		return nil
+	}
+	if len(report.Items) == 0 {
+		fmt.Fprintln(w, noItemsMessage(report))
+		return nil
+	}
+
+	fmt.Fprintln(w, ".")
+	r.writeChildren(w, buildTree(report.Items), "", r.Color.enabled(w))
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, summaryLine(report.Summary))
+	return nil
+}
+
+func (r *TreeReporter) writeChildren(w io.Writer, node *treeNode, indent string, color bool) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		branch, next := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, next = "└── ", "    "
+		}
+		c := node.children[name]
+		fmt.Fprintln(w, indent+branch+treeLabel(c, color))
+		r.writeChildren(w, c, indent+next, color)
+	}
+}
+
+// treeLabel renders a node's name, marked and optionally colored when it has
+// an item of its own.
+func treeLabel(n *treeNode, color bool) string {
+	if n.item == nil {
+		return n.name
+	}
+	label := treeMarkers[n.item.Type] + " " + n.name
+	if n.item.Type == domain.EmptyDir {
+		label = emptyDirMarkers[n.item.Reason] + " " + n.name + "/"
+	}
+	if n.item.Type == domain.Renamed || n.item.Type == domain.Moved {
+		label += " -> " + n.item.NewPath
+	}
+	if color {
+		label = colorize(n.item.Type, label)
+	}
+	return label
+}
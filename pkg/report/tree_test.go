@@ -0,0 +1,59 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestTreeReporter(t *testing.T) {
+	report := sampleReport()
+	report.Items = append(report.Items,
+		domain.DiffItem{Type: domain.Renamed, Path: "show/s01/a.mkv", NewPath: "show/s01/b.mkv"},
+		domain.DiffItem{Type: domain.EmptyDir, Path: "show/s02", Reason: domain.EmptyDirInTarget},
+	)
+	out := render(t, &TreeReporter{}, report)
+	want := `Source: /src
+Target: /tgt
+
+.
+├── gone
+│   └── - old.mkv
+├── + new.mkv
+└── show
+    ├── ~ ep1.mkv
+    ├── s01
+    │   └── > a.mkv -> show/s01/b.mkv
+    └── + s02/
+
+` + summaryLine(report.Summary) + "\n"
+	if out != want {
+		t.Errorf("output = \n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestTreeReporterColor(t *testing.T) {
+	out := render(t, &TreeReporter{Color: ColorAlways}, sampleReport())
+	if !strings.Contains(out, colorize(domain.Missing, "- old.mkv")) {
+		t.Errorf("missing node isn't colored:\n%q", out)
+	}
+	if strings.Contains(out, colorize(domain.Missing, "gone")) {
+		t.Errorf("unchanged directory is colored:\n%q", out)
+	}
+	if out := render(t, &TreeReporter{}, sampleReport()); strings.Contains(out, "\x1b[") {
+		t.Errorf("output to a buffer is colored:\n%q", out)
+	}
+}
+
+func TestTreeReporterNoItems(t *testing.T) {
+	out := render(t, &TreeReporter{}, &domain.DiffReport{SourceDir: "/src", TargetDir: "/tgt"})
+	if !strings.HasSuffix(out, "No differences found.\n") || strings.Contains(out, "\n.\n") {
+		t.Errorf("output = %q", out)
+	}
+
+	out = render(t, &TreeReporter{SummaryOnly: true}, sampleReport())
+	if strings.Contains(out, "old.mkv") || !strings.Contains(out, "Missing: 1") {
+		t.Errorf("SummaryOnly output = %q", out)
+	}
+}
@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// failingFS serves fsys but fails to open the paths in bad, like a directory
+// or file the scanner isn't allowed to read.
+type failingFS struct {
+	fsys fstest.MapFS
+	bad  []string
+}
+
+var errUnreadable = errors.New("permission denied")
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if slices.Contains(f.bad, name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errUnreadable}
+	}
+	return f.fsys.Open(name)
+}
+
+func TestScanContinueOnError(t *testing.T) {
+	fsys := failingFS{
+		fsys: fstest.MapFS{
+			"lib/a.mkv":         {Data: []byte("1")},
+			"lib/locked/b.mkv":  {Data: []byte("1")},
+			"lib/open/c.mkv":    {Data: []byte("1")},
+			"lib/open/deep/d.x": {Data: []byte("1")},
+		},
+		bad: []string{"lib/locked"},
+	}
+
+	_, err := NewLinearScannerWithOptions(ScanOptions{FS: fsys}).Scan("lib")
+	if !errors.Is(err, errUnreadable) {
+		t.Fatalf("Scan error = %v, want %v", err, errUnreadable)
+	}
+
+	tree, err := NewLinearScannerWithOptions(ScanOptions{FS: fsys, ContinueOnError: true}).Scan("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.mkv", "locked", "open", "open/c.mkv", "open/deep", "open/deep/d.x"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if len(tree.ScanErrors) != 1 || tree.ScanErrors[0].Path != "locked" {
+		t.Errorf("ScanErrors = %+v, want one for locked", tree.ScanErrors)
+	}
+
+	// An unreadable root still fails.
+	fsys.bad = []string{"lib"}
+	if _, err := NewLinearScannerWithOptions(ScanOptions{FS: fsys, ContinueOnError: true}).Scan("lib"); err == nil {
+		t.Error("Scan of an unreadable root succeeded")
+	}
+}
+
+func TestScanFollowSymlinks(t *testing.T) {
+	root := writeTree(t, map[string]string{"media/a.mkv": "1"})
+	links := map[string]string{
+		"media/loop":   "..",
+		"media/broken": "missing.mkv",
+		"alias":        "media",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Skipf("can't create symlinks: %v", err)
+		}
+	}
+
+	tree, err := NewLinearScannerWithOptions(ScanOptions{FollowSymlinks: true}).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"alias/a.mkv", "media/a.mkv"} {
+		if _, ok := tree.Assets[p]; !ok {
+			t.Errorf("paths = %v, want %s", paths(tree), p)
+		}
+	}
+	var broken []string
+	for _, e := range tree.ScanErrors {
+		broken = append(broken, e.Path)
+	}
+	if want := []string{"alias/broken", "media/broken"}; !slices.Equal(broken, want) {
+		t.Errorf("ScanErrors = %+v, want %v", tree.ScanErrors, want)
+	}
+	// Cycles are skipped without counting as errors.
+	if want := []string{"alias/loop", "media/loop"}; !slices.Equal(tree.SymlinkCycles, want) {
+		t.Errorf("SymlinkCycles = %v, want %v", tree.SymlinkCycles, want)
+	}
+}
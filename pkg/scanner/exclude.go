@@ -0,0 +1,41 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Excludes is a list of glob patterns for paths to leave out of a scan.
+// Patterns use doublestar syntax, so "**" matches any number of directories.
+// A pattern containing a "/" is matched against the slash-separated path
+// relative to the scan root, e.g. "**/extras/*"; any other pattern is matched
+// against the file or directory name alone, e.g. "*sample*".
+type Excludes []string
+
+// Validate reports the first malformed pattern.
+func (e Excludes) Validate() error {
+	for _, pattern := range e {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid exclude pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Match reports whether relPath, relative to the scan root and separated by
+// slashes, matches any of the patterns. Malformed patterns never match.
+func (e Excludes) Match(relPath string) bool {
+	name := relPath[strings.LastIndex(relPath, "/")+1:]
+	for _, pattern := range e {
+		subject := name
+		if strings.Contains(pattern, "/") {
+			subject = relPath
+		}
+		if ok, _ := doublestar.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"strings"
+
+	"mddiff/pkg/domain"
+)
+
+// FilterTree returns the part of tree that a scan with s's options would have
+// recorded, for a tree that wasn't scanned by s, such as a manifest or a scan
+// of another host. Ignore, exclude, extension, size, depth and symlink options
+// and Filter apply as they do to a scan. The tree's own IgnoreFileName can't
+// be read, so only a configured IgnoreFile applies. ScanErrors and
+// SymlinkCycles are kept as they are.
+func (s *LinearScanner) FilterTree(tree *domain.DirectoryTree) (*domain.DirectoryTree, error) {
+	var rules IgnoreRules
+	if s.ignoreFile != "" {
+		var err error
+		if rules, err = s.loadIgnoreRules(nil, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	filtered := &domain.DirectoryTree{
+		RootPath:      tree.RootPath,
+		Assets:        make(map[string]domain.Asset, len(tree.Assets)),
+		ScanErrors:    tree.ScanErrors,
+		SymlinkCycles: tree.SymlinkCycles,
+	}
+	for relPath, asset := range tree.Assets {
+		if !s.keeps(rules, relPath, asset) {
+			continue
+		}
+		filtered.Assets[relPath] = asset
+		if !asset.IsDir {
+			filtered.FileCount++
+			filtered.TotalSize += asset.Size
+		}
+	}
+	return filtered, nil
+}
+
+// keeps reports whether a scan would record asset at relPath: neither it nor
+// any directory above it is skipped, and it passes the size, depth, symlink
+// and Filter options.
+func (s *LinearScanner) keeps(rules IgnoreRules, relPath string, asset domain.Asset) bool {
+	if s.maxDepth > 0 && strings.Count(relPath, "/")+1 > s.maxDepth {
+		return false
+	}
+	for i, c := range relPath {
+		if c == '/' && s.skippedPath(rules, relPath[:i], true) {
+			return false
+		}
+	}
+	if s.skippedPath(rules, relPath, asset.IsDir) {
+		return false
+	}
+	if s.skipSymlinks && asset.IsSymlink {
+		return false
+	}
+	if !asset.IsDir && asset.Size < s.minSize {
+		return false
+	}
+	return s.filter == nil || s.filter(asset)
+}
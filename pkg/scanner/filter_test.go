@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mddiff/pkg/domain"
+)
+
+func TestFilterTree(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"movie.mkv":               "12345",
+		"movie.nfo":               "1",
+		"Season 1/ep1.mkv":        "123",
+		"Season 1/ep1.srt":        "12",
+		"extras/sample.mkv":       "1234",
+		"extras/deep/trailer.mp4": "12",
+	})
+	ignoreFile := filepath.Join(t.TempDir(), "ignore")
+	if err := os.WriteFile(ignoreFile, []byte("deep/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	full, err := NewLinearScanner().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts ScanOptions
+	}{
+		{"no options", ScanOptions{}},
+		{"ignore ext", ScanOptions{IgnoreExt: []string{"nfo", ".SRT"}}},
+		{"ignore names", ScanOptions{IgnoreNames: []string{"extras", "*.nfo"}}},
+		{"include ext", ScanOptions{IncludeExt: []string{".mkv"}}},
+		{"exclude", ScanOptions{Exclude: Excludes{"**/deep/*", "Season*"}}},
+		{"ignore file", ScanOptions{IgnoreFile: ignoreFile}},
+		{"min size", ScanOptions{MinSize: 3}},
+		{"max depth", ScanOptions{MaxDepth: 2}},
+		{"filter", ScanOptions{Filter: func(a domain.Asset) bool { return a.IsDir || a.Size%2 == 1 }}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewLinearScannerWithOptions(tt.opts)
+			want, err := s.Scan(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.FilterTree(full)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(paths(got), paths(want)) {
+				t.Errorf("FilterTree kept %q, a scan kept %q", paths(got), paths(want))
+			}
+			if got.FileCount != want.FileCount || got.TotalSize != want.TotalSize {
+				t.Errorf("FilterTree counted %d files, %d bytes, want %d, %d",
+					got.FileCount, got.TotalSize, want.FileCount, want.TotalSize)
+			}
+		})
+	}
+}
+
+func TestFilterTreeSymlinks(t *testing.T) {
+	tree := &domain.DirectoryTree{Assets: map[string]domain.Asset{
+		"a.mkv":    {Path: "a.mkv", Size: 1},
+		"link.mkv": {Path: "link.mkv", IsSymlink: true, LinkTarget: "a.mkv"},
+	}}
+	got, err := NewLinearScannerWithOptions(ScanOptions{SkipSymlinks: true}).FilterTree(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.mkv"}; !slices.Equal(paths(got), want) {
+		t.Errorf("FilterTree kept %q, want %q", paths(got), want)
+	}
+}
+
+func TestFilterTreeIgnoreFileMissing(t *testing.T) {
+	s := NewLinearScannerWithOptions(ScanOptions{IgnoreFile: filepath.Join(t.TempDir(), "missing")})
+	if _, err := s.FilterTree(&domain.DirectoryTree{}); err == nil {
+		t.Error("FilterTree succeeded without the ignore file")
+	}
+}
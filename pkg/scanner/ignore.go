@@ -0,0 +1,84 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// builtinIgnoreNames are OS and editor metadata that never belong in a media
+// library.
+var builtinIgnoreNames = []string{".DS_Store", "Thumbs.db", ".git", ".idea", ".vscode"}
+
+// DefaultIgnoreList returns the OS and editor metadata names a LinearScanner
+// skips unless ScanOptions.NoDefaultIgnores is set.
+func DefaultIgnoreList() []string {
+	return append([]string{}, builtinIgnoreNames...)
+}
+
+// globChars are the characters that make a name ignore entry a
+// filepath.Match pattern rather than an exact name.
+const globChars = `*?[\`
+
+// nameSet matches file and directory names against exact names and glob
+// patterns such as "*.part".
+type nameSet struct {
+	exact map[string]bool
+	globs []string
+}
+
+// newNameSet builds a nameSet from ignore entries, applied as by
+// buildIgnoreSet. A "!" entry removes an earlier entry that is spelled the
+// same; it doesn't carve a name out of a pattern.
+func newNameSet(entries []string) nameSet {
+	set := nameSet{exact: buildIgnoreSet(entries, nil)}
+	for entry := range set.exact {
+		if strings.ContainsAny(entry, globChars) {
+			set.globs = append(set.globs, entry)
+			delete(set.exact, entry)
+		}
+	}
+	return set
+}
+
+// match reports whether name is one of the exact names or matches a pattern.
+// Malformed patterns never match.
+func (n nameSet) match(name string) bool {
+	if n.exact[name] {
+		return true
+	}
+	for _, pattern := range n.globs {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// negationPrefix marks an ignore entry that removes a name or extension added
+// by an earlier entry.
+const negationPrefix = "!"
+
+// buildIgnoreSet applies ignore entries in order: a plain entry adds to the
+// set and an entry prefixed with "!" removes it again, so later entries win.
+// normalize, if non-nil, is applied to each entry before it is stored.
+func buildIgnoreSet(entries []string, normalize func(string) string) map[string]bool {
+	set := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		negate := strings.HasPrefix(entry, negationPrefix)
+		entry = strings.TrimPrefix(entry, negationPrefix)
+		if normalize != nil {
+			entry = normalize(entry)
+		}
+		if entry == "" {
+			continue
+		}
+
+		if negate {
+			delete(set, entry)
+		} else {
+			set[entry] = true
+		}
+	}
+	return set
+}
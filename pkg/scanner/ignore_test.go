@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestBuildIgnoreSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   []string
+		normalize func(string) string
+		want      []string
+	}{
+		{"plain entries", []string{"a", " b ", ""}, nil, []string{"a", "b"}},
+		{"later negation wins", []string{"a", "b", "!a"}, nil, []string{"b"}},
+		{"later entry re-adds", []string{"a", "!a", "a"}, nil, []string{"a"}},
+		{"negating an absent entry", []string{"!a", "b"}, nil, []string{"b"}},
+		{"normalized", []string{"MKV", ".nfo", "!.mkv"}, normalizeExt, []string{".nfo"}},
+		{"lone negation", []string{"!"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Sorted(maps.Keys(buildIgnoreSet(tt.entries, tt.normalize)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("buildIgnoreSet(%q) = %q, want %q", tt.entries, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameSet(t *testing.T) {
+	set := newNameSet([]string{"Thumbs.db", "*.part", "keep.part", "!keep.part", "[bad"})
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Thumbs.db", true},
+		{"thumbs.db", false},
+		{"movie.part", true},
+		// Negation removes only the exact entry, not a match of a pattern.
+		{"keep.part", true},
+		{"[bad", false},
+		{"movie.mkv", false},
+	}
+	for _, tt := range tests {
+		if got := set.match(tt.name); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanNegatedDefaultIgnore(t *testing.T) {
+	root := writeTree(t, map[string]string{".DS_Store": "1", "Thumbs.db": "1", "a.mkv": "1"})
+	tree, err := NewLinearScannerWithOptions(ScanOptions{IgnoreNames: []string{"!.DS_Store"}}).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".DS_Store", "a.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestScanIgnoreNamePatterns(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"movie.mkv":          "1",
+		"movie.mkv.part":     "1",
+		"show/ep1.part":      "1",
+		"~$notes.docx":       "1",
+		".cache/thumb.jpg":   "1",
+		".cache-v2/a/b.jpg":  "1",
+		"show/.cached/c.jpg": "1",
+		"cache.mkv":          "1",
+	})
+	opts := ScanOptions{IgnoreNames: []string{"*.part", "~$*", ".cache*"}}
+	tree, err := NewLinearScannerWithOptions(opts).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Matching directories are skipped along with their contents.
+	want := []string{"cache.mkv", "movie.mkv", "show"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+
+	// Counting applies the same patterns.
+	n, err := NewLinearScannerWithOptions(opts).Count(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("Count = %d, want %d", n, len(want))
+	}
+}
@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// IgnoreFileName is the gitignore-style file read from the root of each
+// scanned tree.
+const IgnoreFileName = ".mddiffignore"
+
+// ignoreRule is one pattern from an ignore file.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	// anchored patterns are matched against the path from the root rather
+	// than the name alone.
+	anchored bool
+}
+
+// IgnoreRules is a parsed ignore file. As in gitignore, blank lines and lines
+// starting with "#" are skipped, "!" re-includes a path an earlier pattern
+// ignored, a trailing "/" only matches directories, and a pattern containing
+// any other "/" is matched against the path from the root. Patterns use
+// doublestar syntax, so "**" matches any number of directories. A file inside
+// an ignored directory can't be re-included, since the directory isn't read.
+type IgnoreRules []ignoreRule
+
+// ParseIgnoreFile reads ignore rules from r.
+func ParseIgnoreFile(r io.Reader) (IgnoreRules, error) {
+	var rules IgnoreRules
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), " \t\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if rule.negate = strings.HasPrefix(text, negationPrefix); rule.negate {
+			text = text[len(negationPrefix):]
+		}
+		// A backslash escapes a leading "#" or "!".
+		text = strings.TrimPrefix(text, `\`)
+		if rule.dirOnly = strings.HasSuffix(text, "/"); rule.dirOnly {
+			text = strings.TrimSuffix(text, "/")
+		}
+		rule.anchored = strings.Contains(text, "/")
+		rule.pattern = strings.TrimPrefix(text, "/")
+
+		if rule.pattern == "" || !doublestar.ValidatePattern(rule.pattern) {
+			return nil, fmt.Errorf("line %d: invalid pattern %q", line, sc.Text())
+		}
+		rules = append(rules, rule)
+	}
+	return rules, sc.Err()
+}
+
+// Ignored reports whether relPath, relative to the scan root and separated by
+// slashes, is ignored. The last matching rule decides.
+func (rules IgnoreRules) Ignored(relPath string, isDir bool) bool {
+	name := path.Base(relPath)
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		subject := name
+		if rule.anchored {
+			subject = relPath
+		}
+		if ok, _ := doublestar.Match(rule.pattern, subject); ok {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// loadIgnoreRules reads the ignore rules for a tree rooted at dir in fsys:
+// the configured ignore file if there is one, or else the tree's own
+// IgnoreFileName, which may be absent.
+func (s *LinearScanner) loadIgnoreRules(fsys fs.FS, dir string) (IgnoreRules, error) {
+	var data []byte
+	var err error
+	if s.ignoreFile != "" {
+		data, err = os.ReadFile(s.ignoreFile) // #nosec G304 -- path is chosen by the user
+	} else {
+		data, err = fs.ReadFile(fsys, path.Join(dir, IgnoreFileName))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ignore file: %w", err)
+	}
+
+	rules, err := ParseIgnoreFile(bytes.NewReader(data))
+	if err != nil {
+		name := s.ignoreFile
+		if name == "" {
+			name = IgnoreFileName
+		}
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules, err := ParseIgnoreFile(strings.NewReader(`# junk
+*.nfo
+
+!keep.nfo
+samples/
+/extras/*.mkv
+**/trailers/*.mkv
+\#literal
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"movie.nfo", false, true},
+		{"show/ep1.nfo", false, true},
+		{"keep.nfo", false, false},
+		{"show/keep.nfo", false, false},
+		{"movie.mkv", false, false},
+		{"samples", true, true},
+		{"show/samples", true, true},
+		// Directory-only patterns don't match files.
+		{"samples", false, false},
+		// Patterns with a slash are matched from the root.
+		{"extras/a.mkv", false, true},
+		{"show/extras/a.mkv", false, false},
+		{"trailers/a.mkv", false, true},
+		{"show/s01/trailers/a.mkv", false, true},
+		{"#literal", false, true},
+	}
+	for _, tt := range tests {
+		if got := rules.Ignored(filepath.FromSlash(tt.path), tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnoreFileInvalid(t *testing.T) {
+	for _, in := range []string{"/\n", "!\n", "ok\n[oops\n"} {
+		if _, err := ParseIgnoreFile(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseIgnoreFile(%q) succeeded", in)
+		}
+	}
+	_, err := ParseIgnoreFile(strings.NewReader("ok\n[oops\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error = %v, want it to name line 2", err)
+	}
+}
+
+func TestScanIgnoreFile(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		IgnoreFileName:           "*.nfo\n!keep.nfo\nsamples/\n",
+		"a.mkv":                  "1",
+		"a.nfo":                  "1",
+		"keep.nfo":               "1",
+		"samples/s.mkv":          "1",
+		"show/samples/s.mkv":     "1",
+		"show/ep1.nfo":           "1",
+		"show/samples.mkv":       "1",
+		"show/" + IgnoreFileName: "*.mkv\n",
+	})
+	tree, err := NewLinearScanner().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the root's ignore file is read, and it isn't scanned itself.
+	want := []string{"a.mkv", "keep.nfo", "show", "show/samples.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+
+	// IgnoreFile replaces the tree's own file.
+	override := filepath.Join(t.TempDir(), "ignore")
+	if err := os.WriteFile(override, []byte("show/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tree, err = NewLinearScannerWithOptions(ScanOptions{IgnoreFile: override}).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"a.mkv", "a.nfo", "keep.nfo", "samples", "samples/s.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths with IgnoreFile = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("[oops\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLinearScanner().Scan(root); err == nil || !strings.Contains(err.Error(), IgnoreFileName) {
+		t.Errorf("Scan error = %v, want one naming %s", err, IgnoreFileName)
+	}
+	missing := filepath.Join(t.TempDir(), "nope")
+	if _, err := NewLinearScannerWithOptions(ScanOptions{IgnoreFile: missing}).Scan(root); err == nil {
+		t.Error("Scan with a missing IgnoreFile succeeded")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package scanner
+
+import (
+	"io/fs"
+
+	"mddiff/pkg/domain"
+)
+
+// RecordsOwnership reports whether the scanner fills in Asset.Owner on this
+// platform.
+const RecordsOwnership = false
+
+func ownerOf(fs.FileInfo) *domain.Owner {
+	return nil
+}
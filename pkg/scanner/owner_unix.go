@@ -0,0 +1,22 @@
+//go:build unix
+
+package scanner
+
+import (
+	"io/fs"
+	"syscall"
+
+	"mddiff/pkg/domain"
+)
+
+// RecordsOwnership reports whether the scanner fills in Asset.Owner on this
+// platform.
+const RecordsOwnership = true
+
+func ownerOf(info fs.FileInfo) *domain.Owner {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return &domain.Owner{UID: st.Uid, GID: st.Gid}
+}
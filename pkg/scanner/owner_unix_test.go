@@ -0,0 +1,25 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"testing"
+)
+
+func TestScanRecordsOwner(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.mkv": "1"})
+	tree, err := NewLinearScanner().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := tree.Assets["a.mkv"].Owner
+	if owner == nil {
+		t.Fatal("Owner is nil")
+	}
+	// New files may take their group from the directory, so only the owner is
+	// predictable.
+	if uid := os.Getuid(); int(owner.UID) != uid {
+		t.Errorf("Owner = %+v, want UID %d", *owner, uid)
+	}
+}
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProgressWindow is how far back Progress looks when computing the
+// current throughput.
+const DefaultProgressWindow = 10 * time.Second
+
+// Progress counts scanned entries and estimates throughput and time remaining.
+// Any number of goroutines may call Add while another reads Status.
+type Progress struct {
+	done  atomic.Int64
+	total atomic.Int64
+
+	window  time.Duration
+	mu      sync.Mutex
+	samples []progressSample
+}
+
+type progressSample struct {
+	at   time.Time
+	done int64
+}
+
+// ProgressStatus is a snapshot of a Progress.
+type ProgressStatus struct {
+	Done  int64
+	Total int64
+	// Rate is the number of entries per second over the recent window.
+	Rate float64
+	// ETA is the estimated time remaining, or -1 when it can't be estimated
+	// yet.
+	ETA time.Duration
+}
+
+// NewProgress returns a Progress expecting total entries, measuring
+// throughput over window.
+func NewProgress(total int64, window time.Duration) *Progress {
+	p := &Progress{window: window}
+	p.total.Store(total)
+	return p
+}
+
+// SetTotal sets the number of entries expected, e.g. once Count returns.
+func (p *Progress) SetTotal(total int64) {
+	p.total.Store(total)
+}
+
+// Add records n more scanned entries.
+func (p *Progress) Add(n int64) {
+	p.done.Add(n)
+}
+
+// Status records a sample at now and returns the current counts, the rolling
+// throughput since the oldest sample inside the window, and the resulting ETA.
+func (p *Progress) Status(now time.Time) ProgressStatus {
+	st := ProgressStatus{Done: p.done.Load(), Total: p.total.Load(), ETA: -1}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.samples = append(p.samples, progressSample{at: now, done: st.Done})
+	// Keep one sample at or before the window's start so the rate always
+	// spans the full window once there's enough history.
+	for len(p.samples) > 2 && now.Sub(p.samples[1].at) >= p.window {
+		p.samples = p.samples[1:]
+	}
+
+	oldest := p.samples[0]
+	if elapsed := now.Sub(oldest.at).Seconds(); elapsed > 0 {
+		st.Rate = float64(st.Done-oldest.done) / elapsed
+	}
+	if st.Rate > 0 && st.Total >= st.Done {
+		st.ETA = time.Duration(float64(st.Total-st.Done) / st.Rate * float64(time.Second))
+	}
+	return st
+}
+
+// Count quickly counts the entries a scan of rootPath would visit, without
+// reading file metadata, so it can be used as the total for a Progress.
+// Unreadable directories are skipped.
+func (s *LinearScanner) Count(ctx context.Context, rootPath string) (int64, error) {
+	var n int64
+	fsys, dir, _ := s.walkRoot(rootPath)
+	rules, err := s.loadIgnoreRules(fsys, dir)
+	if err != nil {
+		return 0, err
+	}
+	err = fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if s.skipped(rules, relativePath(dir, p), d) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		n++
+		if d.IsDir() && s.atDepthLimit(relativePath(dir, p)) {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressStatus(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := NewProgress(1000, 10*time.Second)
+
+	if st := p.Status(start); st.Done != 0 || st.Rate != 0 || st.ETA != -1 {
+		t.Errorf("first status = %+v, want no rate or ETA yet", st)
+	}
+
+	p.Add(100)
+	st := p.Status(start.Add(time.Second))
+	if st.Done != 100 || st.Total != 1000 || st.Rate != 100 || st.ETA != 9*time.Second {
+		t.Errorf("status = %+v, want 100/s and 9s left", st)
+	}
+
+	// The rate follows the last window: 20 seconds later, only the recent
+	// slowdown counts.
+	for i := 2; i <= 20; i++ {
+		p.Add(10)
+		p.Status(start.Add(time.Duration(i) * time.Second))
+	}
+	p.Add(10)
+	st = p.Status(start.Add(21 * time.Second))
+	if st.Done != 300 || st.Rate != 10 || st.ETA != 70*time.Second {
+		t.Errorf("status = %+v, want 10/s over the window and 70s left", st)
+	}
+
+	// Without a total there's no ETA.
+	p.SetTotal(0)
+	p.Add(10)
+	if st := p.Status(start.Add(22 * time.Second)); st.ETA != -1 {
+		t.Errorf("status = %+v, want no ETA without a total", st)
+	}
+}
+
+func TestProgressConcurrentAdd(t *testing.T) {
+	p := NewProgress(0, DefaultProgressWindow)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				p.Add(1)
+			}
+		})
+	}
+	wg.Go(func() {
+		for range 100 {
+			p.Status(time.Now())
+		}
+	})
+	wg.Wait()
+	if st := p.Status(time.Now()); st.Done != 8000 {
+		t.Errorf("Done = %d, want 8000", st.Done)
+	}
+}
+
+func TestCount(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.mkv":        "1",
+		"show/ep1.mkv": "1",
+		"show/ep2.mkv": "1",
+		".DS_Store":    "1",
+		".git/config":  "1",
+	})
+	progress := NewProgress(0, DefaultProgressWindow)
+	s := NewLinearScannerWithOptions(ScanOptions{Progress: progress})
+	n, err := s.Count(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := s.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 || n != int64(len(tree.Assets)) {
+		t.Errorf("Count = %d, want 4 like the scan's %v", n, paths(tree))
+	}
+	if st := progress.Status(time.Now()); st.Done != n {
+		t.Errorf("progress after the scan = %d, want %d", st.Done, n)
+	}
+
+	if _, err := s.Count(context.Background(), filepath.Join(root, "missing")); err == nil {
+		t.Error("Count of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,390 @@
+/*
+Package scanner walks media directories and records their contents.
+*/
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"mddiff/pkg/domain"
+)
+
+// ScanOptions customizes what a LinearScanner records.
+//
+// IgnoreExt and IgnoreNames are applied in order, and an entry prefixed with
+// "!" removes one added earlier. IgnoreNames is applied after the built-in OS
+// and editor metadata names, so "!Thumbs.db" scans Thumbs.db files again.
+// mddiff's own ignore file is skipped even without the built-in names.
+type ScanOptions struct {
+	// IgnoreExt lists file extensions to skip. Matching is case-insensitive
+	// and the leading dot is optional.
+	IgnoreExt []string
+	// IgnoreNames lists file or directory names to skip, either exactly or
+	// as filepath.Match patterns such as "*.part".
+	IgnoreNames []string
+	// NoDefaultIgnores leaves out the names of DefaultIgnoreList, so only
+	// IgnoreNames are skipped.
+	NoDefaultIgnores bool
+	// IncludeExt, when not empty, limits the scan to files with these
+	// extensions, matched like IgnoreExt. IgnoreExt is applied afterwards, and
+	// directories are always scanned.
+	IncludeExt []string
+	// Exclude lists glob patterns for paths to skip. An excluded directory
+	// isn't descended into.
+	Exclude Excludes
+	// IgnoreFile, when set, is read instead of the IgnoreFileName file at the
+	// root of each scanned tree. See IgnoreRules for its syntax.
+	IgnoreFile string
+	// FollowSymlinks scans the target of each symbolic link in place of the
+	// link, descending into linked directories. A link that leads back to a
+	// directory it is in is not followed and is recorded in
+	// DirectoryTree.SymlinkCycles. Broken links are skipped and recorded in
+	// DirectoryTree.ScanErrors even without ContinueOnError. It has no effect
+	// when FS is set.
+	FollowSymlinks bool
+	// SkipSymlinks leaves symbolic links out of the scan.
+	SkipSymlinks bool
+	// MinSize drops files smaller than this many bytes. Directories are
+	// still scanned.
+	MinSize int64
+	// MaxDepth, when positive, limits the scan to paths at most this many
+	// levels deep: 1 records only the root's immediate children. Directories
+	// at the limit are recorded but not descended into.
+	MaxDepth int
+	// Filter, when set, is called for every asset that isn't ignored and
+	// returning false drops it from the tree. Dropping a directory only drops
+	// its own entry; its contents are still scanned and filtered one by one.
+	// With more than one worker, it must be safe for concurrent use.
+	Filter func(domain.Asset) bool
+	// ContinueOnError records unreadable files and directories in
+	// DirectoryTree.ScanErrors and skips them instead of failing the scan.
+	// An unreadable root still fails.
+	ContinueOnError bool
+	// Progress, when set, is advanced for every entry that isn't ignored.
+	Progress *Progress
+	// OnProgress, when set, is called on the walking goroutine after every
+	// entry that isn't ignored, with the number of such entries the current
+	// scan has found so far.
+	OnProgress func(scanned int)
+	// Workers is the number of goroutines reading file metadata while a
+	// single goroutine walks the tree. Zero or one reads it on the walking
+	// goroutine. The resulting tree is the same either way.
+	Workers int
+	// FS, when set, is scanned instead of the OS filesystem, e.g. an
+	// fstest.MapFS or a zip.Reader. Scan's rootPath is then a path within it.
+	FS fs.FS
+}
+
+// LinearScanner walks a directory tree on a single goroutine, optionally
+// handing each entry's metadata reads to a pool of workers.
+type LinearScanner struct {
+	ignoreList      nameSet
+	ignoreExt       map[string]bool
+	includeExt      map[string]bool
+	exclude         Excludes
+	ignoreFile      string
+	minSize         int64
+	maxDepth        int
+	followSymlinks  bool
+	skipSymlinks    bool
+	filter          func(domain.Asset) bool
+	continueOnError bool
+	progress        *Progress
+	onProgress      func(int)
+	workers         int
+	fsys            fs.FS
+}
+
+// NewLinearScanner returns a scanner that skips common OS and editor metadata.
+func NewLinearScanner() *LinearScanner {
+	return NewLinearScannerWithOptions(ScanOptions{})
+}
+
+// NewLinearScannerWithOptions returns a scanner configured by opts.
+func NewLinearScannerWithOptions(opts ScanOptions) *LinearScanner {
+	var names []string
+	if !opts.NoDefaultIgnores {
+		names = DefaultIgnoreList()
+	}
+	names = append(append(names, IgnoreFileName), opts.IgnoreNames...)
+	return &LinearScanner{
+		ignoreList:      newNameSet(names),
+		ignoreExt:       buildIgnoreSet(opts.IgnoreExt, normalizeExt),
+		includeExt:      buildIgnoreSet(opts.IncludeExt, normalizeExt),
+		exclude:         opts.Exclude,
+		ignoreFile:      opts.IgnoreFile,
+		minSize:         opts.MinSize,
+		maxDepth:        opts.MaxDepth,
+		followSymlinks:  opts.FollowSymlinks && opts.FS == nil,
+		skipSymlinks:    opts.SkipSymlinks,
+		filter:          opts.Filter,
+		continueOnError: opts.ContinueOnError,
+		progress:        opts.Progress,
+		onProgress:      opts.OnProgress,
+		workers:         opts.Workers,
+		fsys:            opts.FS,
+	}
+}
+
+// NewFSScanner returns a scanner that reads fsys instead of the OS filesystem.
+// Assets it finds have no AbsPath, so they can't be hashed.
+func NewFSScanner(fsys fs.FS) *LinearScanner {
+	return NewLinearScannerWithOptions(ScanOptions{FS: fsys})
+}
+
+// skipped reports whether the entry d at relPath is ignored or excluded.
+// rules are the ignore file rules of the tree being walked.
+func (s *LinearScanner) skipped(rules IgnoreRules, relPath string, d fs.DirEntry) bool {
+	return s.skippedPath(rules, relPath, d.IsDir())
+}
+
+// skippedPath is like skipped for a path that isn't read from a filesystem.
+func (s *LinearScanner) skippedPath(rules IgnoreRules, relPath string, isDir bool) bool {
+	name := path.Base(relPath)
+	if s.ignoreList.match(name) || s.exclude.Match(relPath) || rules.Ignored(relPath, isDir) {
+		return true
+	}
+	if isDir {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if len(s.includeExt) > 0 && !s.includeExt[ext] {
+		return true
+	}
+	return s.ignoreExt[ext]
+}
+
+// atDepthLimit reports whether relPath is as deep as the scan goes, so a
+// directory there must not be descended into.
+func (s *LinearScanner) atDepthLimit(relPath string) bool {
+	return s.maxDepth > 0 && strings.Count(relPath, "/")+1 >= s.maxDepth
+}
+
+// walkRoot returns the filesystem and directory within it to walk for
+// rootPath, and a function giving the on-disk location of a path relative to
+// rootPath. Without an FS, rootPath is a directory on the OS filesystem. With
+// one, rootPath is a slash-separated path in it, such as ".", and assets have
+// no on-disk location.
+func (s *LinearScanner) walkRoot(rootPath string) (fsys fs.FS, dir string, absPath func(relPath string) string) {
+	if s.fsys == nil {
+		return os.DirFS(rootPath), ".", func(relPath string) string {
+			return filepath.Join(rootPath, filepath.FromSlash(relPath))
+		}
+	}
+	return s.fsys, rootPath, func(string) string { return "" }
+}
+
+// relativePath converts p, a path walked from dir, to a path relative to dir.
+// dir itself is ".". Like every path in a DirectoryTree, it is separated by
+// forward slashes on every OS, so reports and manifests don't depend on where
+// they were made.
+func relativePath(dir, p string) string {
+	if p == dir {
+		return "."
+	}
+	if dir != "." {
+		p = strings.TrimPrefix(p, dir+"/")
+	}
+	return p
+}
+
+// symlinkCycle reports whether the directory symlink at relPath, under the OS
+// directory rootPath, leads to the root or to one of the directories the link
+// is in, which would make following it loop forever.
+func symlinkCycle(rootPath, relPath string) (bool, error) {
+	target, err := filepath.EvalSymlinks(filepath.Join(rootPath, filepath.FromSlash(relPath)))
+	if err != nil {
+		return false, err
+	}
+	for dir := filepath.Dir(filepath.FromSlash(relPath)); ; dir = filepath.Dir(dir) {
+		real, err := filepath.EvalSymlinks(filepath.Join(rootPath, dir))
+		if err != nil {
+			return false, err
+		}
+		if real == target {
+			return true, nil
+		}
+		if dir == "." {
+			return false, nil
+		}
+	}
+}
+
+// normalizeExt lowercases ext and ensures it starts with a dot.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// Scan walks rootPath and returns every file and directory beneath it.
+func (s *LinearScanner) Scan(rootPath string) (*domain.DirectoryTree, error) {
+	return s.ScanContext(context.Background(), rootPath)
+}
+
+// ScanContext is like Scan but stops when ctx is done. It then returns the
+// assets found so far along with an error wrapping ctx.Err().
+func (s *LinearScanner) ScanContext(ctx context.Context, rootPath string) (*domain.DirectoryTree, error) {
+	tree := &domain.DirectoryTree{
+		RootPath: rootPath,
+		Assets:   make(map[string]domain.Asset),
+	}
+
+	// mu guards tree when metadata is read by workers.
+	var mu sync.Mutex
+
+	// record adds err against relPath to the tree's scan errors.
+	record := func(relPath string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		tree.ScanErrors = append(tree.ScanErrors, domain.ScanError{Path: relPath, Err: err.Error()})
+	}
+	// skip records err against relPath when continuing past errors.
+	skip := func(relPath string, err error) error {
+		if !s.continueOnError || relPath == "." {
+			return err
+		}
+		record(relPath, err)
+		return nil
+	}
+
+	fsys, dir, absPath := s.walkRoot(rootPath)
+	rules, err := s.loadIgnoreRules(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", rootPath, err)
+	}
+
+	// add reads the metadata of d and records it in tree.
+	add := func(relPath string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return skip(relPath, err)
+		}
+
+		asset := domain.Asset{
+			Path:    relPath,
+			AbsPath: absPath(relPath),
+			IsDir:   d.IsDir(),
+		}
+		if !d.IsDir() {
+			asset.Ext = path.Ext(d.Name())
+			asset.Size = info.Size()
+			asset.ModTime = info.ModTime()
+			asset.IsSymlink = d.Type()&fs.ModeSymlink != 0
+			if asset.IsSymlink {
+				if asset.LinkTarget, err = fs.ReadLink(fsys, path.Join(dir, relPath)); err != nil {
+					return skip(relPath, err)
+				}
+			}
+			asset.Owner = ownerOf(info)
+			if asset.Size < s.minSize {
+				return nil
+			}
+		}
+		if s.filter != nil && !s.filter(asset) {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		tree.Assets[relPath] = asset
+		if !asset.IsDir {
+			tree.FileCount++
+			tree.TotalSize += asset.Size
+		}
+		return nil
+	}
+	visit, wait := add, func() error { return nil }
+	if s.workers > 1 {
+		visit, wait = startWorkers(ctx, s.workers, add)
+	}
+
+	scanned := 0
+	var walk fs.WalkDirFunc
+	walk = func(p string, d fs.DirEntry, err error) error {
+		relPath := relativePath(dir, p)
+		if err != nil {
+			return skip(relPath, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		if s.skipped(rules, relPath, d) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if s.skipSymlinks && d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if s.followSymlinks && d.Type()&fs.ModeSymlink != 0 {
+			info, err := fs.Stat(fsys, p)
+			if err != nil {
+				record(relPath, fmt.Errorf("broken symlink: %w", err))
+				return nil
+			}
+			if info.IsDir() {
+				cycle, err := symlinkCycle(rootPath, relPath)
+				if err != nil {
+					return skip(relPath, err)
+				}
+				if cycle {
+					mu.Lock()
+					tree.SymlinkCycles = append(tree.SymlinkCycles, relPath)
+					mu.Unlock()
+					return nil
+				}
+				// The walk starts by visiting the linked directory itself.
+				return fs.WalkDir(fsys, p, walk)
+			}
+			d = fs.FileInfoToDirEntry(info)
+		}
+
+		if s.progress != nil {
+			s.progress.Add(1)
+		}
+		if s.onProgress != nil {
+			scanned++
+			s.onProgress(scanned)
+		}
+		if err := visit(relPath, d); err != nil {
+			return err
+		}
+		if d.IsDir() && s.atDepthLimit(relPath) {
+			return fs.SkipDir
+		}
+		return nil
+	}
+	err = fs.WalkDir(fsys, dir, walk)
+	if werr := wait(); err == nil {
+		err = werr
+	}
+	// Workers finish out of order, so keep errors in walk order.
+	sort.Slice(tree.ScanErrors, func(i, j int) bool {
+		return tree.ScanErrors[i].Path < tree.ScanErrors[j].Path
+	})
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return tree, fmt.Errorf("scanning %s: %w", rootPath, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", rootPath, err)
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,361 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"mddiff/pkg/domain"
+)
+
+// writeTree creates files under a new temporary directory, one per entry of
+// files mapping a slash-separated path to its content, and returns the
+// directory.
+func writeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for p, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// paths returns the sorted paths of tree's assets.
+func paths(tree *domain.DirectoryTree) []string {
+	var out []string
+	for p := range tree.Assets {
+		out = append(out, p)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestScan(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"movie.mkv":          "12345",
+		"movie.srt":          "12",
+		"show/ep1.mkv":       "123",
+		".DS_Store":          "x",
+		"show/Thumbs.db":     "x",
+		"show/.mddiffignore": "",
+	})
+
+	tree, err := NewLinearScanner().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"movie.mkv", "movie.srt", "show", "show/ep1.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if tree.RootPath != root {
+		t.Errorf("RootPath = %q, want %q", tree.RootPath, root)
+	}
+	if tree.FileCount != 3 || tree.TotalSize != 10 {
+		t.Errorf("FileCount, TotalSize = %d, %d, want 3, 10", tree.FileCount, tree.TotalSize)
+	}
+
+	movie := tree.Assets["movie.mkv"]
+	if movie.Ext != ".mkv" || movie.Size != 5 || movie.IsDir {
+		t.Errorf("movie.mkv = %+v", movie)
+	}
+	if want := filepath.Join(root, "movie.mkv"); movie.AbsPath != want {
+		t.Errorf("AbsPath = %q, want %q", movie.AbsPath, want)
+	}
+	if show := tree.Assets["show"]; !show.IsDir || show.Ext != "" {
+		t.Errorf("show = %+v, want a directory without an extension", show)
+	}
+}
+
+func TestScanSlashPaths(t *testing.T) {
+	root := writeTree(t, map[string]string{"show/s01/ep1.mkv": "1", "show/s01/extras/bts.mkv": "1"})
+	for _, workers := range []int{1, 4} {
+		tree, err := NewLinearScannerWithOptions(ScanOptions{Workers: workers}).Scan(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"show", "show/s01", "show/s01/ep1.mkv", "show/s01/extras", "show/s01/extras/bts.mkv"}
+		if got := paths(tree); !slices.Equal(got, want) {
+			t.Errorf("paths = %v, want %v", got, want)
+		}
+		for key, a := range tree.Assets {
+			if strings.Contains(a.Path, `\`) || a.Path != key {
+				t.Errorf("asset %q has Path %q, want the forward-slash key", key, a.Path)
+			}
+			// Disk access still uses the OS separator.
+			if want := filepath.Join(root, filepath.FromSlash(key)); a.AbsPath != want {
+				t.Errorf("asset %q has AbsPath %q, want %q", key, a.AbsPath, want)
+			}
+		}
+	}
+}
+
+func TestScanOptions(t *testing.T) {
+	files := map[string]string{
+		"a.mkv":       "1",
+		"a.nfo":       "1",
+		"b.MKV":       "1",
+		"d/c.txt":     "1",
+		"d/e/f.mkv":   "1",
+		"@eaDir/x.db": "1",
+		".DS_Store":   "1",
+	}
+	tests := []struct {
+		name string
+		opts ScanOptions
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"@eaDir", "@eaDir/x.db", "a.mkv", "a.nfo", "b.MKV", "d", "d/c.txt", "d/e", "d/e/f.mkv"},
+		},
+		{
+			name: "ignored extensions",
+			opts: ScanOptions{IgnoreExt: []string{"nfo", ".TXT"}},
+			want: []string{"@eaDir", "@eaDir/x.db", "a.mkv", "b.MKV", "d", "d/e", "d/e/f.mkv"},
+		},
+		{
+			name: "ignored names",
+			opts: ScanOptions{IgnoreNames: []string{"@eaDir", "e"}},
+			want: []string{"a.mkv", "a.nfo", "b.MKV", "d", "d/c.txt"},
+		},
+		{
+			name: "included extensions",
+			opts: ScanOptions{IncludeExt: []string{"mkv"}},
+			want: []string{"@eaDir", "a.mkv", "b.MKV", "d", "d/e", "d/e/f.mkv"},
+		},
+		{
+			name: "no default ignores",
+			opts: ScanOptions{NoDefaultIgnores: true, IgnoreNames: []string{"@eaDir", "d"}},
+			want: []string{".DS_Store", "a.mkv", "a.nfo", "b.MKV"},
+		},
+	}
+	root := writeTree(t, files)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := NewLinearScannerWithOptions(tt.opts).Scan(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(tree); !slices.Equal(got, tt.want) {
+				t.Errorf("paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanMaxDepth(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"movie.mkv":                         "1",
+		"show/ep1.mkv":                      "1",
+		"show/extras/bts.mkv":               "1",
+		"show/extras/behind/the/scenes.mkv": "1",
+	})
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{1, []string{"movie.mkv", "show"}},
+		{2, []string{"movie.mkv", "show", "show/ep1.mkv", "show/extras"}},
+		{3, []string{"movie.mkv", "show", "show/ep1.mkv", "show/extras", "show/extras/behind", "show/extras/bts.mkv"}},
+		{0, []string{
+			"movie.mkv", "show", "show/ep1.mkv", "show/extras", "show/extras/behind", "show/extras/behind/the",
+			"show/extras/behind/the/scenes.mkv", "show/extras/bts.mkv",
+		}},
+	}
+	for _, tt := range tests {
+		for _, workers := range []int{1, 4} {
+			tree, err := NewLinearScannerWithOptions(ScanOptions{MaxDepth: tt.maxDepth, Workers: workers}).Scan(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := paths(tree); !slices.Equal(got, tt.want) {
+				t.Errorf("MaxDepth=%d, Workers=%d: paths = %v, want %v", tt.maxDepth, workers, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestScanMissingRoot(t *testing.T) {
+	_, err := NewLinearScanner().Scan(filepath.Join(t.TempDir(), "nope"))
+	if err == nil {
+		t.Fatal("Scan of a missing directory succeeded")
+	}
+}
+
+func TestFSScanner(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/a.mkv":     {Data: []byte("123")},
+		"lib/sub/b.mkv": {Data: []byte("12")},
+		"lib/.DS_Store": {Data: []byte("1")},
+		"other/c.mkv":   {Data: []byte("1")},
+	}
+	tree, err := NewFSScanner(fsys).Scan("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.mkv", "sub", "sub/b.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if a := tree.Assets["a.mkv"]; a.Size != 3 || a.AbsPath != "" {
+		t.Errorf("a.mkv = %+v, want size 3 and no AbsPath", a)
+	}
+}
+
+func TestScanFilter(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"big.mkv":       "12345",
+		"small.mkv":     "1",
+		"empty.mkv":     "",
+		"show/ep1.mkv":  "123456",
+		"show/tiny.srt": "12",
+	})
+	var seen []string
+	opts := ScanOptions{Filter: func(a domain.Asset) bool {
+		seen = append(seen, a.Path)
+		return a.IsDir || a.Size > 2
+	}}
+	tree, err := NewLinearScannerWithOptions(opts).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"big.mkv", "show", "show/ep1.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if tree.FileCount != 2 || tree.TotalSize != 11 {
+		t.Errorf("FileCount, TotalSize = %d, %d, want 2, 11", tree.FileCount, tree.TotalSize)
+	}
+	if len(seen) != 6 {
+		t.Errorf("Filter saw %v, want every asset", seen)
+	}
+
+	// Dropping a directory keeps its contents.
+	opts.Filter = func(a domain.Asset) bool { return !a.IsDir }
+	tree, err = NewLinearScannerWithOptions(opts).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"big.mkv", "empty.mkv", "show/ep1.mkv", "show/tiny.srt", "small.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestScanMinSize(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"big.mkv":        strings.Repeat("x", 2000),
+		"small.url":      strings.Repeat("x", 100),
+		"empty.mkv":      "",
+		"show/ep1.mkv":   strings.Repeat("x", 1000),
+		"show/small.nfo": strings.Repeat("x", 999),
+	})
+	tree, err := NewLinearScannerWithOptions(ScanOptions{MinSize: 1000}).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"big.mkv", "show", "show/ep1.mkv"}
+	if got := paths(tree); !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+	if tree.FileCount != 2 || tree.TotalSize != 3000 {
+		t.Errorf("FileCount, TotalSize = %d, %d, want 2, 3000", tree.FileCount, tree.TotalSize)
+	}
+}
+
+func TestScanRecordsSymlinks(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.mkv": "12345"})
+	if err := os.Symlink("a.mkv", filepath.Join(root, "link.mkv")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+	tree, err := NewLinearScanner().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := tree.Assets["a.mkv"]; a.IsSymlink {
+		t.Errorf("a.mkv = %+v, want a regular file", a)
+	}
+	if link := tree.Assets["link.mkv"]; !link.IsSymlink || link.LinkTarget != "a.mkv" {
+		t.Errorf("link.mkv = %+v, want a symlink to a.mkv", link)
+	}
+}
+
+func TestScanFollowSymlinkedDirectory(t *testing.T) {
+	root := writeTree(t, map[string]string{"store/s01/ep1.mkv": "123", "show/extra.mkv": "1"})
+	if err := os.Symlink(filepath.Join("..", "store", "s01"), filepath.Join(root, "show", "s01")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+	if err := os.Symlink("self", filepath.Join(root, "self")); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := NewLinearScanner().Scan(filepath.Join(root, "show"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"extra.mkv", "s01"}; !slices.Equal(paths(tree), want) {
+		t.Errorf("paths = %v, want %v", paths(tree), want)
+	}
+
+	tree, err = NewLinearScannerWithOptions(ScanOptions{FollowSymlinks: true}).Scan(filepath.Join(root, "show"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"extra.mkv", "s01", "s01/ep1.mkv"}; !slices.Equal(paths(tree), want) {
+		t.Errorf("followed paths = %v, want %v", paths(tree), want)
+	}
+	if ep := tree.Assets["s01/ep1.mkv"]; ep.Size != 3 || ep.IsSymlink {
+		t.Errorf("s01/ep1.mkv = %+v, want the linked 3-byte file", ep)
+	}
+
+	// A link to itself can't be resolved, so it is skipped, even without
+	// ContinueOnError, rather than looping or failing the scan.
+	tree, err = NewLinearScannerWithOptions(ScanOptions{FollowSymlinks: true}).Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.ScanErrors) != 1 || tree.ScanErrors[0].Path != "self" {
+		t.Errorf("ScanErrors = %+v, want one for self", tree.ScanErrors)
+	}
+	if _, ok := tree.Assets["show/s01/ep1.mkv"]; !ok {
+		t.Errorf("paths = %v, want show/s01/ep1.mkv", paths(tree))
+	}
+}
+
+func TestScanContext(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.mkv": "1", "b.mkv": "1", "c/d.mkv": "1", "c/e.mkv": "1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree, err := NewLinearScanner().ScanContext(ctx, root)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanContext error = %v, want %v", err, context.Canceled)
+	}
+	if tree == nil {
+		t.Fatal("ScanContext returned no partial tree")
+	}
+
+	// Cancel after the first entry; the partial tree keeps what was found.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	opts := ScanOptions{OnProgress: func(int) { cancel() }}
+	tree, err = NewLinearScannerWithOptions(opts).ScanContext(ctx, root)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanContext error = %v, want %v", err, context.Canceled)
+	}
+	if n := len(tree.Assets); n == 0 || n >= 5 {
+		t.Errorf("partial tree = %v, want some but not all of the 5 assets", paths(tree))
+	}
+}
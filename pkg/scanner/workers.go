@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+)
+
+// scanJob is an entry handed from the walking goroutine to a worker.
+type scanJob struct {
+	relPath string
+	d       fs.DirEntry
+}
+
+// startWorkers runs fn for each queued entry on n goroutines. send queues an
+// entry, failing once fn has failed or ctx is done. wait must be called after
+// the last send; it stops the workers and returns the first error from fn, or
+// the error of ctx.
+func startWorkers(
+	ctx context.Context, n int, fn func(relPath string, d fs.DirEntry) error,
+) (send func(relPath string, d fs.DirEntry) error, wait func() error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	jobs := make(chan scanJob, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			for j := range jobs {
+				if err := fn(j.relPath, j.d); err != nil {
+					cancel(err)
+				}
+			}
+		})
+	}
+
+	send = func(relPath string, d fs.DirEntry) error {
+		select {
+		case jobs <- scanJob{relPath: relPath, d: d}:
+			return nil
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+	wait = func() error {
+		close(jobs)
+		wg.Wait()
+		err := context.Cause(ctx)
+		cancel(nil)
+		return err
+	}
+	return send, wait
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScanWorkers(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 50 {
+		files[fmt.Sprintf("show%d/ep%d.mkv", i%5, i)] = fmt.Sprint(i)
+	}
+	files[".DS_Store"] = "x"
+	root := writeTree(t, files)
+
+	serial, err := NewLinearScanner().Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 8, 64} {
+		tree, err := NewLinearScannerWithOptions(ScanOptions{Workers: workers}).Scan(root)
+		if err != nil {
+			t.Fatalf("Workers=%d: %v", workers, err)
+		}
+		if !reflect.DeepEqual(tree, serial) {
+			t.Errorf("Workers=%d: tree = %+v, want %+v", workers, tree, serial)
+		}
+	}
+}
+
+func TestScanWorkersContinueOnError(t *testing.T) {
+	fsys := failingFS{
+		fsys: fstest.MapFS{
+			"lib/a/x.mkv": {Data: []byte("1")},
+			"lib/b/x.mkv": {Data: []byte("1")},
+			"lib/c/x.mkv": {Data: []byte("1")},
+			"lib/d/x.mkv": {Data: []byte("1")},
+		},
+		bad: []string{"lib/d", "lib/b"},
+	}
+	opts := ScanOptions{FS: fsys, ContinueOnError: true}
+	serial, err := NewLinearScannerWithOptions(opts).Scan("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Workers = 4
+	tree, err := NewLinearScannerWithOptions(opts).Scan("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tree, serial) {
+		t.Errorf("tree = %+v, want %+v", tree, serial)
+	}
+	if len(tree.ScanErrors) != 2 || tree.ScanErrors[0].Path != "b" || tree.ScanErrors[1].Path != "d" {
+		t.Errorf("ScanErrors = %+v, want b and d in walk order", tree.ScanErrors)
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	files := make(map[string]string)
+	for i := range 2000 {
+		files[fmt.Sprintf("show%02d/season%d/ep%04d.mkv", i%40, i%4, i)] = "x"
+	}
+	root := writeTree(b, files)
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			s := NewLinearScannerWithOptions(ScanOptions{Workers: workers})
+			for b.Loop() {
+				if _, err := s.Scan(root); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanContextWorkers(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 200 {
+		files[fmt.Sprintf("d%d/f%d.mkv", i%10, i)] = "x"
+	}
+	root := writeTree(t, files)
+
+	// Cancel partway through; the scan stops without reading the rest.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seen := 0
+	opts := ScanOptions{Workers: 4, OnProgress: func(int) {
+		if seen++; seen == 20 {
+			cancel()
+		}
+	}}
+	tree, err := NewLinearScannerWithOptions(opts).ScanContext(ctx, root)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanContext error = %v, want %v", err, context.Canceled)
+	}
+	if n := len(tree.Assets); n >= len(files) {
+		t.Errorf("partial tree has %d assets, want fewer than %d", n, len(files))
+	}
+}